@@ -106,7 +106,8 @@ function AdminEditProblem() {
       difficulty: form.difficulty,
       tags: form.tags.split(',').map((t) => t.trim()).filter(Boolean),
       config,
-      testCases
+      testCases,
+      replaceTestCases: true
     };
 
     try {
@@ -4,14 +4,18 @@ import (
 	"bufio"
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"onlinejudge-server-go/internal/app"
+	"onlinejudge-server-go/internal/migrate"
+	"onlinejudge-server-go/prisma"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
@@ -43,9 +47,13 @@ func main() {
 		log.Fatal(err)
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(30 * time.Minute)
+	maxOpen, maxIdle, lifetime, err := dbPoolConfig(os.Getenv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(lifetime)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -53,9 +61,41 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if !envBool("SKIP_MIGRATIONS") {
+		migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), 10*time.Minute)
+		applied, err := migrate.Up(migrateCtx, db, prisma.Migrations)
+		cancelMigrate()
+		for _, name := range applied {
+			log.Printf("Applied migration %s", name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	a, err := app.New(app.Config{
-		DB:        db,
-		JWTSecret: jwtSecret,
+		DB:                     db,
+		JWTSecret:              jwtSecret,
+		MemThrottleOn:          envFloat("MEM_THROTTLE_ON"),
+		MemThrottleOff:         envFloat("MEM_THROTTLE_OFF"),
+		MemMonitorInterval:     envDuration("MEM_MONITOR_INTERVAL"),
+		MemMonitorDebug:        envBool("MEM_MONITOR_DEBUG"),
+		SubmissionThrottleMode: os.Getenv("SUBMISSION_THROTTLE_MODE"),
+		MaxBodyBytes:           envInt64("MAX_BODY_BYTES"),
+		MaxLargeBodyBytes:      envInt64("MAX_LARGE_BODY_BYTES"),
+		DebugErrors:            envBool("DEBUG_ERRORS"),
+		CompileTimeout:         envDuration("COMPILE_TIMEOUT"),
+		MinTimeLimitMs:         int(envInt64("PROBLEM_MIN_TIME_LIMIT_MS")),
+		MaxTimeLimitMs:         int(envInt64("PROBLEM_MAX_TIME_LIMIT_MS")),
+		MinMemoryLimitMB:       int(envInt64("PROBLEM_MIN_MEMORY_LIMIT_MB")),
+		MaxMemoryLimitMB:       int(envInt64("PROBLEM_MAX_MEMORY_LIMIT_MB")),
+		TrustedProxies:         envList("TRUSTED_PROXIES"),
+		StaticDir:              os.Getenv("STATIC_DIR"),
+		DisableStatic:          envBool("DISABLE_STATIC"),
+		SPADir:                 os.Getenv("SPA_DIR"),
+		BanCascadeMaxUsers:     int(envInt64("BAN_CASCADE_MAX_USERS")),
+		StrictPreferences:      envBool("STRICT_PREFERENCES"),
+		JudgePoolSize:          int(envInt64("JUDGE_POOL_SIZE")),
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -73,6 +113,82 @@ func main() {
 	}
 }
 
+// dbPoolConfig reads DB_MAX_OPEN, DB_MAX_IDLE and DB_CONN_LIFETIME through
+// getenv. Unset values default to 25, DB_MAX_OPEN and 30m; set values must
+// be positive, and idle connections may not exceed open ones.
+func dbPoolConfig(getenv func(string) string) (maxOpen, maxIdle int, lifetime time.Duration, err error) {
+	maxOpen, maxIdle, lifetime = 25, 0, 30*time.Minute
+	if v := strings.TrimSpace(getenv("DB_MAX_OPEN")); v != "" {
+		if maxOpen, err = strconv.Atoi(v); err != nil || maxOpen <= 0 {
+			return 0, 0, 0, fmt.Errorf("DB_MAX_OPEN must be a positive integer, got %q", v)
+		}
+	}
+	maxIdle = maxOpen
+	if v := strings.TrimSpace(getenv("DB_MAX_IDLE")); v != "" {
+		if maxIdle, err = strconv.Atoi(v); err != nil || maxIdle <= 0 {
+			return 0, 0, 0, fmt.Errorf("DB_MAX_IDLE must be a positive integer, got %q", v)
+		}
+		if maxIdle > maxOpen {
+			return 0, 0, 0, fmt.Errorf("DB_MAX_IDLE (%d) must be at most DB_MAX_OPEN (%d)", maxIdle, maxOpen)
+		}
+	}
+	if v := strings.TrimSpace(getenv("DB_CONN_LIFETIME")); v != "" {
+		if lifetime, err = time.ParseDuration(v); err != nil || lifetime <= 0 {
+			return 0, 0, 0, fmt.Errorf("DB_CONN_LIFETIME must be a positive duration, got %q", v)
+		}
+	}
+	return maxOpen, maxIdle, lifetime, nil
+}
+
+func envFloat(key string) float64 {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return f
+}
+
+func envInt64(key string) int64 {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return n
+}
+
+func envBool(key string) bool {
+	v := strings.TrimSpace(os.Getenv(key))
+	return v == "1" || strings.EqualFold(v, "true")
+}
+
+func envList(key string) []string {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+func envDuration(key string) time.Duration {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return d
+}
+
 func normalizeDatabaseURL(raw string) string {
 	u, err := url.Parse(raw)
 	if err != nil {
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDBPoolConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		open, idle int
+		lifetime   time.Duration
+		wantErr    bool
+	}{
+		{name: "defaults", open: 25, idle: 25, lifetime: 30 * time.Minute},
+		{name: "blank values use defaults", env: map[string]string{"DB_MAX_OPEN": " ", "DB_CONN_LIFETIME": ""}, open: 25, idle: 25, lifetime: 30 * time.Minute},
+		{name: "idle defaults to open", env: map[string]string{"DB_MAX_OPEN": "10"}, open: 10, idle: 10, lifetime: 30 * time.Minute},
+		{name: "all set", env: map[string]string{"DB_MAX_OPEN": "40", "DB_MAX_IDLE": "5", "DB_CONN_LIFETIME": "5m"}, open: 40, idle: 5, lifetime: 5 * time.Minute},
+		{name: "idle equal to open", env: map[string]string{"DB_MAX_OPEN": "8", "DB_MAX_IDLE": "8"}, open: 8, idle: 8, lifetime: 30 * time.Minute},
+
+		{name: "invalid open", env: map[string]string{"DB_MAX_OPEN": "many"}, wantErr: true},
+		{name: "invalid idle", env: map[string]string{"DB_MAX_IDLE": "1.5"}, wantErr: true},
+		{name: "invalid lifetime", env: map[string]string{"DB_CONN_LIFETIME": "30"}, wantErr: true},
+		{name: "zero open", env: map[string]string{"DB_MAX_OPEN": "0"}, wantErr: true},
+		{name: "negative open", env: map[string]string{"DB_MAX_OPEN": "-1"}, wantErr: true},
+		{name: "zero idle", env: map[string]string{"DB_MAX_IDLE": "0"}, wantErr: true},
+		{name: "negative lifetime", env: map[string]string{"DB_CONN_LIFETIME": "-1m"}, wantErr: true},
+		{name: "zero lifetime", env: map[string]string{"DB_CONN_LIFETIME": "0s"}, wantErr: true},
+		{name: "idle above open", env: map[string]string{"DB_MAX_OPEN": "5", "DB_MAX_IDLE": "6"}, wantErr: true},
+		{name: "idle above default open", env: map[string]string{"DB_MAX_IDLE": "26"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			open, idle, lifetime, err := dbPoolConfig(getenv)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("dbPoolConfig = %d, %d, %v; want an error", open, idle, lifetime)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if open != tt.open || idle != tt.idle || lifetime != tt.lifetime {
+				t.Errorf("dbPoolConfig = %d, %d, %v; want %d, %d, %v", open, idle, lifetime, tt.open, tt.idle, tt.lifetime)
+			}
+		})
+	}
+}
@@ -9,9 +9,10 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,8 +31,91 @@ import (
 type Config struct {
 	DB        *sql.DB
 	JWTSecret string
+
+	// MemThrottleOn and MemThrottleOff are the memory usage fractions at which
+	// run-code throttling is enabled and disabled again. Zero means default;
+	// the default for MemThrottleOff follows MemThrottleOn.
+	MemThrottleOn      float64
+	MemThrottleOff     float64
+	MemMonitorInterval time.Duration
+	// MemMonitorDebug logs every memory sample instead of only state changes.
+	MemMonitorDebug bool
+	// SubmissionThrottleMode controls submissions under memory pressure:
+	// "defer" accepts them and holds judging until pressure subsides,
+	// "reject" answers 503 like run-code does.
+	SubmissionThrottleMode string
+	// MaxBodyBytes caps request bodies on ordinary API routes and
+	// MaxLargeBodyBytes on problem and attachment uploads. Zero means default.
+	MaxBodyBytes      int64
+	MaxLargeBodyBytes int64
+	// DebugErrors includes raw internal error text in 500 responses to admins.
+	DebugErrors bool
+	// CompileTimeout bounds a single compilation. Zero means the judger default.
+	CompileTimeout time.Duration
+	// Accepted range for problem time limits (ms) and memory limits (MB),
+	// including per-language overrides. Zero means default.
+	MinTimeLimitMs   int
+	MaxTimeLimitMs   int
+	MinMemoryLimitMB int
+	MaxMemoryLimitMB int
+	// TrustedProxies lists proxy CIDRs or IPs whose X-Forwarded-For and
+	// X-Real-IP headers are believed. Empty trusts no proxy.
+	TrustedProxies []string
+	// StaticDir is served under /static/. Empty means "./static".
+	// DisableStatic leaves /static/ unrouted.
+	StaticDir     string
+	DisableStatic bool
+	// SPADir, when set, is a frontend build directory whose files are served
+	// for unmatched GET requests outside /api and /static, falling back to
+	// its index.html so client-side routes can be deep-linked.
+	SPADir string
+	// BanCascadeMaxUsers is the most distinct users an IP may have for an IP
+	// ban to also ban them. Zero means default.
+	BanCascadeMaxUsers int
+	// StrictPreferences rejects user preference keys the server does not
+	// know instead of storing them as-is.
+	StrictPreferences bool
+	// JudgePoolSize is how many pre-started judge containers to keep warm
+	// for reuse. Zero creates a fresh container for every judge.
+	JudgePoolSize int
 }
 
+const (
+	submissionThrottleDefer  = "defer"
+	submissionThrottleReject = "reject"
+)
+
+const (
+	defaultMemThrottleOn = 0.8
+	// defaultMemThrottleGap is how far below the throttle-on threshold
+	// throttling is lifted unless MemThrottleOff is set.
+	defaultMemThrottleGap     = 0.2
+	defaultMemMonitorInterval = 5 * time.Second
+	defaultMaxBodyBytes       = 1 << 20
+	defaultMaxLargeBodyBytes  = 64 << 20
+	defaultStaticDir          = "./static"
+	defaultBanCascadeMaxUsers = 5
+
+	defaultMinTimeLimitMs   = 100
+	defaultMaxTimeLimitMs   = 30000
+	defaultMinMemoryLimitMB = 16
+	defaultMaxMemoryLimitMB = 1024
+
+	judgeWorkerCount         = 2
+	judgeQueueCapacity       = 128
+	judgeRetryInitialBackoff = 5 * time.Second
+	judgeRetryMaxBackoff     = 2 * time.Minute
+	judgeFailureRetries      = 2
+	// judgeDockerRetries caps the retries while Docker is unavailable; with
+	// the backoff above they span roughly ten minutes.
+	judgeDockerRetries = 8
+	// judgeOverflowMax caps the submissions judged outside the queue when
+	// it is full. Beyond that new submissions are refused.
+	judgeOverflowMax = 256
+	// dockerStatusTTL is how long the health endpoints reuse a Docker ping.
+	dockerStatusTTL = 5 * time.Second
+)
+
 type App struct {
 	store          *store.Store
 	jwtSecret      []byte
@@ -39,11 +123,63 @@ type App struct {
 	httpRouter     http.Handler
 	codeRunMu      sync.Mutex
 	codeRunHistory map[int][]time.Time
-	geoIPService   *GeoIPService
-	sensitiveCache sync.Map
-	judgeQueue     chan judgeTask
-	judgeOnce      sync.Once
-	memoryThrottle uint32
+	// codeRunIPHistory shares codeRunMu with codeRunHistory.
+	codeRunIPHistory map[string][]time.Time
+	guestRunMu       sync.Mutex
+	guestRunHistory  map[string][]time.Time
+	whitelistMu      sync.Mutex
+	whitelistCache   map[string]whitelistEntry
+	geoIPService     *GeoIPService
+	judgeQueue       chan judgeTask
+	judgeOverflow    chan struct{}
+	webhooks         *webhookSender
+	judgeOnce        sync.Once
+	judgeActive      int32
+	memoryThrottle   uint32
+	memThrottleOn    float64
+	memThrottleOff   float64
+	memInterval      time.Duration
+	memDebug         bool
+	submitThrottle   string
+	maxBody          int64
+	maxLargeBody     int64
+	debugErrors      bool
+	compileTimeout   time.Duration
+	limitBounds      limitBounds
+	trustedProxies   []netip.Prefix
+	// staticDir is empty when static serving is disabled.
+	staticDir     string
+	spaDir        string
+	banCascadeMax int
+	strictPrefs   bool
+
+	// Sensitive-path patterns are reloaded from settings every
+	// sensitiveReloadInterval; sensitiveCache memoizes per-path results for
+	// the current patterns and is cleared whenever they change.
+	sensitiveMu        sync.RWMutex
+	sensitiveMatcher   *pathMatcher
+	sensitiveLoadedAt  time.Time
+	sensitiveCache     sync.Map
+	sensitiveCacheSize int64
+
+	// dockerCheck caches the last Docker ping for the health endpoints.
+	dockerMu    sync.Mutex
+	dockerCheck dockerCheck
+
+	overviewMu    sync.Mutex
+	overviewCache store.SiteOverview
+	overviewAt    time.Time
+
+	// leaderboardCache holds public contest leaderboard pages;
+	// leaderboardInvalidated records when each contest was last invalidated.
+	leaderboardMu          sync.Mutex
+	leaderboardCache       map[leaderboardKey]leaderboardEntry
+	leaderboardInvalidated map[int]time.Time
+
+	// The OpenAPI document is built from httpRouter on first request.
+	openAPIOnce sync.Once
+	openAPIDoc  map[string]any
+	openAPIErr  error
 }
 
 type judgeTask struct {
@@ -51,6 +187,7 @@ type judgeTask struct {
 	problem      store.ProblemWithTestCases
 	code         string
 	language     string
+	contestID    *int
 }
 
 type userClaims struct {
@@ -80,38 +217,206 @@ func New(cfg Config) (*App, error) {
 	if imageName == "" {
 		imageName = "judge-runner:latest"
 	}
-	runner, err := judger.NewDockerRunner(imageName)
+	if cfg.JudgePoolSize < 0 {
+		return nil, errors.New("judge pool size must not be negative")
+	}
+	runner, err := judger.NewDockerRunner(imageName, cfg.JudgePoolSize)
+	if err != nil {
+		return nil, err
+	}
+
+	throttleOn, throttleOff, err := memThrottleThresholds(cfg.MemThrottleOn, cfg.MemThrottleOff)
+	if err != nil {
+		return nil, err
+	}
+	memInterval := cfg.MemMonitorInterval
+	if memInterval <= 0 {
+		memInterval = defaultMemMonitorInterval
+	}
+	submitThrottle := strings.ToLower(strings.TrimSpace(cfg.SubmissionThrottleMode))
+	if submitThrottle == "" {
+		submitThrottle = submissionThrottleDefer
+	}
+	if submitThrottle != submissionThrottleDefer && submitThrottle != submissionThrottleReject {
+		return nil, errors.New("submission throttle mode must be defer or reject")
+	}
+
+	maxBody := cfg.MaxBodyBytes
+	if maxBody <= 0 {
+		maxBody = defaultMaxBodyBytes
+	}
+	maxLargeBody := cfg.MaxLargeBodyBytes
+	if maxLargeBody <= 0 {
+		maxLargeBody = defaultMaxLargeBodyBytes
+	}
+	if maxLargeBody < maxBody {
+		return nil, errors.New("large body limit must not be below the default body limit")
+	}
+
+	bounds := limitBounds{
+		minTime: cfg.MinTimeLimitMs,
+		maxTime: cfg.MaxTimeLimitMs,
+		minMem:  cfg.MinMemoryLimitMB,
+		maxMem:  cfg.MaxMemoryLimitMB,
+	}
+	if bounds.minTime == 0 {
+		bounds.minTime = defaultMinTimeLimitMs
+	}
+	if bounds.maxTime == 0 {
+		bounds.maxTime = defaultMaxTimeLimitMs
+	}
+	if bounds.minMem == 0 {
+		bounds.minMem = defaultMinMemoryLimitMB
+	}
+	if bounds.maxMem == 0 {
+		bounds.maxMem = defaultMaxMemoryLimitMB
+	}
+	if bounds.minTime <= 0 || bounds.maxTime < bounds.minTime || bounds.minMem <= 0 || bounds.maxMem < bounds.minMem {
+		return nil, errors.New("problem limit bounds must be positive with min not above max")
+	}
+
+	trustedProxies, err := parseTrustedProxies(cfg.TrustedProxies)
 	if err != nil {
 		return nil, err
 	}
 
+	banCascadeMax := cfg.BanCascadeMaxUsers
+	if banCascadeMax == 0 {
+		banCascadeMax = defaultBanCascadeMaxUsers
+	}
+	if banCascadeMax < 0 {
+		return nil, errors.New("ban cascade user cap must be positive")
+	}
+
+	staticDir := ""
+	if !cfg.DisableStatic {
+		staticDir = strings.TrimSpace(cfg.StaticDir)
+		if staticDir == "" {
+			staticDir = defaultStaticDir
+		}
+	}
+
 	a := &App{
-		store:          store.New(cfg.DB),
-		jwtSecret:      []byte(secret),
-		docker:         runner,
-		codeRunHistory: make(map[int][]time.Time),
-		geoIPService:   NewGeoIPService(),
-		judgeQueue:     make(chan judgeTask, 128),
+		store:                  store.New(cfg.DB),
+		jwtSecret:              []byte(secret),
+		docker:                 runner,
+		codeRunHistory:         make(map[int][]time.Time),
+		codeRunIPHistory:       make(map[string][]time.Time),
+		guestRunHistory:        make(map[string][]time.Time),
+		whitelistCache:         make(map[string]whitelistEntry),
+		geoIPService:           NewGeoIPService(),
+		judgeQueue:             make(chan judgeTask, judgeQueueCapacity),
+		judgeOverflow:          make(chan struct{}, judgeOverflowMax),
+		webhooks:               newWebhookSender(),
+		leaderboardCache:       make(map[leaderboardKey]leaderboardEntry),
+		leaderboardInvalidated: make(map[int]time.Time),
+		memThrottleOn:          throttleOn,
+		memThrottleOff:         throttleOff,
+		memInterval:            memInterval,
+		memDebug:               cfg.MemMonitorDebug,
+		submitThrottle:         submitThrottle,
+		maxBody:                maxBody,
+		maxLargeBody:           maxLargeBody,
+		debugErrors:            cfg.DebugErrors,
+		compileTimeout:         cfg.CompileTimeout,
+		limitBounds:            bounds,
+		trustedProxies:         trustedProxies,
+		staticDir:              staticDir,
+		spaDir:                 strings.TrimSpace(cfg.SPADir),
+		banCascadeMax:          banCascadeMax,
+		strictPrefs:            cfg.StrictPreferences,
 	}
 	a.startJudgeWorkers()
 	a.startMemoryMonitor()
+	a.startRetentionCleanup()
+	a.startSensitiveAlerts()
+	a.startIPMarkCleanup()
+	a.startContestReminders()
 	a.httpRouter = a.buildRouter()
 	return a, nil
 }
 
 func (a *App) startJudgeWorkers() {
 	a.judgeOnce.Do(func() {
-		workerCount := 2
-		for i := 0; i < workerCount; i++ {
+		for i := 0; i < judgeWorkerCount; i++ {
 			go func() {
 				for task := range a.judgeQueue {
-					a.judgeSubmission(task.submissionID, task.problem, task.code, task.language)
+					a.waitForMemoryPressure()
+					atomic.AddInt32(&a.judgeActive, 1)
+					a.judgeSubmission(task.submissionID, task.problem, task.code, task.language, task.contestID)
+					atomic.AddInt32(&a.judgeActive, -1)
 				}
 			}()
 		}
 	})
 }
 
+// memThrottleThresholds applies the defaults to the configured memory
+// throttle thresholds and validates them. An unset throttle-off threshold
+// sits defaultMemThrottleGap below throttle-on, or halfway to zero when
+// throttle-on is too low for that.
+func memThrottleThresholds(on, off float64) (float64, float64, error) {
+	if on == 0 {
+		on = defaultMemThrottleOn
+	}
+	if on <= 0 || on >= 1 {
+		return 0, 0, errors.New("memory throttle-on threshold must be between 0 and 1")
+	}
+	if off == 0 {
+		off = on - defaultMemThrottleGap
+		if off <= 0 {
+			off = on / 2
+		}
+	}
+	if off <= 0 || off >= on {
+		return 0, 0, errors.New("memory throttle-off threshold must be positive and below throttle-on")
+	}
+	return on, off, nil
+}
+
+// waitForMemoryPressure blocks while the memory throttle is on so deferred
+// submissions stay Pending until the host recovers.
+func (a *App) waitForMemoryPressure() {
+	if a.submitThrottle != submissionThrottleDefer {
+		return
+	}
+	for a.isMemoryThrottled() {
+		time.Sleep(a.memInterval)
+	}
+}
+
+// dockerStatus pings the Docker daemon used by the judge.
+func (a *App) dockerStatus(ctx context.Context) (bool, string) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if err := a.docker.Ping(ctx); err != nil {
+		return false, err.Error()
+	}
+	return true, ""
+}
+
+type dockerCheck struct {
+	ok  bool
+	err string
+	at  time.Time
+}
+
+// cachedDockerStatus is dockerStatus reused for dockerStatusTTL, so probes
+// polling the health endpoints do not ping Docker on every request.
+func (a *App) cachedDockerStatus(ctx context.Context) (bool, string) {
+	a.dockerMu.Lock()
+	c := a.dockerCheck
+	a.dockerMu.Unlock()
+	if !c.at.IsZero() && time.Since(c.at) < dockerStatusTTL {
+		return c.ok, c.err
+	}
+	ok, msg := a.dockerStatus(ctx)
+	a.dockerMu.Lock()
+	a.dockerCheck = dockerCheck{ok: ok, err: msg, at: time.Now()}
+	a.dockerMu.Unlock()
+	return ok, msg
+}
+
 func (a *App) isMemoryThrottled() bool {
 	return atomic.LoadUint32(&a.memoryThrottle) == 1
 }
@@ -126,42 +431,45 @@ func (a *App) setMemoryThrottled(on bool) {
 
 func (a *App) startMemoryMonitor() {
 	go func() {
-		ticker := time.NewTicker(5 * time.Second)
+		ticker := time.NewTicker(a.memInterval)
 		defer ticker.Stop()
 		for range ticker.C {
 			hostUsed, hostTotal := readHostMemory()
-			cgUsed, cgLimit := readCgroupMemory()
+			cg := readCgroupMemory()
 			var hostRatio float64
-			var cgRatio float64
-			if hostTotal > 0 && hostUsed >= 0 {
+			if hostTotal > 0 {
 				hostRatio = float64(hostUsed) / float64(hostTotal)
 			}
-			if cgLimit > 0 && cgUsed >= 0 {
-				cgRatio = float64(cgUsed) / float64(cgLimit)
-			}
+			cgRatio := cg.Ratio()
 
-			throttleOn := hostRatio > 0.8 || cgRatio > 0.8
-			throttleOff := hostRatio < 0.6 && cgRatio < 0.6
+			a.updateMemoryThrottle(hostRatio, cgRatio)
 
-			if throttleOn && !a.isMemoryThrottled() {
-				a.setMemoryThrottled(true)
-				log.Printf("[memory-monitor] enable throttle host=%.1f%% cgroup=%.1f%%", hostRatio*100, cgRatio*100)
-			} else if throttleOff && a.isMemoryThrottled() {
-				a.setMemoryThrottled(false)
-				log.Printf("[memory-monitor] disable throttle host=%.1f%% cgroup=%.1f%%", hostRatio*100, cgRatio*100)
+			if a.memDebug {
+				log.Printf("[memory-monitor] host=%.1f%% (%d/%d) cgroup-v%d=%.1f%% (%d/%d) throttle=%t",
+					hostRatio*100, hostUsed, hostTotal, cg.Version, cgRatio*100, cg.Used, cg.Limit, a.isMemoryThrottled())
 			}
-
-			go func() {
-				cmd := exec.Command("free", "-h")
-				out, err := cmd.CombinedOutput()
-				if err == nil {
-					log.Printf("[memory-monitor] free -h output:\n%s", string(out))
-				}
-			}()
 		}
 	}()
 }
 
+// updateMemoryThrottle applies the on/off hysteresis to one memory sample
+// and reports whether the throttle state changed.
+func (a *App) updateMemoryThrottle(hostRatio, cgRatio float64) bool {
+	throttleOn := hostRatio > a.memThrottleOn || cgRatio > a.memThrottleOn
+	throttleOff := hostRatio < a.memThrottleOff && cgRatio < a.memThrottleOff
+
+	if throttleOn && !a.isMemoryThrottled() {
+		a.setMemoryThrottled(true)
+		log.Printf("[memory-monitor] enable throttle host=%.1f%% cgroup=%.1f%%", hostRatio*100, cgRatio*100)
+		return true
+	} else if throttleOff && a.isMemoryThrottled() {
+		a.setMemoryThrottled(false)
+		log.Printf("[memory-monitor] disable throttle host=%.1f%% cgroup=%.1f%%", hostRatio*100, cgRatio*100)
+		return true
+	}
+	return false
+}
+
 func (a *App) Router() http.Handler {
 	return a.httpRouter
 }
@@ -173,24 +481,63 @@ func (a *App) buildRouter() http.Handler {
 	r.Use(middleware.Recoverer)
 	r.Use(a.cors)
 
-	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
+	if a.staticDir != "" {
+		r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir(a.staticDir))))
+	}
+	if a.spaDir != "" {
+		r.NotFound(a.spaFallback(a.spaDir))
+	}
 
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+		status, docker := "ok", "ok"
+		if ok, _ := a.cachedDockerStatus(r.Context()); !ok {
+			status, docker = "degraded", "unavailable"
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"status": status, "docker": docker})
 	})
+	r.Get("/health/live", a.handleHealthLive)
+	r.Get("/health/ready", a.handleHealthReady)
 
 	r.Route("/api", func(r chi.Router) {
+		// Set before any nested Route so every /api subrouter inherits them.
+		r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "Not found")
+		})
+		r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, http.StatusMethodNotAllowed, errCodeMethod, "Method not allowed")
+		})
 		r.Use(a.logAccess)
+		r.Use(gzipJSON)
+		r.Use(limitBody(a.maxBody))
+		r.Get("/openapi.json", a.handleOpenAPI)
+
 		r.Route("/auth", func(r chi.Router) {
-			r.Post("/register", a.handleRegister)
+			r.With(a.blockDuringMaintenance).Post("/register", a.handleRegister)
 			r.Post("/login", a.handleLogin)
 			r.With(a.authenticateToken).Post("/change-password", a.handleChangePassword)
+			r.With(a.authenticateToken).Get("/me", a.handleAuthMe)
 		})
 
 		r.Route("/user", func(r chi.Router) {
 			r.Use(a.authenticateToken)
 			r.Get("/preferences", a.handleGetPreferences)
 			r.Put("/preferences", a.handleUpdatePreferences)
+			r.Patch("/preferences", a.handlePatchPreferences)
+			r.Get("/solved", a.handleUserSolved)
+			r.Get("/achievements", a.handleUserAchievements)
+			r.Get("/bookmarks", a.handleUserBookmarks)
+			r.Get("/notifications", a.handleUserNotifications)
+			r.Get("/notifications/unread-count", a.handleUserNotificationsUnreadCount)
+			r.Post("/notifications/read-all", a.handleUserNotificationsReadAll)
+			r.Post("/notifications/{id}/read", a.handleUserNotificationRead)
+		})
+
+		r.Get("/users/{username}/solved", a.handlePublicUserSolved)
+		r.Get("/users/{username}/featured", a.handlePublicUserFeatured)
+
+		r.Route("/stats", func(r chi.Router) {
+			r.Get("/overview", a.handleStatsOverview)
+			r.Get("/recent-accepted", a.handleStatsRecentAccepted)
 		})
 
 		r.Route("/problems", func(r chi.Router) {
@@ -199,32 +546,63 @@ func (a *App) buildRouter() http.Handler {
 
 			r.With(a.authenticateToken, a.authorizeAdmin).Get("/admin", a.handleProblemListAdmin)
 			r.With(a.authenticateToken, a.authorizeAdmin).Get("/{id}/admin", a.handleProblemGetAdmin)
-			r.With(a.authenticateToken, a.authorizeAdmin).Post("/", a.handleProblemCreate)
-			r.With(a.authenticateToken, a.authorizeAdmin).Put("/{id}", a.handleProblemUpdate)
+			r.With(a.authenticateToken, a.authorizeAdmin).Get("/{id}/stats", a.handleProblemStats)
+			r.With(a.authenticateToken, a.authorizeAdmin, limitBody(a.maxLargeBody)).Post("/", a.handleProblemCreate)
+			r.With(a.authenticateToken, a.authorizeAdmin, limitBody(a.maxLargeBody)).Post("/validate", a.handleProblemValidate)
+			r.With(a.authenticateToken, a.authorizeAdmin).Post("/{id}/stability-check", a.handleProblemStabilityCheck)
+			r.With(a.authenticateToken, a.authorizeAdmin, limitBody(a.maxLargeBody)).Put("/{id}", a.handleProblemUpdate)
+			r.With(a.authenticateToken, a.authorizeAdmin).Patch("/visibility", a.handleProblemBatchVisibility)
+			r.With(a.authenticateToken, a.authorizeAdmin).Post("/tags/assign", a.handleProblemTagsAssign)
 			r.With(a.authenticateToken, a.authorizeAdmin).Patch("/{id}/visibility", a.handleProblemVisibility)
 			r.With(a.authenticateToken, a.authorizeAdmin).Delete("/{id}", a.handleProblemDelete)
 			r.With(a.authenticateToken, a.authorizeAdmin).Post("/{id}/clone", a.handleProblemClone)
+			r.With(a.authenticateToken, a.authorizeAdmin).Get("/{id}/export", a.handleProblemExport)
+			r.With(a.authenticateToken, a.authorizeAdmin, limitBody(a.maxLargeBody)).Post("/import", a.handleProblemImport)
+			r.With(a.authenticateToken).Post("/{id}/bookmark", a.handleProblemBookmarkAdd)
+			r.With(a.authenticateToken).Delete("/{id}/bookmark", a.handleProblemBookmarkRemove)
+			r.With(a.authenticateToken).Get("/{id}/note", a.handleProblemNoteGet)
+			r.With(a.authenticateToken).Put("/{id}/note", a.handleProblemNotePut)
 		})
 
 		r.Route("/submissions", func(r chi.Router) {
 			r.With(a.authenticateToken).Get("/", a.handleSubmissionList)
+			r.With(a.authenticateToken).Get("/rate-limit/status", a.handleSubmissionRateLimitStatus)
 			r.With(a.authenticateToken).Get("/{id}", a.handleSubmissionDetail)
-			r.With(a.authenticateToken).Post("/", a.handleSubmissionCreate)
+			r.With(a.authenticateToken).Get("/{id}/diff", a.handleSubmissionDiff)
+			r.With(a.authenticateToken, a.blockDuringMaintenance).Post("/", a.handleSubmissionCreate)
+			r.With(a.authenticateToken, a.blockDuringMaintenance).Post("/{id}/resubmit", a.handleSubmissionResubmit)
+			r.With(a.authenticateToken).Patch("/{id}", a.handleSubmissionLabel)
+			r.With(a.authenticateToken).Put("/{id}/featured", a.handleSubmissionFeature)
+			r.With(a.authenticateToken).Delete("/{id}/featured", a.handleSubmissionUnfeature)
 		})
 
-		r.With(a.authenticateToken).Post("/run", a.handleRunCode)
+		r.With(a.authenticateToken, a.blockDuringMaintenance).Post("/run", a.handleRunCode)
+		r.With(a.authenticateToken).Get("/run/rate-limit/status", a.handleCodeRunRateLimitStatus)
+		r.With(a.blockDuringMaintenance).Post("/run/guest", a.handleGuestRunCode)
 
 		r.Route("/settings", func(r chi.Router) {
 			r.Get("/registration", a.handleRegistrationGet)
 			r.With(a.authenticateToken, a.authorizeAdmin).Put("/registration", a.handleRegistrationPut)
+			r.Get("/maintenance", a.handleMaintenanceGet)
+			r.With(a.authenticateToken, a.authorizeAdmin).Put("/maintenance", a.handleMaintenancePut)
 			r.Get("/homepage", a.handleHomepageGet)
 			r.With(a.authenticateToken, a.authorizeAdmin).Put("/homepage", a.handleHomepagePut)
 			r.Get("/footer", a.handleFooterGet)
 			r.With(a.authenticateToken, a.authorizeAdmin).Put("/footer", a.handleFooterPut)
+			r.Get("/banner", a.handleBannerGet)
+			r.With(a.authenticateToken, a.authorizeAdmin).Put("/banner", a.handleBannerPut)
 			r.Get("/rate-limit", a.handleRateLimitGet)
 			r.With(a.authenticateToken, a.authorizeAdmin).Put("/rate-limit", a.handleRateLimitPut)
 			r.Get("/code-run-rate-limit", a.handleCodeRunRateLimitGet)
 			r.With(a.authenticateToken, a.authorizeAdmin).Put("/code-run-rate-limit", a.handleCodeRunRateLimitPut)
+			r.With(a.authenticateToken, a.authorizeAdmin).Get("/submission-retention", a.handleRetentionGet)
+			r.With(a.authenticateToken, a.authorizeAdmin).Put("/submission-retention", a.handleRetentionPut)
+			r.Get("/code-templates", a.handleCodeTemplatesGet)
+			r.With(a.authenticateToken, a.authorizeAdmin).Put("/code-templates", a.handleCodeTemplatesPut)
+			r.With(a.authenticateToken, a.authorizeAdmin).Get("/compile-options", a.handleCompileOptionsGet)
+			r.With(a.authenticateToken, a.authorizeAdmin).Put("/compile-options", a.handleCompileOptionsPut)
+			r.Get("/guest-run", a.handleGuestRunSettingsGet)
+			r.With(a.authenticateToken, a.authorizeAdmin).Put("/guest-run", a.handleGuestRunSettingsPut)
 			r.Get("/turnstile", a.handleTurnstileGet)
 			r.With(a.authenticateToken, a.authorizeAdmin).Put("/turnstile", a.handleTurnstilePut)
 			r.With(a.authenticateToken, a.authorizeAdmin).Post("/turnstile/verify", a.handleTurnstileVerify)
@@ -263,9 +641,24 @@ func (a *App) buildRouter() http.Handler {
 			r.Delete("/ip-marks/{ip}", a.handleIPMarkDelete)
 			r.Get("/ip-marks/{ip}/associations", a.handleIPMarkAssociations)
 			r.Get("/system-status", a.handleSystemStatus)
+			r.Get("/sensitive-patterns", a.handleSensitivePatternsGet)
+			r.Put("/sensitive-patterns", a.handleSensitivePatternsPut)
+			r.Get("/sensitive-alert", a.handleSensitiveAlertGet)
+			r.Put("/sensitive-alert", a.handleSensitiveAlertPut)
+			r.Get("/auto-ban", a.handleAutoBanGet)
+			r.Put("/auto-ban", a.handleAutoBanPut)
 		})
 
 		r.With(a.authenticateToken, a.authorizeAdmin).Delete("/admin/submissions/{id}", a.handleAdminDeleteSubmission)
+		r.With(a.authenticateToken, a.authorizeAdmin).Get("/admin/stats/languages", a.handleAdminLanguageStats)
+
+		r.Route("/admin/webhooks", func(r chi.Router) {
+			r.Use(a.authenticateToken, a.authorizeAdmin)
+			r.Get("/", a.handleWebhookList)
+			r.Post("/", a.handleWebhookCreate)
+			r.Put("/{id}", a.handleWebhookUpdate)
+			r.Delete("/{id}", a.handleWebhookDelete)
+		})
 
 		r.Route("/contests", func(r chi.Router) {
 			r.Get("/public", a.handleContestPublicList)
@@ -283,10 +676,13 @@ func (a *App) buildRouter() http.Handler {
 				r.With(a.authorizeAdmin).Post("/", a.handleContestCreate)
 				r.With(a.authorizeAdmin).Post("/batch/publish", a.handleContestBatchPublish)
 				r.With(a.authorizeAdmin).Get("/{id}/export", a.handleContestExport)
-				r.With(a.authorizeAdmin).Post("/{id}/attachments", a.handleContestAttachmentUpload)
+				r.With(a.authorizeAdmin).Get("/{id}/summary", a.handleContestSummary)
+				r.With(a.authorizeAdmin, limitBody(a.maxLargeBody)).Post("/{id}/attachments", a.handleContestAttachmentUpload)
 				r.With(a.authorizeAdmin).Get("/", a.handleContestAdminList)
 				r.With(a.authorizeAdmin).Get("/{id}", a.handleContestAdminGet)
 				r.With(a.authorizeAdmin).Put("/{id}", a.handleContestAdminUpdate)
+				r.With(a.authorizeAdmin).Get("/{id}/participants", a.handleContestParticipantList)
+				r.With(a.authorizeAdmin).Put("/{id}/participants/{userId}/disqualified", a.handleContestParticipantDisqualify)
 			})
 		})
 	})
@@ -378,35 +774,16 @@ func (a *App) logAccess(next http.Handler) http.Handler {
 				IsSensitive: sensitive,
 			}
 			_ = a.store.CreateAccessHistory(ctx, params)
-		}(u.ID, getClientIP(r), r.UserAgent(), accessType, path, status, r.Header.Get("X-WebRTC-IP"), isSensitive)
+		}(u.ID, a.rawClientIP(r), r.UserAgent(), accessType, path, status, r.Header.Get("X-WebRTC-IP"), isSensitive)
 	})
 }
 
-func (a *App) isSensitivePath(p string) bool {
-	if v, ok := a.sensitiveCache.Load(p); ok {
-		if b, ok := v.(bool); ok {
-			return b
-		}
-	}
-	l := strings.ToLower(p)
-	sensitive := false
-	if strings.HasPrefix(l, "/api/admin") ||
-		strings.HasPrefix(l, "/admin") ||
-		strings.HasPrefix(l, "/.git") ||
-		strings.HasPrefix(l, "/.env") ||
-		strings.Contains(l, "config") {
-		sensitive = true
-	}
-	a.sensitiveCache.Store(p, sensitive)
-	return sensitive
-}
-
 func (a *App) authenticateToken(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		parts := strings.Fields(authHeader)
 		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-			w.WriteHeader(http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Authentication required")
 			return
 		}
 
@@ -419,7 +796,7 @@ func (a *App) authenticateToken(next http.Handler) http.Handler {
 			return a.jwtSecret, nil
 		})
 		if err != nil || !tok.Valid {
-			w.WriteHeader(http.StatusForbidden)
+			writeError(w, http.StatusForbidden, errCodeInvalidToken, "Invalid or expired token")
 			return
 		}
 
@@ -432,7 +809,7 @@ func (a *App) authorizeAdmin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		u, ok := a.currentUser(r)
 		if !ok || u.Role != "ADMIN" {
-			w.WriteHeader(http.StatusForbidden)
+			writeError(w, http.StatusForbidden, errCodeForbidden, "Admin privileges required")
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -448,6 +825,16 @@ func (a *App) currentUser(r *http.Request) (userClaims, bool) {
 	return u, ok
 }
 
+// currentUserID returns the authenticated user's id for createdBy/updatedBy columns.
+func (a *App) currentUserID(r *http.Request) *int {
+	u, ok := a.currentUser(r)
+	if !ok {
+		return nil
+	}
+	id := u.ID
+	return &id
+}
+
 func (a *App) tryUserFromAuthHeader(r *http.Request) (userClaims, bool) {
 	authHeader := r.Header.Get("Authorization")
 	parts := strings.Fields(authHeader)
@@ -469,7 +856,7 @@ func (a *App) tryUserFromAuthHeader(r *http.Request) (userClaims, bool) {
 
 func (a *App) handleRegister(w http.ResponseWriter, r *http.Request) {
 	// Check IP ban
-	clientIP := getClientIP(r)
+	clientIP := a.clientIP(r)
 	isBanned, err := a.store.IsIPBanned(r.Context(), clientIP)
 	if err == nil && isBanned {
 		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Your IP has been banned from registration"})
@@ -482,8 +869,8 @@ func (a *App) handleRegister(w http.ResponseWriter, r *http.Request) {
 		Role     string `json:"role"`
 		CfToken  string `json:"cfToken"`
 	}
-	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
 		return
 	}
 	if strings.TrimSpace(body.Username) == "" || strings.TrimSpace(body.Password) == "" {
@@ -500,14 +887,8 @@ func (a *App) handleRegister(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Registration is currently disabled"})
 		return
 	}
-	turnEnabled, _ := a.store.GetTurnstileEnabled(r.Context())
-	if !turnEnabled {
-		if v := strings.TrimSpace(os.Getenv("TURNSTILE_ENABLED")); v == "1" || strings.EqualFold(v, "true") {
-			turnEnabled = true
-		}
-	}
-	if turnEnabled {
-		ok, errs := a.verifyTurnstile(r, body.CfToken)
+	if a.captchaEnabled(r.Context()) {
+		ok, errs := a.verifyCaptcha(r, body.CfToken)
 		if !ok {
 			writeJSON(w, http.StatusForbidden, map[string]any{"error": "Verification failed", "codes": errs})
 			return
@@ -542,7 +923,7 @@ func (a *App) handleRegister(w http.ResponseWriter, r *http.Request) {
 
 func (a *App) handleLogin(w http.ResponseWriter, r *http.Request) {
 	// Check IP ban
-	clientIP := getClientIP(r)
+	clientIP := a.clientIP(r)
 	isBanned, err := a.store.IsIPBanned(r.Context(), clientIP)
 	if err == nil && isBanned {
 		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Your IP has been banned"})
@@ -554,8 +935,8 @@ func (a *App) handleLogin(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password"`
 		CfToken  string `json:"cfToken"`
 	}
-	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
 		return
 	}
 
@@ -575,14 +956,8 @@ func (a *App) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	turnEnabled, _ := a.store.GetTurnstileEnabled(r.Context())
-	if !turnEnabled {
-		if v := strings.TrimSpace(os.Getenv("TURNSTILE_ENABLED")); v == "1" || strings.EqualFold(v, "true") {
-			turnEnabled = true
-		}
-	}
-	if turnEnabled {
-		ok, errs := a.verifyTurnstile(r, body.CfToken)
+	if a.captchaEnabled(r.Context()) {
+		ok, errs := a.verifyCaptcha(r, body.CfToken)
 		if !ok {
 			writeJSON(w, http.StatusForbidden, map[string]any{"error": "Verification failed", "codes": errs})
 			return
@@ -619,16 +994,52 @@ func (a *App) handleLogin(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"token": signed, "role": u.Role, "username": u.Username})
 }
 
+// handleAuthMe re-reads the user so role, ban and preference changes made
+// after the token was issued are visible to the client.
+func (a *App) handleAuthMe(w http.ResponseWriter, r *http.Request) {
+	u, _ := a.currentUser(r)
+	user, err := a.store.GetUserByID(r.Context(), u.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "User not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	var prefs any = map[string]any{}
+	if user.Preferences != nil {
+		prefs = user.Preferences
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":           user.ID,
+		"username":     user.Username,
+		"role":         user.Role,
+		"isBanned":     user.IsBanned,
+		"bannedAt":     user.BannedAt,
+		"bannedReason": user.BannedReason,
+		"preferences":  prefs,
+	})
+}
+
 func (a *App) handleChangePassword(w http.ResponseWriter, r *http.Request) {
 	u, _ := a.currentUser(r)
 	var body struct {
 		Current string `json:"currentPassword"`
 		New     string `json:"newPassword"`
+		CfToken string `json:"cfToken"`
 	}
-	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
 		return
 	}
+	if a.captchaActionEnabled(r.Context(), captchaActionPasswordChange) {
+		ok, errs := a.verifyCaptcha(r, body.CfToken)
+		if !ok {
+			writeJSON(w, http.StatusForbidden, map[string]any{"error": "Verification failed", "codes": errs})
+			return
+		}
+	}
 	cur := strings.TrimSpace(body.Current)
 	nw := strings.TrimSpace(body.New)
 	if cur == "" || nw == "" {
@@ -685,14 +1096,16 @@ func isStrongPassword(pw string) bool {
 
 func (a *App) handleProblemListPublic(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
+	matchAll := parseTagMode(q)
 	p := store.ListProblemsParams{
-		Difficulty: q.Get("difficulty"),
-		Search:     q.Get("search"),
-		Tags:       parseTags(q),
+		Difficulty:   q.Get("difficulty"),
+		Search:       q.Get("search"),
+		Tags:         parseTags(q),
+		TagsMatchAll: matchAll,
 	}
 	items, err := a.store.ListProblemsPublic(r.Context(), p)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -707,6 +1120,13 @@ func (a *App) handleProblemListPublic(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 		}
+		bookmarks, err := a.store.GetBookmarkedProblemIDs(r.Context(), user.ID)
+		if err == nil {
+			for i := range items {
+				v := bookmarks[items[i].ID]
+				items[i].Bookmarked = &v
+			}
+		}
 	}
 
 	writeJSON(w, http.StatusOK, items)
@@ -714,31 +1134,52 @@ func (a *App) handleProblemListPublic(w http.ResponseWriter, r *http.Request) {
 
 func (a *App) handleProblemListAdmin(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
+	matchAll := parseTagMode(q)
 	p := store.ListProblemsParams{
-		Difficulty: q.Get("difficulty"),
-		Search:     q.Get("search"),
-		Tags:       parseTags(q),
+		Difficulty:   q.Get("difficulty"),
+		Search:       q.Get("search"),
+		Tags:         parseTags(q),
+		TagsMatchAll: matchAll,
 	}
 	items, err := a.store.ListProblemsAdmin(r.Context(), p)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
+	counts, err := a.store.GetTestCaseCountsByProblem(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	for i := range items {
+		n := counts[items[i].ID]
+		items[i].TestCaseCount = &n
+		items[i].NoTestCases = n == 0
+	}
 	writeJSON(w, http.StatusOK, items)
 }
 
 func (a *App) handleProblemGetPublic(w http.ResponseWriter, r *http.Request) {
-	id, ok := parseIntParam(chi.URLParam(r, "id"))
-	if !ok {
+	ref := strings.TrimSpace(chi.URLParam(r, "id"))
+	if ref == "" {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid problem id"})
 		return
 	}
-	p, err := a.store.GetProblemByID(r.Context(), id)
-	if err != nil || !p.Visible {
+	p, err := a.getProblemByRef(r.Context(), ref)
+	if err != nil || !p.IsPublic() {
 		writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
 		return
 	}
-	writeJSON(w, http.StatusOK, p)
+	templates, err := a.problemTemplates(r.Context(), p.Config)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	p.Config = store.PublicProblemConfig(p.Config)
+	writeJSON(w, http.StatusOK, struct {
+		store.Problem
+		Templates map[string]string `json:"templates"`
+	}{p, templates})
 }
 
 func (a *App) handleProblemGetAdmin(w http.ResponseWriter, r *http.Request) {
@@ -753,16 +1194,42 @@ func (a *App) handleProblemGetAdmin(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, p)
+	editors, err := a.store.GetProblemEditors(r.Context(), id)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		store.ProblemWithTestCases
+		store.Editors
+	}{p, editors})
+}
+
+func (a *App) handleProblemStats(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid problem id"})
+		return
+	}
+	st, err := a.store.GetProblemStats(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
 }
 
 func (a *App) handleProblemCreate(w http.ResponseWriter, r *http.Request) {
 	var raw map[string]any
 	if err := readJSON(r, &raw); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+		writeBodyError(w, err)
 		return
 	}
 
@@ -776,18 +1243,31 @@ func (a *App) handleProblemCreate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	defaultCompileOptions, _ := raw["defaultCompileOptions"].(string)
+	if errs := compileOptionErrors("defaultCompileOptions", defaultCompileOptions); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid compile options", "details": errs})
+		return
+	}
 	difficulty, _ := raw["difficulty"].(string)
 	if strings.TrimSpace(difficulty) == "" {
 		difficulty = "LEVEL2"
 	}
 
 	tags := normalizeStringList(raw["tags"])
+	slug, slugErr := parseProblemSlug(raw["slug"])
+	if slugErr != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": slugErr})
+		return
+	}
 
 	var cfg json.RawMessage
 	if v, ok := raw["config"]; ok {
 		b, _ := json.Marshal(v)
 		cfg = b
 	}
+	if errs := a.problemLimitErrors(timeLimit, memoryLimit, cfg); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid limits or config", "details": errs})
+		return
+	}
 
 	testCases := []store.TestCaseInput{}
 	if v, ok := raw["testCases"]; ok {
@@ -803,6 +1283,14 @@ func (a *App) handleProblemCreate(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	if allowEmpty, _ := raw["allowEmpty"].(bool); len(testCases) == 0 && !allowEmpty {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": noTestCasesError})
+		return
+	}
+	if errs := testCaseErrors(testCases, cfg); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid test cases", "details": errs})
+		return
+	}
 
 	contestID, _ := parseOptionalIntAny(raw["contestId"])
 
@@ -814,12 +1302,18 @@ func (a *App) handleProblemCreate(w http.ResponseWriter, r *http.Request) {
 		DefaultCompileOptions: defaultCompileOptions,
 		Difficulty:            difficulty,
 		Tags:                  tags,
+		Slug:                  slug,
 		Config:                cfg,
 		TestCases:             testCases,
 		ContestID:             contestID,
+		CreatedBy:             a.currentUserID(r),
 	})
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		if errors.Is(err, store.ErrUniqueViolation) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Slug already exists"})
+			return
+		}
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, created)
@@ -834,7 +1328,7 @@ func (a *App) handleProblemUpdate(w http.ResponseWriter, r *http.Request) {
 
 	var raw map[string]any
 	if err := readJSON(r, &raw); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+		writeBodyError(w, err)
 		return
 	}
 
@@ -848,17 +1342,32 @@ func (a *App) handleProblemUpdate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	defaultCompileOptions, _ := raw["defaultCompileOptions"].(string)
+	if errs := compileOptionErrors("defaultCompileOptions", defaultCompileOptions); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid compile options", "details": errs})
+		return
+	}
 	difficulty, _ := raw["difficulty"].(string)
 	if strings.TrimSpace(difficulty) == "" {
 		difficulty = "LEVEL2"
 	}
 	tags := normalizeStringList(raw["tags"])
+	// Leave the slug alone unless the payload mentions it.
+	_, hasSlug := raw["slug"]
+	slug, slugErr := parseProblemSlug(raw["slug"])
+	if slugErr != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": slugErr})
+		return
+	}
 
 	var cfg json.RawMessage
 	if v, ok := raw["config"]; ok {
 		b, _ := json.Marshal(v)
 		cfg = b
 	}
+	if errs := a.problemLimitErrors(timeLimit, memoryLimit, cfg); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid limits or config", "details": errs})
+		return
+	}
 
 	testCases := []store.TestCaseInput{}
 	if v, ok := raw["testCases"]; ok {
@@ -874,6 +1383,23 @@ func (a *App) handleProblemUpdate(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	// Test cases are only replaced when asked to, so a metadata-only update
+	// cannot wipe them. Without the flag a non-empty list still replaces
+	// them, which is what older clients rely on.
+	replaceTestCases := len(testCases) > 0
+	if v, ok := raw["replaceTestCases"].(bool); ok {
+		replaceTestCases = v
+	}
+	if replaceTestCases {
+		if allowEmpty, _ := raw["allowEmpty"].(bool); len(testCases) == 0 && !allowEmpty {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": noTestCasesError})
+			return
+		}
+		if errs := testCaseErrors(testCases, cfg); len(errs) > 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid test cases", "details": errs})
+			return
+		}
+	}
 
 	updated, err := a.store.UpdateProblem(r.Context(), store.UpdateProblemParams{
 		ID:                    id,
@@ -885,19 +1411,30 @@ func (a *App) handleProblemUpdate(w http.ResponseWriter, r *http.Request) {
 		Difficulty:            difficulty,
 		Tags:                  tags,
 		Config:                cfg,
+		UpdateTestCases:       replaceTestCases,
 		TestCases:             testCases,
+		UpdatedBy:             a.currentUserID(r),
+		UpdateSlug:            hasSlug,
+		Slug:                  slug,
 	})
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		if errors.Is(err, store.ErrUniqueViolation) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Slug already exists"})
+			return
+		}
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, updated)
 }
 
+// handleProblemVisibility sets the visible flag. An optional visibleFrom
+// keeps a visible problem hidden from non-admins until that time; omitting
+// it publishes immediately.
 func (a *App) handleProblemVisibility(w http.ResponseWriter, r *http.Request) {
 	id, ok := parseIntParam(chi.URLParam(r, "id"))
 	if !ok {
@@ -905,10 +1442,11 @@ func (a *App) handleProblemVisibility(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var body struct {
-		Visible *bool `json:"visible"`
+		Visible     *bool      `json:"visible"`
+		VisibleFrom *time.Time `json:"visibleFrom"`
 	}
-	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
 		return
 	}
 	if body.Visible == nil {
@@ -916,16 +1454,103 @@ func (a *App) handleProblemVisibility(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p, err := a.store.UpdateProblemVisibility(r.Context(), id, *body.Visible)
+	p, err := a.store.UpdateProblemVisibility(r.Context(), id, *body.Visible, body.VisibleFrom, a.currentUserID(r))
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": p.ID, "visible": p.Visible, "visibleFrom": p.VisibleFrom})
+}
+
+// handleProblemBatchVisibility shows or hides several problems at once.
+// Ids that are not positive integers are ignored; any visibleFrom schedule
+// is cleared.
+func (a *App) handleProblemBatchVisibility(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		IDs     []any `json:"ids"`
+		Visible *bool `json:"visible"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if len(body.IDs) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Ids are required"})
+		return
+	}
+	ids := make([]int, 0, len(body.IDs))
+	for _, v := range body.IDs {
+		if id, ok := parseIntAny(v); ok && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Ids are invalid"})
+		return
+	}
+	if body.Visible == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Visible flag is required"})
+		return
+	}
+
+	count, err := a.store.BatchSetProblemVisibility(r.Context(), ids, *body.Visible, a.currentUserID(r))
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"count": count})
+}
+
+// handleProblemTagsAssign adds and removes tags on several problems at once.
+// Unlike a full update it leaves the rest of each problem, including its
+// test cases, alone.
+func (a *App) handleProblemTagsAssign(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		IDs    []any `json:"ids"`
+		Add    any   `json:"add"`
+		Remove any   `json:"remove"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if len(body.IDs) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Ids are required"})
+		return
+	}
+	ids := make([]int, 0, len(body.IDs))
+	for _, v := range body.IDs {
+		if id, ok := parseIntAny(v); ok && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Ids are invalid"})
+		return
+	}
+	add := normalizeStringList(body.Add)
+	remove := normalizeStringList(body.Remove)
+	if len(add) == 0 && len(remove) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Tags to add or remove are required"})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"id": p.ID, "visible": p.Visible})
+	if add == nil {
+		add = []string{}
+	}
+	if remove == nil {
+		remove = []string{}
+	}
+
+	count, err := a.store.AssignProblemTags(r.Context(), ids, add, remove, a.currentUserID(r))
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"count": count})
 }
 
 func (a *App) handleProblemDelete(w http.ResponseWriter, r *http.Request) {
@@ -935,7 +1560,7 @@ func (a *App) handleProblemDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := a.store.DeleteProblemCascade(r.Context(), id); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"success": true})
@@ -948,16 +1573,21 @@ func (a *App) handleProblemClone(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var body struct {
-		Title string `json:"title"`
+		Title      string `json:"title"`
+		AllowEmpty bool   `json:"allowEmpty"`
 	}
 	_ = readJSON(r, &body)
-	created, err := a.store.CloneProblem(r.Context(), id, body.Title)
+	created, err := a.store.CloneProblem(r.Context(), id, body.Title, body.AllowEmpty, a.currentUserID(r))
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		if errors.Is(err, store.ErrNoTestCases) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": noTestCasesError})
+			return
+		}
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, created)
@@ -994,7 +1624,7 @@ func (a *App) handleSubmissionList(w http.ResponseWriter, r *http.Request) {
 		ExcludeContest: excludeContest,
 	})
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, items)
@@ -1015,7 +1645,7 @@ func (a *App) handleSubmissionDetail(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Submission not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -1033,6 +1663,9 @@ func (a *App) handleSubmissionDetail(w http.ResponseWriter, r *http.Request) {
 		Output         string `json:"output"`
 		Input          string `json:"input,omitempty"`
 		ExpectedOutput string `json:"expectedOutput,omitempty"`
+		Stderr         string `json:"stderr,omitempty"`
+		ExitCode       int    `json:"exitCode,omitempty"`
+		Signal         string `json:"signal,omitempty"`
 	}
 
 	var rawResults []store.JudgeCaseResult
@@ -1056,6 +1689,9 @@ func (a *App) handleSubmissionDetail(w http.ResponseWriter, r *http.Request) {
 				item.Input = "N/A"
 				item.ExpectedOutput = "N/A"
 			}
+			item.Stderr = res.Stderr
+			item.ExitCode = res.ExitCode
+			item.Signal = res.Signal
 		}
 		outCases = append(outCases, item)
 	}
@@ -1069,6 +1705,7 @@ func (a *App) handleSubmissionDetail(w http.ResponseWriter, r *http.Request) {
 		"language":   sub.Language,
 		"code":       sub.Code,
 		"output":     sub.Output,
+		"label":      sub.Label,
 		"createdAt":  sub.CreatedAt,
 		"problem": map[string]any{
 			"id":    sub.Problem.ID,
@@ -1086,67 +1723,118 @@ func (a *App) handleSubmissionDetail(w http.ResponseWriter, r *http.Request) {
 
 func (a *App) handleSubmissionCreate(w http.ResponseWriter, r *http.Request) {
 	u, _ := a.currentUser(r)
+	if !a.checkCanSubmit(w, r, u) {
+		return
+	}
 
+	var raw map[string]any
+	if err := readJSON(r, &raw); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	token, _ := raw["cfToken"].(string)
+	if !a.checkSubmissionCaptcha(w, r, token) {
+		return
+	}
+	problemID, okPID := parseIntAny(raw["problemId"])
+	code, _ := raw["code"].(string)
+	language, _ := raw["language"].(string)
+	if !okPID || strings.TrimSpace(code) == "" || strings.TrimSpace(language) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid payload"})
+		return
+	}
+
+	contestIDVal, hasContest := raw["contestId"]
+	var contestID *int
+	if hasContest {
+		if id, ok := parseIntAny(contestIDVal); ok && id > 0 {
+			contestID = &id
+		}
+	}
+
+	a.createSubmission(w, r, u, problemID, code, language, contestID)
+}
+
+// checkCanSubmit rejects the request when the user or IP is banned or memory
+// pressure rejects submissions.
+func (a *App) checkCanSubmit(w http.ResponseWriter, r *http.Request, u userClaims) bool {
 	// Check if user is banned
 	user, err := a.store.GetUserByID(r.Context(), u.ID)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Failed to check user status"})
-		return
+		return false
 	}
 	if user.IsBanned {
 		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Your account has been banned"})
-		return
+		return false
 	}
 
 	// Check IP ban
-	clientIP := getClientIP(r)
+	clientIP := a.clientIP(r)
 	isBanned, err := a.store.IsIPBanned(r.Context(), clientIP)
 	if err == nil && isBanned {
 		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Your IP has been banned"})
-		return
+		return false
+	}
+
+	if a.isMemoryThrottled() && a.submitThrottle == submissionThrottleReject {
+		w.Header().Set("X-System-Status", "memory_throttle")
+		log.Printf("[memory-throttle] 内存限流拒绝 user=%d ip=%s path=%s", u.ID, clientIP, r.URL.Path)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"error": "System is under memory pressure. Please submit later.",
+		})
+		return false
 	}
+	return true
+}
 
-	// Check rate limit
-	rateLimit, _ := a.store.GetSubmissionRateLimit(r.Context())
+// checkSubmissionRateLimit rejects the request when the user is over the
+// per-minute submission limit, which a contest may override. Each contest and
+// practice have separate windows. Whitelisted IPs are exempt.
+func (a *App) checkSubmissionRateLimit(w http.ResponseWriter, r *http.Request, u userClaims, contestID *int) bool {
+	rateLimit, _ := a.store.GetEffectiveSubmissionRateLimit(r.Context(), contestID)
 	windowStart := time.Now().Add(-time.Minute)
-	count, err := a.store.CountUserSubmissionsInWindow(r.Context(), u.ID, windowStart)
-	if err == nil && count >= rateLimit {
+	count, err := a.store.CountUserSubmissionsInWindow(r.Context(), u.ID, contestID, windowStart)
+	if err == nil && count >= rateLimit && !a.isIPWhitelisted(r.Context(), a.clientIP(r)) {
+		retryAfter := time.Minute
+		if oldest, err := a.store.OldestUserSubmissionInWindow(r.Context(), u.ID, contestID, windowStart); err == nil {
+			retryAfter = time.Until(oldest.Add(time.Minute))
+		}
+		setRetryAfter(w, retryAfter)
 		writeJSON(w, http.StatusTooManyRequests, map[string]any{
 			"error":  "Rate limit exceeded. Please wait before submitting again.",
 			"limit":  rateLimit,
 			"window": "1 minute",
 		})
-		return
+		return false
 	}
+	return true
+}
 
-	var raw map[string]any
-	if err := readJSON(r, &raw); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
-		return
-	}
-	problemID, okPID := parseIntAny(raw["problemId"])
-	code, _ := raw["code"].(string)
-	language, _ := raw["language"].(string)
-	if !okPID || strings.TrimSpace(code) == "" || strings.TrimSpace(language) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid payload"})
-		return
+// checkSubmissionCaptcha verifies token when captcha is required for
+// submissions.
+func (a *App) checkSubmissionCaptcha(w http.ResponseWriter, r *http.Request, token string) bool {
+	if !a.captchaActionEnabled(r.Context(), captchaActionSubmission) {
+		return true
 	}
-
-	contestIDVal, hasContest := raw["contestId"]
-	var contestID *int
-	if hasContest {
-		if id, ok := parseIntAny(contestIDVal); ok && id > 0 {
-			contestID = &id
-		}
+	ok, errs := a.verifyCaptcha(r, token)
+	if !ok {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Verification failed", "codes": errs})
+		return false
 	}
+	return true
+}
 
+// createSubmission validates the problem and contest, stores the submission
+// and queues it for judging.
+func (a *App) createSubmission(w http.ResponseWriter, r *http.Request, u userClaims, problemID int, code, language string, contestID *int) {
 	p, err := a.store.GetProblemWithTestCases(r.Context(), problemID)
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -1168,6 +1856,22 @@ func (a *App) handleSubmissionCreate(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusForbidden, map[string]any{"error": "Contest ended"})
 			return
 		}
+		// The contest comes from the client. Unless it is running and has
+		// this problem, the submission is practice, so a contest's own rate
+		// limit and window cannot be borrowed for other problems.
+		inContest, err := a.store.ContestHasProblem(r.Context(), contest.ID, problemID)
+		if err != nil {
+			a.writeInternalError(w, r, err)
+			return
+		}
+		if !inContest || now.Before(contest.StartTime) {
+			contestID = nil
+			contestExists = false
+		}
+	}
+
+	if !a.checkSubmissionRateLimit(w, r, u, contestID) {
+		return
 	}
 
 	if contestExists && len(contest.Languages) > 0 {
@@ -1189,6 +1893,11 @@ func (a *App) handleSubmissionCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(a.judgeQueue) == cap(a.judgeQueue) && len(a.judgeOverflow) == cap(a.judgeOverflow) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "The judge queue is full. Please submit later."})
+		return
+	}
+
 	sub, err := a.store.CreateSubmission(r.Context(), store.CreateSubmissionParams{
 		ProblemID: problemID,
 		Code:      code,
@@ -1197,16 +1906,28 @@ func (a *App) handleSubmissionCreate(w http.ResponseWriter, r *http.Request) {
 		ContestID: contestID,
 	})
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
 	problemForJudge := p
 	subID := sub.ID
 	select {
-	case a.judgeQueue <- judgeTask{submissionID: subID, problem: problemForJudge, code: code, language: language}:
+	case a.judgeQueue <- judgeTask{submissionID: subID, problem: problemForJudge, code: code, language: language, contestID: contestID}:
+	case a.judgeOverflow <- struct{}{}:
+		go func() {
+			defer func() { <-a.judgeOverflow }()
+			a.waitForMemoryPressure()
+			a.judgeSubmission(subID, problemForJudge, code, language, contestID)
+		}()
 	default:
-		go a.judgeSubmission(subID, problemForJudge, code, language)
+		// Both filled up since the check above.
+		log.Printf("[judge] queue full, submission %d not judged", subID)
+		_ = a.store.UpdateSubmissionStatus(context.Background(), subID, "System Error", "The judge queue was full. Please resubmit.")
+		a.notifySubmissionJudged(subID, problemForJudge.ID, language, "System Error", 0)
+	}
+	if a.isMemoryThrottled() {
+		w.Header().Set("X-System-Status", "memory_throttle")
 	}
 
 	writeJSON(w, http.StatusOK, sub)
@@ -1215,7 +1936,7 @@ func (a *App) handleSubmissionCreate(w http.ResponseWriter, r *http.Request) {
 func (a *App) handleRunCode(w http.ResponseWriter, r *http.Request) {
 	u, ok := a.currentUser(r)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Authentication required")
 		return
 	}
 
@@ -1229,7 +1950,7 @@ func (a *App) handleRunCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	clientIP := getClientIP(r)
+	clientIP := a.clientIP(r)
 	isBanned, err := a.store.IsIPBanned(r.Context(), clientIP)
 	if err == nil && isBanned {
 		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Your IP has been banned"})
@@ -1245,29 +1966,19 @@ func (a *App) handleRunCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	allowed, limit, used, err := a.allowCodeRun(r.Context(), u.ID)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Failed to check rate limit"})
-		return
-	}
-	if !allowed {
-		writeJSON(w, http.StatusTooManyRequests, map[string]any{
-			"error":  "Code run rate limit exceeded. Please wait before testing again.",
-			"limit":  limit,
-			"used":   used,
-			"window": "1 minute",
-		})
+	if !a.checkCodeRunLimit(w, r, u.ID, clientIP) {
 		return
 	}
 
 	var body struct {
-		ProblemID int    `json:"problemId"`
-		Language  string `json:"language"`
-		Code      string `json:"code"`
-		Input     string `json:"input"`
+		ProblemID  int    `json:"problemId"`
+		Language   string `json:"language"`
+		Code       string `json:"code"`
+		Input      string `json:"input"`
+		UseSamples bool   `json:"useSamples"`
 	}
 	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+		writeBodyError(w, err)
 		return
 	}
 	if body.ProblemID <= 0 || strings.TrimSpace(body.Code) == "" || strings.TrimSpace(body.Language) == "" {
@@ -1281,39 +1992,51 @@ func (a *App) handleRunCode(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
-	timeLimit := p.TimeLimit
-	if len(p.Config) > 0 {
-		var cfg map[string]map[string]any
-		if json.Unmarshal(p.Config, &cfg) == nil {
-			if langCfg, ok := cfg[body.Language]; ok {
-				if tl, ok := parseIntAny(langCfg["timeLimit"]); ok && tl > 0 {
-					timeLimit = tl
-				}
-			}
-		}
-	}
-
-	opts := judger.Options{
-		TimeLimitMs:    timeLimit,
-		MemoryLimitMB:  p.MemoryLimit,
-		CompileOptions: p.DefaultCompileOptions,
-	}
+	opts := a.judgeOptions(r.Context(), p.Problem, body.Language)
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
 	defer cancel()
 
+	if body.UseSamples {
+		a.runCodeSamples(ctx, w, r, u, p, body.Language, body.Code, opts)
+		return
+	}
+	a.runCodeInput(ctx, w, r, body.Language, body.Code, body.Input, opts)
+}
+
+// writeJudgeError reports a failed Judge call and returns true if err was
+// set: 503 while Docker is unreachable, otherwise a logged 500. The System
+// Error result that comes with the error is not a verdict on the code.
+func (a *App) writeJudgeError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, judger.ErrDockerUnavailable) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "Judge service temporarily unavailable"})
+		return true
+	}
+	a.writeInternalError(w, r, err)
+	return true
+}
+
+// runCodeInput runs code once against caller-supplied input and writes the
+// verdict, output and resource usage.
+func (a *App) runCodeInput(ctx context.Context, w http.ResponseWriter, r *http.Request, language, code, input string, opts judger.Options) {
 	testCases := []judger.TestCase{
 		{
-			Input:          body.Input,
+			Input:          input,
 			ExpectedOutput: "",
 		},
 	}
 
-	judgeRes, _ := a.docker.Judge(ctx, body.Language, body.Code, testCases, opts)
+	judgeRes, err := a.docker.Judge(ctx, language, code, testCases, opts)
+	if a.writeJudgeError(w, r, err) {
+		return
+	}
 
 	if judgeRes.Status != "Judged" || len(judgeRes.Results) == 0 {
 		writeJSON(w, http.StatusOK, map[string]any{
@@ -1324,62 +2047,182 @@ func (a *App) handleRunCode(w http.ResponseWriter, r *http.Request) {
 	}
 
 	res := judgeRes.Results[0]
+	// The input is the caller's own, so their stderr is safe to show.
+	output := res.Output
+	if res.Status == "Runtime Error" && res.Stderr != "" {
+		output = res.Stderr
+	}
 	writeJSON(w, http.StatusOK, map[string]any{
 		"status":     res.Status,
-		"output":     res.Output,
+		"output":     output,
 		"timeUsed":   res.TimeUsed,
 		"memoryUsed": res.MemoryUsed,
 	})
 }
 
-func (a *App) judgeSubmission(submissionID int, p store.ProblemWithTestCases, code string, language string) {
+// runCodeSampleCases is how many leading test cases a sample run uses until
+// problems can flag their sample cases explicitly.
+const runCodeSampleCases = 3
+
+// runCodeSamples judges code against the problem's first test cases without
+// creating a submission. Inputs and outputs are withheld because the cases
+// may be hidden; only per-case verdicts and resource usage are returned.
+func (a *App) runCodeSamples(ctx context.Context, w http.ResponseWriter, r *http.Request, u userClaims, p store.ProblemWithTestCases, language, code string, opts judger.Options) {
+	if !p.IsPublic() && u.Role != "ADMIN" {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
+		return
+	}
+	samples := p.TestCases
+	if len(samples) > runCodeSampleCases {
+		samples = samples[:runCodeSampleCases]
+	}
+	if len(samples) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Problem has no sample test cases"})
+		return
+	}
+	testCases := make([]judger.TestCase, 0, len(samples))
+	for _, tc := range samples {
+		testCases = append(testCases, judger.TestCase{Input: tc.Input, ExpectedOutput: tc.ExpectedOutput})
+	}
+
+	judgeRes, err := a.docker.Judge(ctx, language, code, testCases, opts)
+	if a.writeJudgeError(w, r, err) {
+		return
+	}
+	if judgeRes.Status != "Judged" {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"status": judgeRes.Status,
+			"output": judgeRes.Output,
+			"total":  len(testCases),
+		})
+		return
+	}
+
+	status := "Accepted"
+	passed := 0
+	cases := make([]map[string]any, 0, len(judgeRes.Results))
+	for i, res := range judgeRes.Results {
+		if res.Status == "Accepted" {
+			passed++
+		} else if status == "Accepted" {
+			status = res.Status
+		}
+		cases = append(cases, map[string]any{
+			"case":       i + 1,
+			"status":     res.Status,
+			"passed":     res.Status == "Accepted",
+			"timeUsed":   res.TimeUsed,
+			"memoryUsed": res.MemoryUsed,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":  status,
+		"passed":  passed,
+		"total":   len(testCases),
+		"results": cases,
+	})
+}
+
+// judgeOptions resolves the judger options for a problem and language. A
+// config that no longer parses falls back to the problem defaults rather
+// than failing the submission.
+func (a *App) judgeOptions(ctx context.Context, p store.Problem, language string) judger.Options {
+	cfg, err := store.ParseProblemConfig(p.Config)
+	if err != nil {
+		log.Printf("[judge] problem %d has invalid config, using defaults: %v", p.ID, err)
+	}
+	timeLimit, memoryLimit := cfg.LimitsFor(language, p.TimeLimit, p.MemoryLimit)
+	var checker *judger.Checker
+	if cfg.Checker != nil {
+		checker = &judger.Checker{Language: cfg.Checker.Language, Code: cfg.Checker.Code}
+	}
+	return judger.Options{
+		TimeLimitMs:    timeLimit,
+		MemoryLimitMB:  memoryLimit,
+		CompileOptions: a.compileOptionsFor(ctx, p.DefaultCompileOptions, language),
+		CompileTimeout: a.compileTimeout,
+		CompareMode:    cfg.CompareMode,
+		Checker:        checker,
+	}
+}
+
+// judgeSubmission judges a queued submission and stores the verdict.
+// contestID is the submission's contest, whose cached leaderboard is
+// dropped once the verdict is saved.
+func (a *App) judgeSubmission(submissionID int, p store.ProblemWithTestCases, code string, language string, contestID *int) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
+	if contestID != nil {
+		defer a.invalidateContestLeaderboard(*contestID)
+	}
 
 	if len(p.TestCases) == 0 {
 		_ = a.store.UpdateSubmissionStatus(ctx, submissionID, "System Error", "No test cases found during judging.")
+		a.notifySubmissionJudged(submissionID, p.ID, language, "System Error", 0)
 		return
 	}
 
-	timeLimit := p.TimeLimit
-	if len(p.Config) > 0 {
-		var cfg map[string]map[string]any
-		if json.Unmarshal(p.Config, &cfg) == nil {
-			if langCfg, ok := cfg[language]; ok {
-				if tl, ok := parseIntAny(langCfg["timeLimit"]); ok && tl > 0 {
-					timeLimit = tl
-				}
-			}
-		}
-	}
-
 	testCases := make([]judger.TestCase, 0, len(p.TestCases))
 	for _, tc := range p.TestCases {
 		testCases = append(testCases, judger.TestCase{Input: tc.Input, ExpectedOutput: tc.ExpectedOutput})
 	}
 
-	opts := judger.Options{
-		TimeLimitMs:    timeLimit,
-		MemoryLimitMB:  p.MemoryLimit,
-		CompileOptions: p.DefaultCompileOptions,
+	opts := a.judgeOptions(ctx, p.Problem, language)
+	// Judging marks the submission as picked up by a worker; the final
+	// verdict below always overwrites it.
+	_ = a.store.UpdateSubmissionStatus(ctx, submissionID, "Judging", "")
+	// Large batches against a slow limit can outlast the default deadline;
+	// give the judge as long as its container is allowed to live.
+	judgeTimeout := judger.ContainerLifetime(len(testCases), opts)
+	if judgeTimeout < 10*time.Minute {
+		judgeTimeout = 10 * time.Minute
+	}
+	judgeCtx, judgeCancel := context.WithTimeout(context.Background(), judgeTimeout)
+	defer judgeCancel()
+	judgeRes, err := a.docker.Judge(judgeCtx, language, code, testCases, opts)
+	// Docker being down says nothing about the code: keep the submission
+	// Pending and retry with backoff instead of failing the whole queue.
+	// Other judge failures are retried a few times. Either way the
+	// submission ends as a System Error the student can resubmit once the
+	// retries run out.
+	backoff, failures, dockerRetries := judgeRetryInitialBackoff, 0, 0
+	for err != nil {
+		wait := judgeRetryInitialBackoff
+		if errors.Is(err, judger.ErrDockerUnavailable) {
+			dockerRetries++
+			if dockerRetries > judgeDockerRetries {
+				log.Printf("[judge] docker still unavailable after %d retries, giving up on submission %d", judgeDockerRetries, submissionID)
+				judgeRes = judger.JudgeResult{Status: "System Error", Output: "The judge service is unavailable. Please resubmit later."}
+				break
+			}
+			wait, backoff = backoff, min(backoff*2, judgeRetryMaxBackoff)
+			_ = a.store.UpdateSubmissionStatus(context.Background(), submissionID, "Pending", "Judge service temporarily unavailable, waiting to retry.")
+			log.Printf("[judge] docker unavailable, submission %d retrying in %s", submissionID, wait)
+		} else {
+			failures++
+			if failures > judgeFailureRetries {
+				log.Printf("[judge] submission %d failed after %d retries: %v", submissionID, judgeFailureRetries, err)
+				judgeRes = judger.JudgeResult{Status: "System Error", Output: "Judging failed because of a server problem. Please resubmit."}
+				break
+			}
+			_ = a.store.UpdateSubmissionStatus(context.Background(), submissionID, "Pending", "Judging failed, waiting to retry.")
+			log.Printf("[judge] submission %d failed, retrying in %s: %v", submissionID, wait, err)
+		}
+		time.Sleep(wait)
+		retryCtx, retryCancel := context.WithTimeout(context.Background(), judgeTimeout)
+		_ = a.store.UpdateSubmissionStatus(retryCtx, submissionID, "Judging", "")
+		judgeRes, err = a.docker.Judge(retryCtx, language, code, testCases, opts)
+		retryCancel()
 	}
-	judgeRes, _ := a.docker.Judge(ctx, language, code, testCases, opts)
 
-	finalStatus := "Accepted"
+	var finalStatus, output string
 	maxTime := 0
 	maxMemory := 0
-	passed := 0
 	results := judgeRes.Results
-	output := ""
 
 	if judgeRes.Status == "Judged" {
+		finalStatus, output = finalVerdict(results)
 		for _, r := range results {
-			if r.Status == "Accepted" {
-				passed++
-			} else if finalStatus == "Accepted" {
-				finalStatus = r.Status
-				output = r.Output
-			}
 			if r.TimeUsed > maxTime {
 				maxTime = r.TimeUsed
 			}
@@ -1387,42 +2230,75 @@ func (a *App) judgeSubmission(submissionID int, p store.ProblemWithTestCases, co
 				maxMemory = r.MemoryUsed
 			}
 		}
-		if finalStatus == "Accepted" {
-			output = "All test cases passed"
-		}
 	} else {
 		finalStatus = judgeRes.Status
 		output = judgeRes.Output
 		results = nil
 	}
 
-	score := 0
-	if len(p.TestCases) > 0 {
-		score = int(float64(passed) / float64(len(p.TestCases)) * 100.0)
-	}
+	cfg, _ := store.ParseProblemConfig(p.Config)
+	score := submissionScore(cfg, results, len(p.TestCases))
 
-	var resultsJSON json.RawMessage
+	var resultsJSON, summaryJSON json.RawMessage
 	if results != nil {
 		if b, err := json.Marshal(results); err == nil {
 			resultsJSON = b
 		}
+		if b, err := json.Marshal(summarizeVerdicts(results)); err == nil {
+			summaryJSON = b
+		}
 	}
 
-	_ = a.store.UpdateSubmissionJudged(ctx, store.UpdateSubmissionJudgedParams{
-		ID:            submissionID,
-		Status:        finalStatus,
-		TimeUsed:      maxTime,
-		MemoryUsed:    maxMemory,
-		Score:         score,
-		TestCaseJSON:  resultsJSON,
-		OutputMessage: output,
+	// ctx may have expired while waiting for Docker to come back.
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer saveCancel()
+	_ = a.store.UpdateSubmissionJudged(saveCtx, store.UpdateSubmissionJudgedParams{
+		ID:             submissionID,
+		Status:         finalStatus,
+		TimeUsed:       maxTime,
+		MemoryUsed:     maxMemory,
+		Score:          score,
+		TestCaseJSON:   resultsJSON,
+		OutputMessage:  output,
+		VerdictSummary: summaryJSON,
 	})
+	a.notifySubmissionJudged(submissionID, p.ID, language, finalStatus, score)
+}
+
+// finalVerdict returns the status and output of the first test case that
+// was not accepted, or Accepted when every case passed.
+func finalVerdict(results []judger.CaseResult) (status, output string) {
+	for _, r := range results {
+		if r.Status != "Accepted" {
+			return r.Status, r.Output
+		}
+	}
+	return "Accepted", "All test cases passed"
+}
+
+// verdictSummary counts the verdicts of a judged submission's test cases,
+// so "8/10 passed, 2 TLE" can be shown whatever the final status is.
+type verdictSummary struct {
+	Total  int            `json:"total"`
+	Passed int            `json:"passed"`
+	Counts map[string]int `json:"counts"`
+}
+
+func summarizeVerdicts(results []judger.CaseResult) verdictSummary {
+	s := verdictSummary{Total: len(results), Counts: map[string]int{}}
+	for _, r := range results {
+		s.Counts[r.Status]++
+		if r.Status == "Accepted" {
+			s.Passed++
+		}
+	}
+	return s
 }
 
 func (a *App) handleRegistrationGet(w http.ResponseWriter, r *http.Request) {
 	enabled, err := a.store.IsRegistrationEnabled(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"enabled": enabled})
@@ -1432,8 +2308,8 @@ func (a *App) handleRegistrationPut(w http.ResponseWriter, r *http.Request) {
 	var body struct {
 		Enabled *bool `json:"enabled"`
 	}
-	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
 		return
 	}
 	if body.Enabled == nil {
@@ -1442,7 +2318,7 @@ func (a *App) handleRegistrationPut(w http.ResponseWriter, r *http.Request) {
 	}
 	enabled, err := a.store.UpsertRegistrationEnabled(r.Context(), *body.Enabled)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"enabled": enabled})
@@ -1451,32 +2327,82 @@ func (a *App) handleRegistrationPut(w http.ResponseWriter, r *http.Request) {
 func (a *App) handleHomepageGet(w http.ResponseWriter, r *http.Request) {
 	content, err := a.store.GetHomepageContent(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"content": content})
+}
+
+func (a *App) handleHomepagePut(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Content string `json:"content"`
+	}
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	content, err := a.store.UpsertHomepageContent(r.Context(), body.Content)
+	if err != nil {
+		a.writeInternalError(w, r, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"content": content})
+	writeJSON(w, http.StatusOK, map[string]any{"content": content})
+}
+
+// contestBodyFields are the keys accepted by contest create and update.
+var contestBodyFields = []string{"name", "description", "startTime", "endTime", "rule", "problemIds", "languages", "isPublished", "password", "submissionRateLimit", "scoringMode", "wrongSubmissionPenalty"}
+
+// parseContestPenalty reads the optional wrongSubmissionPenalty of a contest
+// body, which only OI contests may set above zero.
+func parseContestPenalty(v any, rule string) (*int, string) {
+	if v == nil {
+		return nil, ""
+	}
+	n, ok := parseIntAny(v)
+	if !ok || n < 0 || n > 100 {
+		return nil, "Wrong submission penalty must be between 0 and 100"
+	}
+	if n > 0 && rule != "OI" {
+		return nil, "Wrong submission penalty only applies to OI contests"
+	}
+	return &n, ""
 }
 
-func (a *App) handleHomepagePut(w http.ResponseWriter, r *http.Request) {
-	var body struct {
-		Content string `json:"content"`
+// parseContestScoringMode reads the optional scoringMode of a contest body,
+// returning "" when it is absent.
+func parseContestScoringMode(v any) (string, bool) {
+	if v == nil {
+		return "", true
 	}
-	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
-		return
+	mode, _ := v.(string)
+	mode = strings.ToUpper(strings.TrimSpace(mode))
+	if !slices.Contains(store.ContestScoringModes, mode) {
+		return "", false
 	}
-	content, err := a.store.UpsertHomepageContent(r.Context(), body.Content)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
-		return
+	return mode, true
+}
+
+// parseContestRateLimit reads the optional submissionRateLimit of a contest
+// body. Null means the global limit applies.
+func parseContestRateLimit(v any) (*int, bool) {
+	if v == nil {
+		return nil, true
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"content": content})
+	n, ok := parseIntAny(v)
+	if !ok || n < 1 || n > 100 {
+		return nil, false
+	}
+	return &n, true
 }
 
 func (a *App) handleContestCreate(w http.ResponseWriter, r *http.Request) {
 	var raw map[string]any
 	if err := readJSON(r, &raw); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+		writeBodyError(w, err)
+		return
+	}
+	if err := checkKnownFields(raw, contestBodyFields...); err != nil {
+		writeBodyError(w, err)
 		return
 	}
 	name, _ := raw["name"].(string)
@@ -1517,7 +2443,7 @@ func (a *App) handleContestCreate(w http.ResponseWriter, r *http.Request) {
 		if pw != "" {
 			b, err := bcrypt.GenerateFromPassword([]byte(pw), 10)
 			if err != nil {
-				writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+				a.writeInternalError(w, r, err)
 				return
 			}
 			s := string(b)
@@ -1533,24 +2459,50 @@ func (a *App) handleContestCreate(w http.ResponseWriter, r *http.Request) {
 	languages := normalizeAllowedLanguages(raw["languages"])
 	problemIDs := normalizeIntList(raw["problemIds"])
 
+	rateLimit, ok := parseContestRateLimit(raw["submissionRateLimit"])
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Submission rate limit must be between 1 and 100"})
+		return
+	}
+	scoringMode, ok := parseContestScoringMode(raw["scoringMode"])
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid scoring mode"})
+		return
+	}
+	if scoringMode == "" {
+		scoringMode = store.ContestScoringDefault
+	}
+	penalty, msg := parseContestPenalty(raw["wrongSubmissionPenalty"], rule)
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": msg})
+		return
+	}
+	if penalty == nil {
+		penalty = new(int)
+	}
+
 	createdID, err := a.store.CreateContest(r.Context(), store.CreateContestParams{
-		Name:         name,
-		Description:  description,
-		StartTime:    start,
-		EndTime:      end,
-		Rule:         rule,
-		PasswordHash: passwordHash,
-		IsPublished:  isPublished,
-		Languages:    languages,
-		ProblemIDs:   problemIDs,
+		Name:                   name,
+		Description:            description,
+		StartTime:              start,
+		EndTime:                end,
+		Rule:                   rule,
+		PasswordHash:           passwordHash,
+		IsPublished:            isPublished,
+		Languages:              languages,
+		ProblemIDs:             problemIDs,
+		CreatedBy:              a.currentUserID(r),
+		SubmissionRateLimit:    rateLimit,
+		ScoringMode:            scoringMode,
+		WrongSubmissionPenalty: *penalty,
 	})
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	withProblems, err := a.store.GetContestAdmin(r.Context(), createdID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, withProblems)
@@ -1562,7 +2514,7 @@ func (a *App) handleContestBatchPublish(w http.ResponseWriter, r *http.Request)
 		Published any   `json:"published"`
 	}
 	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+		writeBodyError(w, err)
 		return
 	}
 	if len(body.IDs) == 0 {
@@ -1588,7 +2540,7 @@ func (a *App) handleContestBatchPublish(w http.ResponseWriter, r *http.Request)
 
 	count, err := a.store.BatchSetContestPublished(r.Context(), ids, published)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"count": count})
@@ -1617,7 +2569,7 @@ func (a *App) handleContestExport(w http.ResponseWriter, r *http.Request) {
 
 	submissions, err := a.store.ListContestSubmissionsForExport(r.Context(), contestID, pid, uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	if len(submissions) == 0 {
@@ -1696,7 +2648,7 @@ func (a *App) handleContestPublicList(w http.ResponseWriter, r *http.Request) {
 		items, total, err = a.store.ListPublishedContestsPaged(r.Context(), filter, userID, page, pageSize)
 	}
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -1722,7 +2674,7 @@ func (a *App) handleContestPublicDetail(w http.ResponseWriter, r *http.Request)
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Contest not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -1734,7 +2686,7 @@ func (a *App) handleContestPublicDetail(w http.ResponseWriter, r *http.Request)
 		}
 		joined, err := a.store.HasContestParticipant(r.Context(), id, u.ID)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			a.writeInternalError(w, r, err)
 			return
 		}
 		if !joined {
@@ -1744,7 +2696,7 @@ func (a *App) handleContestPublicDetail(w http.ResponseWriter, r *http.Request)
 	} else if contest.HasPassword {
 		joined, err := a.store.HasContestParticipant(r.Context(), id, u.ID)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			a.writeInternalError(w, r, err)
 			return
 		}
 		if !joined {
@@ -1762,7 +2714,12 @@ func (a *App) handleContestPublicProblem(w http.ResponseWriter, r *http.Request)
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid contest id"})
 		return
 	}
-	order, okOrder := parseIntParam(chi.URLParam(r, "order"))
+	// The problem may be given by numeric order or by letter label (A, B, ...).
+	orderParam := chi.URLParam(r, "order")
+	order, okOrder := parseIntParam(orderParam)
+	if !okOrder {
+		order, okOrder = store.ParseContestProblemLabel(orderParam)
+	}
 	if !okOrder || order < 0 {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid problem order"})
 		return
@@ -1774,7 +2731,7 @@ func (a *App) handleContestPublicProblem(w http.ResponseWriter, r *http.Request)
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Contest not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	if !contest.IsPublished {
@@ -1789,7 +2746,7 @@ func (a *App) handleContestPublicProblem(w http.ResponseWriter, r *http.Request)
 		}
 		joined, err := a.store.HasContestParticipant(r.Context(), id, u.ID)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			a.writeInternalError(w, r, err)
 			return
 		}
 		if !joined {
@@ -1799,7 +2756,7 @@ func (a *App) handleContestPublicProblem(w http.ResponseWriter, r *http.Request)
 	} else if contest.PasswordHash != nil {
 		joined, err := a.store.HasContestParticipant(r.Context(), id, u.ID)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			a.writeInternalError(w, r, err)
 			return
 		}
 		if !joined {
@@ -1813,7 +2770,7 @@ func (a *App) handleContestPublicProblem(w http.ResponseWriter, r *http.Request)
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	p, err := a.store.GetProblemWithTestCases(r.Context(), pid)
@@ -1822,7 +2779,7 @@ func (a *App) handleContestPublicProblem(w http.ResponseWriter, r *http.Request)
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, p)
@@ -1840,7 +2797,7 @@ func (a *App) handleContestPublicAttachmentsList(w http.ResponseWriter, r *http.
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Contest not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	if !contest.IsPublished {
@@ -1855,7 +2812,7 @@ func (a *App) handleContestPublicAttachmentsList(w http.ResponseWriter, r *http.
 		}
 		joined, err := a.store.HasContestParticipant(r.Context(), id, u.ID)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			a.writeInternalError(w, r, err)
 			return
 		}
 		if !joined {
@@ -1865,7 +2822,7 @@ func (a *App) handleContestPublicAttachmentsList(w http.ResponseWriter, r *http.
 	} else if contest.PasswordHash != nil {
 		joined, err := a.store.HasContestParticipant(r.Context(), id, u.ID)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			a.writeInternalError(w, r, err)
 			return
 		}
 		if !joined {
@@ -1913,7 +2870,7 @@ func (a *App) handleContestPublicAttachmentDownload(w http.ResponseWriter, r *ht
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Contest not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	if !contest.IsPublished {
@@ -1928,7 +2885,7 @@ func (a *App) handleContestPublicAttachmentDownload(w http.ResponseWriter, r *ht
 		}
 		joined, err := a.store.HasContestParticipant(r.Context(), id, u.ID)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			a.writeInternalError(w, r, err)
 			return
 		}
 		if !joined {
@@ -1938,7 +2895,7 @@ func (a *App) handleContestPublicAttachmentDownload(w http.ResponseWriter, r *ht
 	} else if contest.PasswordHash != nil {
 		joined, err := a.store.HasContestParticipant(r.Context(), id, u.ID)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			a.writeInternalError(w, r, err)
 			return
 		}
 		if !joined {
@@ -1964,6 +2921,11 @@ func (a *App) handleContestAttachmentUpload(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	if err := r.ParseMultipartForm(16 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, errCodeBodyTooLarge, "Request body too large")
+			return
+		}
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid form"})
 		return
 	}
@@ -2022,7 +2984,7 @@ func (a *App) handleContestPublicLeaderboard(w http.ResponseWriter, r *http.Requ
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Contest not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	if !contest.IsPublished {
@@ -2044,9 +3006,16 @@ func (a *App) handleContestPublicLeaderboard(w http.ResponseWriter, r *http.Requ
 			sortBy = "submissionCount"
 		}
 	}
-	items, total, err := a.store.ListContestLeaderboardPaged(r.Context(), id, contest.Rule, page, pageSize, sortBy, asc)
+	items, total, err := a.contestLeaderboardPage(r.Context(), contest, leaderboardKey{
+		contestID:    contest.ID,
+		page:         page,
+		pageSize:     pageSize,
+		sortBy:       sortBy,
+		asc:          asc,
+		scoreVisible: scoreVisible,
+	})
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	type row struct {
@@ -2090,7 +3059,7 @@ func (a *App) handleContestJoin(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Contest not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -2106,11 +3075,12 @@ func (a *App) handleContestJoin(w http.ResponseWriter, r *http.Request) {
 
 		attempt, found, err := a.store.GetContestPasswordAttempt(r.Context(), id, u.ID)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			a.writeInternalError(w, r, err)
 			return
 		}
 		now := time.Now()
 		if found && attempt.LastFailedAt != nil && now.Sub(*attempt.LastFailedAt) <= window && attempt.FailedCount >= maxAttempts {
+			setRetryAfter(w, attempt.LastFailedAt.Add(window).Sub(now))
 			writeJSON(w, http.StatusTooManyRequests, map[string]any{
 				"error":             "Too many incorrect attempts, please try again later",
 				"remainingAttempts": 0,
@@ -2124,7 +3094,7 @@ func (a *App) handleContestJoin(w http.ResponseWriter, r *http.Request) {
 				newCount = attempt.FailedCount + 1
 			}
 			if _, err := a.store.UpsertContestPasswordAttempt(r.Context(), id, u.ID, newCount, now); err != nil {
-				writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+				a.writeInternalError(w, r, err)
 				return
 			}
 			remaining := max(0, maxAttempts-newCount)
@@ -2138,7 +3108,7 @@ func (a *App) handleContestJoin(w http.ResponseWriter, r *http.Request) {
 				newCount = attempt.FailedCount + 1
 			}
 			if _, err := a.store.UpsertContestPasswordAttempt(r.Context(), id, u.ID, newCount, now); err != nil {
-				writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+				a.writeInternalError(w, r, err)
 				return
 			}
 			remaining := max(0, maxAttempts-newCount)
@@ -2152,7 +3122,7 @@ func (a *App) handleContestJoin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := a.store.UpsertContestParticipant(r.Context(), id, u.ID); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"success": true})
@@ -2161,7 +3131,7 @@ func (a *App) handleContestJoin(w http.ResponseWriter, r *http.Request) {
 func (a *App) handleContestAdminList(w http.ResponseWriter, r *http.Request) {
 	items, err := a.store.ListContestsAdmin(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, items)
@@ -2179,10 +3149,18 @@ func (a *App) handleContestAdminGet(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Contest not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, contest)
+	editors, err := a.store.GetContestEditors(r.Context(), id)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		store.ContestAdminDetail
+		store.Editors
+	}{contest, editors})
 }
 
 func (a *App) handleContestAdminUpdate(w http.ResponseWriter, r *http.Request) {
@@ -2194,7 +3172,11 @@ func (a *App) handleContestAdminUpdate(w http.ResponseWriter, r *http.Request) {
 
 	var raw map[string]any
 	if err := readJSON(r, &raw); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+		writeBodyError(w, err)
+		return
+	}
+	if err := checkKnownFields(raw, contestBodyFields...); err != nil {
+		writeBodyError(w, err)
 		return
 	}
 	name, _ := raw["name"].(string)
@@ -2248,7 +3230,7 @@ func (a *App) handleContestAdminUpdate(w http.ResponseWriter, r *http.Request) {
 		} else {
 			b, err := bcrypt.GenerateFromPassword([]byte(pw), 10)
 			if err != nil {
-				writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+				a.writeInternalError(w, r, err)
 				return
 			}
 			s := string(b)
@@ -2261,49 +3243,304 @@ func (a *App) handleContestAdminUpdate(w http.ResponseWriter, r *http.Request) {
 		isPublished = &v
 	}
 
+	rateLimitRaw, updateRateLimit := raw["submissionRateLimit"]
+	rateLimit, ok := parseContestRateLimit(rateLimitRaw)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Submission rate limit must be between 1 and 100"})
+		return
+	}
+	scoringMode, ok := parseContestScoringMode(raw["scoringMode"])
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid scoring mode"})
+		return
+	}
+	penalty, msg := parseContestPenalty(raw["wrongSubmissionPenalty"], rule)
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": msg})
+		return
+	}
+
 	err := a.store.UpdateContest(r.Context(), store.UpdateContestParams{
-		ID:             id,
-		Name:           name,
-		Description:    description,
-		StartTime:      start,
-		EndTime:        end,
-		Rule:           rule,
-		Languages:      languages,
-		IsPublished:    isPublished,
-		UpdatePassword: updatePassword,
-		PasswordHash:   passwordHashUpdate,
-		UpdateProblems: hasProblemIDs,
-		ProblemIDs:     problemIDs,
+		ID:                     id,
+		Name:                   name,
+		Description:            description,
+		StartTime:              start,
+		EndTime:                end,
+		Rule:                   rule,
+		Languages:              languages,
+		IsPublished:            isPublished,
+		UpdatePassword:         updatePassword,
+		PasswordHash:           passwordHashUpdate,
+		UpdateProblems:         hasProblemIDs,
+		ProblemIDs:             problemIDs,
+		UpdatedBy:              a.currentUserID(r),
+		UpdateRateLimit:        updateRateLimit,
+		SubmissionRateLimit:    rateLimit,
+		ScoringMode:            scoringMode,
+		WrongSubmissionPenalty: penalty,
 	})
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Contest not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
 	contest, err := a.store.GetContestAdmin(r.Context(), id)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, contest)
 }
 
+func (a *App) handleContestParticipantList(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid contest id"})
+		return
+	}
+	items, err := a.store.ListContestParticipants(r.Context(), id)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	if items == nil {
+		items = []store.ContestParticipant{}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (a *App) handleContestParticipantDisqualify(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid contest id"})
+		return
+	}
+	userID, ok := parseIntParam(chi.URLParam(r, "userId"))
+	if !ok || userID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid user id"})
+		return
+	}
+	var body struct {
+		Disqualified *bool `json:"disqualified"`
+	}
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if body.Disqualified == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "disqualified must be boolean"})
+		return
+	}
+	if err := a.store.SetContestParticipantDisqualified(r.Context(), id, userID, *body.Disqualified); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Participant not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	a.invalidateContestLeaderboard(id)
+	writeJSON(w, http.StatusOK, map[string]any{"contestId": id, "userId": userID, "disqualified": *body.Disqualified})
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// setRetryAfter sets the Retry-After header to d rounded up to whole
+// seconds, and to at least one second.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	secs := int((d + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(max(secs, 1)))
+}
+
+// Machine-readable codes for writeError. Clients should branch on the code
+// and treat the message as display text.
+const (
+	errCodeUnauthorized = "unauthorized"
+	errCodeInvalidToken = "invalid_token"
+	errCodeForbidden    = "forbidden"
+	errCodeBodyTooLarge = "body_too_large"
+	errCodeUnknownField = "unknown_field"
+	errCodeInternal     = "internal"
+	errCodeNotFound     = "not_found"
+	errCodeMethod       = "method_not_allowed"
+	errCodeMaintenance  = "maintenance"
+	errCodeConflict     = "conflict"
+	errCodeInvalidValue = "invalid_value"
+	errCodeTimeout      = "timeout"
+)
+
+// writeStoreClientError answers database rejections of client input: 409
+// when the write clashes with existing rows (duplicate key, or a reference
+// that is missing or still in use), 400 when a value itself is invalid.
+// It returns false for any other error.
+func writeStoreClientError(w http.ResponseWriter, err error) bool {
+	switch err := store.ClassifyError(err); {
+	case errors.Is(err, store.ErrUniqueViolation):
+		writeError(w, http.StatusConflict, errCodeConflict, "A record with the same unique value already exists")
+	case errors.Is(err, store.ErrForeignKeyViolation):
+		writeError(w, http.StatusConflict, errCodeConflict, "A referenced record does not exist or is still in use")
+	case errors.Is(err, store.ErrCheckViolation):
+		writeError(w, http.StatusBadRequest, errCodeInvalidValue, "A value violates a database constraint")
+	case errors.Is(err, store.ErrInvalidValue):
+		writeError(w, http.StatusBadRequest, errCodeInvalidValue, "A value is invalid or out of range")
+	default:
+		return false
+	}
+	return true
+}
+
+// writeError writes the standard error envelope {"error": msg, "code": code}.
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, map[string]any{"error": msg, "code": code})
+}
+
+// writeInternalError logs err with the request id and answers with a generic
+// message plus that id, so clients can report it without seeing SQL or paths.
+// With DebugErrors set, admins also get the raw error as "detail".
+// Store errors caused by the submitted values are not internal: they are
+// answered 409 or 400 via writeStoreClientError instead. An aggregation
+// that hit its query timeout is answered 503 so clients retry later.
+func (a *App) writeInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	if writeStoreClientError(w, err) {
+		return
+	}
+	if errors.Is(err, store.ErrQueryTimeout) {
+		log.Printf("[error] request=%s %s %s: %v", middleware.GetReqID(r.Context()), r.Method, r.URL.Path, err)
+		writeError(w, http.StatusServiceUnavailable, errCodeTimeout, "The query took too long. Please try again later.")
+		return
+	}
+	reqID := middleware.GetReqID(r.Context())
+	log.Printf("[error] request=%s %s %s: %v", reqID, r.Method, r.URL.Path, err)
+	body := map[string]any{
+		"error":     "Internal server error",
+		"code":      errCodeInternal,
+		"requestId": reqID,
+	}
+	if a.debugErrors {
+		if u, ok := a.currentUser(r); ok && u.Role == "ADMIN" {
+			body["detail"] = err.Error()
+		}
+	}
+	writeJSON(w, http.StatusInternalServerError, body)
+}
+
+// limitedBody remembers the unlimited body so a route-level limitBody can
+// replace the limit set by an enclosing router instead of stacking on it.
+// tooLarge is set when the declared Content-Length already exceeds the limit.
+type limitedBody struct {
+	io.ReadCloser
+	orig     io.ReadCloser
+	limit    int64
+	tooLarge bool
+}
+
+// Read fails with *http.MaxBytesError straight away when the declared length
+// is over the limit. Checking here rather than in limitBody lets an inner
+// limitBody raise the limit of an outer one before anything is rejected.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.tooLarge {
+		return 0, &http.MaxBytesError{Limit: b.limit}
+	}
+	return b.ReadCloser.Read(p)
+}
+
+// limitBody caps the request body at n bytes; the innermost limit wins.
+// Bodies that declare a larger Content-Length fail on the first read, others
+// fail with *http.MaxBytesError once the limit is crossed. Either way
+// writeBodyError answers 413.
+func limitBody(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			orig := r.Body
+			if lb, ok := orig.(*limitedBody); ok {
+				orig = lb.orig
+			}
+			r.Body = &limitedBody{
+				ReadCloser: http.MaxBytesReader(w, orig, n),
+				orig:       orig,
+				limit:      n,
+				tooLarge:   r.ContentLength > n,
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeBodyError reports a readJSON failure: 413 if the body limit was hit,
+// otherwise 400.
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, errCodeBodyTooLarge, "Request body too large")
+		return
+	}
+	if field, ok := unknownFieldName(err); ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error": "Unknown field \"" + field + "\"",
+			"code":  errCodeUnknownField,
+			"field": field,
+		})
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+}
+
+// unknownFieldName extracts the field from encoding/json's DisallowUnknownFields error.
+func unknownFieldName(err error) (string, bool) {
+	msg := err.Error()
+	const prefix = "json: unknown field "
+	if !strings.HasPrefix(msg, prefix) {
+		return "", false
+	}
+	return strings.Trim(strings.TrimPrefix(msg, prefix), `"`), true
+}
+
+// errUnknownField mimics the decoder error so map-decoded endpoints can reject
+// keys they do not understand through the same writeBodyError path.
+func errUnknownField(name string) error {
+	return errors.New(`json: unknown field "` + name + `"`)
+}
+
+// checkKnownFields rejects keys of a lenient map body outside allowed.
+func checkKnownFields(raw map[string]any, allowed ...string) error {
+	for k := range raw {
+		known := false
+		for _, a := range allowed {
+			if k == a {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return errUnknownField(k)
+		}
+	}
+	return nil
+}
+
 func readJSON(r *http.Request, dst any) error {
 	defer r.Body.Close()
 	dec := json.NewDecoder(r.Body)
 	return dec.Decode(dst)
 }
 
+// readJSONStrict is readJSON for typed bodies: fields not present in dst are
+// an error instead of being silently dropped.
+func readJSONStrict(r *http.Request, dst any) error {
+	defer r.Body.Close()
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(dst)
+}
+
 func parseIntParam(s string) (int, bool) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -2359,6 +3596,14 @@ func parseTags(q map[string][]string) []string {
 	return nil
 }
 
+// parseTagMode reads tagMode=all|any and reports whether all requested tags
+// must match. Any other value falls back to the default "any", which keeps
+// problems that carry at least one of the requested tags.
+func parseTagMode(q map[string][]string) bool {
+	vals := q["tagMode"]
+	return len(vals) > 0 && strings.EqualFold(strings.TrimSpace(vals[0]), "all")
+}
+
 func splitCSV(s string) []string {
 	parts := strings.Split(s, ",")
 	out := make([]string, 0, len(parts))
@@ -2431,11 +3676,10 @@ func normalizeAllowedLanguages(v any) []string {
 	if len(in) == 0 {
 		return nil
 	}
-	allowed := map[string]struct{}{"cpp": {}, "python": {}}
 	out := make([]string, 0, len(in))
 	for _, l := range in {
 		l = strings.TrimSpace(l)
-		if _, ok := allowed[l]; ok {
+		if _, ok := supportedLanguages[l]; ok {
 			out = append(out, l)
 		}
 	}
@@ -2485,40 +3729,92 @@ func max(a, b int) int {
 	return b
 }
 
-func (a *App) allowCodeRun(ctx context.Context, userID int) (bool, int, int, error) {
+// codeRunCheck is the outcome of allowCodeRun. When a run is rejected,
+// Scope names the window that was full ("user" or "ip") and Limit and Used
+// describe that window.
+type codeRunCheck struct {
+	Allowed bool
+	Scope   string
+	Limit   int
+	Used    int
+	// RetryAfter is how long until the oldest run in the exceeded window
+	// expires. It is only set when the run is rejected.
+	RetryAfter time.Duration
+}
+
+// allowCodeRun applies the per-user code-run limit and, so that many
+// accounts behind one address cannot pool their budgets, a per-IP limit
+// alongside it. Requests from whitelisted IPs are always allowed and not
+// counted.
+func (a *App) allowCodeRun(ctx context.Context, userID int, clientIP string) (codeRunCheck, error) {
 	limit, err := a.store.GetCodeRunRateLimit(ctx)
 	if err != nil {
-		return false, 0, 0, err
+		return codeRunCheck{}, err
 	}
-	now := time.Now()
+	ipLimit, err := a.store.GetCodeRunIPRateLimit(ctx)
+	if err != nil {
+		return codeRunCheck{}, err
+	}
+	if a.isIPWhitelisted(ctx, clientIP) {
+		return codeRunCheck{Allowed: true, Scope: "user", Limit: limit}, nil
+	}
+	return a.takeCodeRunSlot(userID, clientIP, limit, ipLimit, time.Now()), nil
+}
+
+// takeCodeRunSlot records a run at now for userID and clientIP unless
+// either one-minute window already holds limit or ipLimit runs.
+func (a *App) takeCodeRunSlot(userID int, clientIP string, limit, ipLimit int, now time.Time) codeRunCheck {
 	windowStart := now.Add(-time.Minute)
 
 	a.codeRunMu.Lock()
 	defer a.codeRunMu.Unlock()
 
-	times := a.codeRunHistory[userID]
-	pruned := times[:0]
-	for _, ts := range times {
-		if ts.After(windowStart) {
-			pruned = append(pruned, ts)
-		}
+	if len(a.codeRunIPHistory) >= runHistoryIPLimit {
+		sweepRunHistory(a.codeRunIPHistory, windowStart)
 	}
-	times = pruned
-	used := len(times)
-	if used >= limit {
-		a.codeRunHistory[userID] = times
-		return false, limit, used, nil
-	}
-	times = append(times, now)
+	times := pruneRunWindow(a.codeRunHistory[userID], windowStart)
+	ipTimes := pruneRunWindow(a.codeRunIPHistory[clientIP], windowStart)
 	a.codeRunHistory[userID] = times
-	return true, limit, len(times), nil
+	a.codeRunIPHistory[clientIP] = ipTimes
+	// A run rejected by either window counts against neither.
+	if len(times) >= limit {
+		return codeRunCheck{Scope: "user", Limit: limit, Used: len(times), RetryAfter: windowRetryAfter(times, now)}
+	}
+	if len(ipTimes) >= ipLimit {
+		return codeRunCheck{Scope: "ip", Limit: ipLimit, Used: len(ipTimes), RetryAfter: windowRetryAfter(ipTimes, now)}
+	}
+	a.codeRunHistory[userID] = append(times, now)
+	a.codeRunIPHistory[clientIP] = append(ipTimes, now)
+	return codeRunCheck{Allowed: true, Scope: "user", Limit: limit, Used: len(times) + 1}
+}
+
+// checkCodeRunLimit writes a 429 and returns false when allowCodeRun
+// rejects the run.
+func (a *App) checkCodeRunLimit(w http.ResponseWriter, r *http.Request, userID int, clientIP string) bool {
+	check, err := a.allowCodeRun(r.Context(), userID, clientIP)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Failed to check rate limit"})
+		return false
+	}
+	if !check.Allowed {
+		setRetryAfter(w, check.RetryAfter)
+		writeJSON(w, http.StatusTooManyRequests, map[string]any{
+			"error":  "Code run rate limit exceeded. Please wait before testing again.",
+			"scope":  check.Scope,
+			"limit":  check.Limit,
+			"used":   check.Used,
+			"window": "1 minute",
+		})
+		return false
+	}
+	return true
 }
 
 // Footer handlers
 func (a *App) handleFooterGet(w http.ResponseWriter, r *http.Request) {
 	content, err := a.store.GetFooterContent(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"content": content})
@@ -2528,23 +3824,73 @@ func (a *App) handleFooterPut(w http.ResponseWriter, r *http.Request) {
 	var body struct {
 		Content string `json:"content"`
 	}
-	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
 		return
 	}
 	content, err := a.store.UpsertFooterContent(r.Context(), body.Content)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"content": content})
 }
 
+// Banner handlers
+func (a *App) handleBannerGet(w http.ResponseWriter, r *http.Request) {
+	banner, err := a.store.GetBannerSettings(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, banner)
+}
+
+func (a *App) handleBannerPut(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Message  string `json:"message"`
+		Severity string `json:"severity"`
+		Active   bool   `json:"active"`
+	}
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	body.Message = strings.TrimSpace(body.Message)
+	if body.Severity == "" {
+		body.Severity = "info"
+	}
+	if body.Severity != "info" && body.Severity != "warning" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "severity must be info or warning"})
+		return
+	}
+	if len([]rune(body.Message)) > 500 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "message must be at most 500 characters"})
+		return
+	}
+	if body.Active && body.Message == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "An active banner needs a message"})
+		return
+	}
+	now := time.Now()
+	banner, err := a.store.UpsertBannerSettings(r.Context(), store.BannerSettings{
+		Message:   body.Message,
+		Severity:  body.Severity,
+		Active:    body.Active,
+		UpdatedAt: &now,
+	})
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, banner)
+}
+
 // Rate limit handlers
 func (a *App) handleRateLimitGet(w http.ResponseWriter, r *http.Request) {
 	limit, err := a.store.GetSubmissionRateLimit(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"limit": limit})
@@ -2554,8 +3900,8 @@ func (a *App) handleRateLimitPut(w http.ResponseWriter, r *http.Request) {
 	var body struct {
 		Limit int `json:"limit"`
 	}
-	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
 		return
 	}
 	if body.Limit < 1 || body.Limit > 100 {
@@ -2564,7 +3910,7 @@ func (a *App) handleRateLimitPut(w http.ResponseWriter, r *http.Request) {
 	}
 	limit, err := a.store.UpsertSubmissionRateLimit(r.Context(), body.Limit)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"limit": limit})
@@ -2573,30 +3919,51 @@ func (a *App) handleRateLimitPut(w http.ResponseWriter, r *http.Request) {
 func (a *App) handleCodeRunRateLimitGet(w http.ResponseWriter, r *http.Request) {
 	limit, err := a.store.GetCodeRunRateLimit(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"limit": limit})
+	ipLimit, err := a.store.GetCodeRunIPRateLimit(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"limit": limit, "ipLimit": ipLimit})
 }
 
 func (a *App) handleCodeRunRateLimitPut(w http.ResponseWriter, r *http.Request) {
 	var body struct {
 		Limit int `json:"limit"`
+		// IPLimit is left unchanged when omitted.
+		IPLimit *int `json:"ipLimit"`
 	}
-	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
 		return
 	}
 	if body.Limit < 1 || body.Limit > 60 {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Rate limit must be between 1 and 60"})
 		return
 	}
+	if body.IPLimit != nil && (*body.IPLimit < 1 || *body.IPLimit > 600) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "IP rate limit must be between 1 and 600"})
+		return
+	}
 	limit, err := a.store.UpsertCodeRunRateLimit(r.Context(), body.Limit)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"limit": limit})
+	var ipLimit int
+	if body.IPLimit != nil {
+		ipLimit, err = a.store.UpsertCodeRunIPRateLimit(r.Context(), *body.IPLimit)
+	} else {
+		ipLimit, err = a.store.GetCodeRunIPRateLimit(r.Context())
+	}
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"limit": limit, "ipLimit": ipLimit})
 }
 
 func (a *App) handleGetPreferences(w http.ResponseWriter, r *http.Request) {
@@ -2604,7 +3971,7 @@ func (a *App) handleGetPreferences(w http.ResponseWriter, r *http.Request) {
 	// Re-fetch user to get latest preferences
 	user, err := a.store.GetUserByID(r.Context(), u.ID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	// Return empty object if preferences is nil
@@ -2615,29 +3982,74 @@ func (a *App) handleGetPreferences(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"preferences": user.Preferences})
 }
 
+func (a *App) handleUserSolved(w http.ResponseWriter, r *http.Request) {
+	u, _ := a.currentUser(r)
+	items, err := a.store.ListSolvedProblems(r.Context(), u.ID, u.Role != "ADMIN")
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// handlePublicUserSolved lists another user's solved problems, limited to
+// visible problems.
+func (a *App) handlePublicUserSolved(w http.ResponseWriter, r *http.Request) {
+	username := strings.TrimSpace(chi.URLParam(r, "username"))
+	user, err := a.store.GetUserByUsername(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "User not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	items, err := a.store.ListSolvedProblems(r.Context(), user.ID, true)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"username": user.Username,
+		"count":    len(items),
+		"problems": items,
+	})
+}
+
 func (a *App) handleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
 	u, _ := a.currentUser(r)
 	var body struct {
 		Preferences json.RawMessage `json:"preferences"`
 	}
 	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+		writeBodyError(w, err)
+		return
+	}
+	prefs, msg := validatePreferences(body.Preferences, a.strictPrefs)
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": msg})
+		return
+	}
+	stored, err := json.Marshal(prefs)
+	if err != nil {
+		a.writeInternalError(w, r, err)
 		return
 	}
 
-	if err := a.store.UpdateUserPreferences(r.Context(), u.ID, body.Preferences); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+	if err := a.store.UpdateUserPreferences(r.Context(), u.ID, stored); err != nil {
+		a.writeInternalError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{"success": true})
+	writeJSON(w, http.StatusOK, map[string]any{"success": true, "preferences": prefs})
 }
 
 // User management handlers
 func (a *App) handleUserList(w http.ResponseWriter, r *http.Request) {
 	users, err := a.store.ListUsers(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, users)
@@ -2662,7 +4074,7 @@ func (a *App) handleUserBan(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "User not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -2684,9 +4096,10 @@ func (a *App) handleUserBan(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if banErr != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": banErr.Error()})
+		a.writeInternalError(w, r, banErr)
 		return
 	}
+	a.notifyBanned(r.Context(), id, body.Reason)
 
 	response := map[string]any{"success": true}
 	if body.BanIP && bannedIPCount > 0 {
@@ -2707,7 +4120,7 @@ func (a *App) handleUserUnban(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "User not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -2733,7 +4146,7 @@ func (a *App) handleUserDelete(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "User not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -2744,7 +4157,7 @@ func (a *App) handleUserDelete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := a.store.DeleteUser(r.Context(), id); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -2760,7 +4173,7 @@ func (a *App) handleUserDeleteSubmissions(w http.ResponseWriter, r *http.Request
 
 	count, err := a.store.DeleteUserSubmissions(r.Context(), id)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -2779,7 +4192,7 @@ func (a *App) handleAdminDeleteSubmission(w http.ResponseWriter, r *http.Request
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Submission not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -2790,7 +4203,7 @@ func (a *App) handleAdminDeleteSubmission(w http.ResponseWriter, r *http.Request
 func (a *App) handleBannedIPList(w http.ResponseWriter, r *http.Request) {
 	ips, err := a.store.ListBannedIPs(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, ips)
@@ -2802,9 +4215,11 @@ func (a *App) handleBanIP(w http.ResponseWriter, r *http.Request) {
 		UserID    *int    `json:"userId"`
 		Reason    string  `json:"reason"`
 		ExpiresAt *string `json:"expiresAt"`
+		// BanAssociatedUsers also bans the users of the IP and their IPs.
+		BanAssociatedUsers bool `json:"banAssociatedUsers"`
 	}
 	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+		writeBodyError(w, err)
 		return
 	}
 	if strings.TrimSpace(body.IP) == "" {
@@ -2823,18 +4238,52 @@ func (a *App) handleBanIP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := a.store.BanIP(r.Context(), body.IP, body.UserID, body.Reason, expiresAt); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
-	userIDs, err := a.store.GetUsersByIP(r.Context(), body.IP)
-	if err == nil {
-		for _, uid := range userIDs {
-			_, _ = a.store.BanUserWithAllIPs(r.Context(), uid, body.Reason)
+	if !body.BanAssociatedUsers {
+		writeJSON(w, http.StatusOK, map[string]any{"success": true, "bannedUsers": 0})
+		return
+	}
+
+	// An IP used by many accounts is most likely shared (a school or office
+	// NAT), so banning all of them would hit unrelated users.
+	allUsers, err := a.store.GetUsersByIP(r.Context(), body.IP)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	if len(allUsers) > a.banCascadeMax {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"success":        true,
+			"bannedUsers":    0,
+			"cascadeSkipped": "shared_ip",
+			"ipUsers":        len(allUsers),
+			"maxUsers":       a.banCascadeMax,
+		})
+		return
+	}
+
+	// The cascade goes one level deep: users of this IP are banned with their
+	// IPs, but other users of those IPs are not. Users who are already banned
+	// are skipped, so re-banning a shared IP does not ban everyone again.
+	userIDs, err := a.store.GetUnbannedUsersByIP(r.Context(), body.IP)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	banned := 0
+	for _, uid := range userIDs {
+		if _, err := a.store.BanUserWithAllIPs(r.Context(), uid, body.Reason); err != nil {
+			a.writeInternalError(w, r, err)
+			return
 		}
+		a.notifyBanned(r.Context(), uid, body.Reason)
+		banned++
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{"success": true})
+	writeJSON(w, http.StatusOK, map[string]any{"success": true, "bannedUsers": banned})
 }
 
 func (a *App) handleUnbanIP(w http.ResponseWriter, r *http.Request) {
@@ -2849,7 +4298,7 @@ func (a *App) handleUnbanIP(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "IP not found in ban list"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -2869,7 +4318,7 @@ func (a *App) handleUnbanIPByID(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Banned IP not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -2893,7 +4342,7 @@ func (a *App) handleAccessHistoryList(w http.ResponseWriter, r *http.Request) {
 
 	records, err := a.store.ListAccessHistory(r.Context(), userID, limit)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -2916,7 +4365,7 @@ func (a *App) handleUserAccessHistory(w http.ResponseWriter, r *http.Request) {
 
 	records, err := a.store.GetAccessHistoryForUser(r.Context(), userID, limit)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -2933,7 +4382,7 @@ func (a *App) handleUserIPAssociations(w http.ResponseWriter, r *http.Request) {
 
 	associations, err := a.store.GetUserIPAssociations(r.Context(), userID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -2978,7 +4427,7 @@ func (a *App) handleErrorStats(w http.ResponseWriter, r *http.Request) {
 
 	stats, err := a.store.GetErrorStats(r.Context(), from, to, statusMin, statusMax, pathLike)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, stats)
@@ -3011,7 +4460,7 @@ func (a *App) handleSensitiveReport(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := a.store.GetSensitiveAccessReport(r.Context(), from, to, limit)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, rows)
@@ -3035,9 +4484,11 @@ func (a *App) handleIPMarkList(w http.ResponseWriter, r *http.Request) {
 			offset = n
 		}
 	}
-	items, err := a.store.ListIPMarks(r.Context(), markType, limit, offset)
+	// Expired marks have no effect, so they are hidden unless asked for.
+	includeExpired := q.Get("includeExpired") == "true"
+	items, err := a.store.ListIPMarks(r.Context(), markType, !includeExpired, limit, offset)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, items)
@@ -3055,7 +4506,7 @@ func (a *App) handleIPMarkUpsert(w http.ResponseWriter, r *http.Request) {
 		ExpireAt *string `json:"expireAt"`
 	}
 	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+		writeBodyError(w, err)
 		return
 	}
 	mt := strings.ToUpper(strings.TrimSpace(body.MarkType))
@@ -3078,8 +4529,8 @@ func (a *App) handleIPMarkUpsert(w http.ResponseWriter, r *http.Request) {
 		op := u.Username
 		operator = &op
 	}
-	if err := a.store.UpsertIPMark(r.Context(), ip, mt, body.Reason, expireAt, operator); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+	if err := a.markIP(r.Context(), ip, mt, body.Reason, expireAt, operator); err != nil {
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"success": true})
@@ -3091,12 +4542,13 @@ func (a *App) handleIPMarkDelete(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "ip is required"})
 		return
 	}
+	a.forgetWhitelist(ip)
 	if err := a.store.DeleteIPMark(r.Context(), ip); err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			writeJSON(w, http.StatusNotFound, map[string]any{"error": "mark not found"})
 			return
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"success": true})
@@ -3110,10 +4562,10 @@ func (a *App) handleIPMarkAssociations(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var mark any
-	m, err := a.store.GetIPMark(r.Context(), ip)
+	m, err := a.store.GetIPMark(r.Context(), ip, true)
 	if err != nil {
 		if !errors.Is(err, store.ErrNotFound) {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			a.writeInternalError(w, r, err)
 			return
 		}
 	} else {
@@ -3122,7 +4574,7 @@ func (a *App) handleIPMarkAssociations(w http.ResponseWriter, r *http.Request) {
 
 	userIDs, err := a.store.GetUsersByIP(r.Context(), ip)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -3130,7 +4582,7 @@ func (a *App) handleIPMarkAssociations(w http.ResponseWriter, r *http.Request) {
 	for _, uid := range userIDs {
 		rows, err := a.store.GetUserIPAssociations(r.Context(), uid)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			a.writeInternalError(w, r, err)
 			return
 		}
 		assoc = append(assoc, rows...)
@@ -3138,7 +4590,7 @@ func (a *App) handleIPMarkAssociations(w http.ResponseWriter, r *http.Request) {
 
 	history, err := a.store.ListAccessHistoryByIP(r.Context(), ip, 200)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		a.writeInternalError(w, r, err)
 		return
 	}
 
@@ -3153,29 +4605,43 @@ func (a *App) handleIPMarkAssociations(w http.ResponseWriter, r *http.Request) {
 
 func (a *App) handleSystemStatus(w http.ResponseWriter, r *http.Request) {
 	hostUsed, hostTotal := readHostMemory()
-	cgUsed, cgLimit := readCgroupMemory()
+	cg := readCgroupMemory()
 	hostRatio := 0.0
-	cgRatio := 0.0
 	if hostTotal > 0 && hostUsed > 0 {
 		hostRatio = float64(hostUsed) / float64(hostTotal)
 	}
-	if cgLimit > 0 && cgUsed > 0 {
-		cgRatio = float64(cgUsed) / float64(cgLimit)
-	}
 	containerID := strings.TrimSpace(os.Getenv("HOSTNAME"))
 	if containerID == "" {
 		containerID = "unknown"
 	}
+	dockerOK, dockerErr := a.dockerStatus(r.Context())
+	statusCounts, err := a.store.CountSubmissionsByStatus(r.Context(), "Pending", "Judging")
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
 	resp := map[string]any{
-		"hostUsedBytes":    hostUsed,
-		"hostTotalBytes":   hostTotal,
-		"hostRatio":        hostRatio,
-		"cgroupUsedBytes":  cgUsed,
-		"cgroupLimitBytes": cgLimit,
-		"cgroupRatio":      cgRatio,
-		"memoryThrottle":   a.isMemoryThrottled(),
-		"containerId":      containerID,
-		"containerName":    containerID,
+		"hostUsedBytes":      hostUsed,
+		"hostTotalBytes":     hostTotal,
+		"hostRatio":          hostRatio,
+		"cgroupVersion":      cg.Version,
+		"cgroupUsedBytes":    cg.Used,
+		"cgroupLimitBytes":   cg.Limit,
+		"cgroupUnlimited":    cg.Version != 0 && cg.Limit == 0,
+		"cgroupRatio":        cg.Ratio(),
+		"memoryThrottle":     a.isMemoryThrottled(),
+		"dockerAvailable":    dockerOK,
+		"judgeQueueDepth":    len(a.judgeQueue),
+		"judgeQueueCapacity": cap(a.judgeQueue),
+		"judgeWorkers":       judgeWorkerCount,
+		"judgeActiveWorkers": atomic.LoadInt32(&a.judgeActive),
+		"pendingSubmissions": statusCounts["Pending"],
+		"judgingSubmissions": statusCounts["Judging"],
+		"containerId":        containerID,
+		"containerName":      containerID,
+	}
+	if dockerErr != "" {
+		resp["dockerError"] = dockerErr
 	}
 	writeJSON(w, http.StatusOK, resp)
 }
@@ -3273,26 +4739,3 @@ func parseUserAgent(ua string) (browser, os string) {
 
 	return browser, os
 }
-
-// getClientIP extracts the client IP from the request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
-	}
-	// Check X-Real-IP header
-	xri := r.Header.Get("X-Real-IP")
-	if xri != "" {
-		return xri
-	}
-	// Fall back to RemoteAddr
-	ip := r.RemoteAddr
-	if idx := strings.LastIndex(ip, ":"); idx != -1 {
-		ip = ip[:idx]
-	}
-	return ip
-}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"onlinejudge-server-go/internal/judger"
+)
+
+func TestLimitBodyInnerLimitWins(t *testing.T) {
+	readStatus := func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	r := chi.NewRouter()
+	r.Use(limitBody(10))
+	r.Post("/small", readStatus)
+	r.Route("/large", func(r chi.Router) {
+		r.Use(limitBody(100))
+		r.Post("/", readStatus)
+	})
+	// The per-route form used for the problem import and test-case routes.
+	r.With(limitBody(100)).Post("/route", readStatus)
+
+	tests := []struct {
+		path string
+		size int
+		want int
+	}{
+		{"/small", 10, http.StatusOK},
+		{"/small", 50, http.StatusRequestEntityTooLarge},
+		{"/large/", 50, http.StatusOK},
+		{"/large/", 200, http.StatusRequestEntityTooLarge},
+		{"/route", 50, http.StatusOK},
+		{"/route", 200, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(strings.Repeat("x", tt.size)))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("POST %s with %d bytes: status %d, want %d", tt.path, tt.size, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestMemThrottleThresholds(t *testing.T) {
+	tests := []struct {
+		on, off         float64
+		wantOn, wantOff float64
+		wantErr         bool
+	}{
+		{0, 0, 0.8, 0.6, false},
+		{0.5, 0, 0.5, 0.3, false},
+		{0.15, 0, 0.15, 0.075, false},
+		{0.9, 0.85, 0.9, 0.85, false},
+		{0, 0.7, 0.8, 0.7, false},
+		{0.5, 0.6, 0, 0, true},
+		{1, 0, 0, 0, true},
+		{-0.1, 0, 0, 0, true},
+		{0.5, -0.1, 0, 0, true},
+	}
+	for _, tt := range tests {
+		on, off, err := memThrottleThresholds(tt.on, tt.off)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("memThrottleThresholds(%v, %v) error = %v, want error %v", tt.on, tt.off, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if math.Abs(on-tt.wantOn) > 1e-9 || math.Abs(off-tt.wantOff) > 1e-9 {
+			t.Errorf("memThrottleThresholds(%v, %v) = %v, %v, want %v, %v", tt.on, tt.off, on, off, tt.wantOn, tt.wantOff)
+		}
+	}
+}
+
+func TestSummarizeVerdicts(t *testing.T) {
+	cases := func(statuses ...string) []judger.CaseResult {
+		out := make([]judger.CaseResult, len(statuses))
+		for i, s := range statuses {
+			out[i] = judger.CaseResult{Status: s, Output: "case " + strconv.Itoa(i+1)}
+		}
+		return out
+	}
+	tests := []struct {
+		name       string
+		results    []judger.CaseResult
+		want       verdictSummary
+		wantStatus string
+		wantOutput string
+	}{
+		{
+			name:       "empty",
+			want:       verdictSummary{Counts: map[string]int{}},
+			wantStatus: "Accepted", wantOutput: "All test cases passed",
+		},
+		{
+			name:       "all accepted",
+			results:    cases("Accepted", "Accepted", "Accepted"),
+			want:       verdictSummary{Total: 3, Passed: 3, Counts: map[string]int{"Accepted": 3}},
+			wantStatus: "Accepted", wantOutput: "All test cases passed",
+		},
+		{
+			name:    "mixed",
+			results: cases("Accepted", "Time Limit Exceeded", "Accepted", "Wrong Answer", "Time Limit Exceeded", "Accepted"),
+			want: verdictSummary{Total: 6, Passed: 3, Counts: map[string]int{
+				"Accepted": 3, "Time Limit Exceeded": 2, "Wrong Answer": 1,
+			}},
+			wantStatus: "Time Limit Exceeded", wantOutput: "case 2",
+		},
+		{
+			name:    "later failure does not replace the first",
+			results: cases("Runtime Error", "Accepted", "Wrong Answer"),
+			want: verdictSummary{Total: 3, Passed: 1, Counts: map[string]int{
+				"Runtime Error": 1, "Accepted": 1, "Wrong Answer": 1,
+			}},
+			wantStatus: "Runtime Error", wantOutput: "case 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarizeVerdicts(tt.results)
+			if got.Total != tt.want.Total || got.Passed != tt.want.Passed || !maps.Equal(got.Counts, tt.want.Counts) {
+				t.Errorf("summarizeVerdicts = %+v, want %+v", got, tt.want)
+			}
+			status, output := finalVerdict(tt.results)
+			if status != tt.wantStatus || output != tt.wantOutput {
+				t.Errorf("finalVerdict = %q, %q; want %q, %q", status, output, tt.wantStatus, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestParseTagMode(t *testing.T) {
+	tests := []struct {
+		query    map[string][]string
+		matchAll bool
+	}{
+		{map[string][]string{}, false},
+		{map[string][]string{"tagMode": {""}}, false},
+		{map[string][]string{"tagMode": {"any"}}, false},
+		{map[string][]string{"tagMode": {"all"}}, true},
+		{map[string][]string{"tagMode": {" ALL "}}, true},
+		{map[string][]string{"tagMode": {"all", "any"}}, true},
+		// Unknown modes fall back to the default.
+		{map[string][]string{"tagMode": {"none"}}, false},
+		{map[string][]string{"tagMode": {"al"}}, false},
+		{map[string][]string{"tagMode": {"any", "all"}}, false},
+	}
+	for _, tt := range tests {
+		if got := parseTagMode(tt.query); got != tt.matchAll {
+			t.Errorf("parseTagMode(%v) = %v, want %v", tt.query, got, tt.matchAll)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"onlinejudge-server-go/internal/store"
+	"onlinejudge-server-go/internal/testdb"
+)
+
+// seedUserWithIPs creates a student who has used the given IPs.
+func seedUserWithIPs(t *testing.T, db *sql.DB, username string, ips ...string) int {
+	t.Helper()
+	ctx := context.Background()
+	var id int
+	if err := db.QueryRowContext(ctx, `INSERT INTO "User" ("username","password") VALUES ($1,'x') RETURNING "id"`, username).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	for _, ip := range ips {
+		if _, err := db.ExecContext(ctx, `INSERT INTO "UserIPAssociation" ("userId","ip") VALUES ($1,$2)`, id, ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return id
+}
+
+func banIPRequest(t *testing.T, a *App, body string) map[string]any {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	a.handleBanIP(rec, httptest.NewRequest(http.MethodPost, "/api/admin/banned-ips", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var out map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func assertBanned(t *testing.T, s *store.Store, userID int, want bool) {
+	t.Helper()
+	u, err := s.GetUserByID(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.IsBanned != want {
+		t.Errorf("user %s banned = %v, want %v", u.Username, u.IsBanned, want)
+	}
+}
+
+func assertIPBanned(t *testing.T, s *store.Store, ip string, want bool) {
+	t.Helper()
+	banned, err := s.IsIPBanned(context.Background(), ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if banned != want {
+		t.Errorf("IP %s banned = %v, want %v", ip, banned, want)
+	}
+}
+
+func TestBanIPWithoutCascade(t *testing.T) {
+	db := testdb.Open(t)
+	a := &App{store: store.New(db), banCascadeMax: defaultBanCascadeMaxUsers}
+	alice := seedUserWithIPs(t, db, "alice", "198.51.100.1")
+
+	out := banIPRequest(t, a, `{"ip":"198.51.100.1","reason":"spam"}`)
+	if out["bannedUsers"] != float64(0) {
+		t.Errorf("bannedUsers = %v, want 0", out["bannedUsers"])
+	}
+	assertIPBanned(t, a.store, "198.51.100.1", true)
+	assertBanned(t, a.store, alice, false)
+}
+
+func TestBanIPSharedIPSkipsCascade(t *testing.T) {
+	db := testdb.Open(t)
+	a := &App{store: store.New(db), banCascadeMax: 3}
+	var users []int
+	for i := 1; i <= 4; i++ {
+		users = append(users, seedUserWithIPs(t, db, "shared"+strconv.Itoa(i), "198.51.100.2", "203.0.113."+strconv.Itoa(i)))
+	}
+
+	out := banIPRequest(t, a, `{"ip":"198.51.100.2","reason":"spam","banAssociatedUsers":true}`)
+	if out["cascadeSkipped"] != "shared_ip" || out["bannedUsers"] != float64(0) || out["ipUsers"] != float64(4) {
+		t.Errorf("response %v, want cascadeSkipped shared_ip with 4 ipUsers and 0 bannedUsers", out)
+	}
+	assertIPBanned(t, a.store, "198.51.100.2", true)
+	for i, id := range users {
+		assertBanned(t, a.store, id, false)
+		assertIPBanned(t, a.store, "203.0.113."+strconv.Itoa(i+1), false)
+	}
+}
+
+func TestBanIPCascadesOneLevel(t *testing.T) {
+	db := testdb.Open(t)
+	a := &App{store: store.New(db), banCascadeMax: 3}
+	// alice and bob share the banned IP; alice also uses a second IP that
+	// carol uses too. carol must not be banned through alice.
+	alice := seedUserWithIPs(t, db, "alice", "198.51.100.3", "198.51.100.4")
+	bob := seedUserWithIPs(t, db, "bob", "198.51.100.3")
+	carol := seedUserWithIPs(t, db, "carol", "198.51.100.4", "198.51.100.5")
+
+	out := banIPRequest(t, a, `{"ip":"198.51.100.3","reason":"cheating","banAssociatedUsers":true}`)
+	if out["bannedUsers"] != float64(2) {
+		t.Errorf("bannedUsers = %v, want 2", out["bannedUsers"])
+	}
+	assertBanned(t, a.store, alice, true)
+	assertBanned(t, a.store, bob, true)
+	assertBanned(t, a.store, carol, false)
+	assertIPBanned(t, a.store, "198.51.100.3", true)
+	assertIPBanned(t, a.store, "198.51.100.4", true)
+	assertIPBanned(t, a.store, "198.51.100.5", false)
+
+	// Re-banning the IP skips users who are already banned.
+	out = banIPRequest(t, a, `{"ip":"198.51.100.3","reason":"cheating","banAssociatedUsers":true}`)
+	if out["bannedUsers"] != float64(0) {
+		t.Errorf("second ban: bannedUsers = %v, want 0", out["bannedUsers"])
+	}
+}
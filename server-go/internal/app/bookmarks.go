@@ -0,0 +1,69 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"onlinejudge-server-go/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (a *App) handleProblemBookmarkAdd(w http.ResponseWriter, r *http.Request) {
+	a.setProblemBookmark(w, r, true)
+}
+
+func (a *App) handleProblemBookmarkRemove(w http.ResponseWriter, r *http.Request) {
+	a.setProblemBookmark(w, r, false)
+}
+
+// setProblemBookmark adds or removes the caller's bookmark on {id}. Both
+// directions are idempotent. Only problems the caller can see may be
+// bookmarked, while removal is always allowed.
+func (a *App) setProblemBookmark(w http.ResponseWriter, r *http.Request, bookmarked bool) {
+	id, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid problem id"})
+		return
+	}
+	u, _ := a.currentUser(r)
+
+	if bookmarked {
+		if !a.checkProblemVisible(w, r, u, id) {
+			return
+		}
+		if err := a.store.AddProblemBookmark(r.Context(), u.ID, id); err != nil {
+			a.writeInternalError(w, r, err)
+			return
+		}
+	} else if err := a.store.RemoveProblemBookmark(r.Context(), u.ID, id); err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"problemId": id, "bookmarked": bookmarked})
+}
+
+// checkProblemVisible writes a 404 and returns false unless problem id
+// exists and is visible to u.
+func (a *App) checkProblemVisible(w http.ResponseWriter, r *http.Request, u userClaims, id int) bool {
+	p, err := a.store.GetProblemByID(r.Context(), id)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		a.writeInternalError(w, r, err)
+		return false
+	}
+	if err != nil || (!p.IsPublic() && u.Role != "ADMIN") {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
+		return false
+	}
+	return true
+}
+
+func (a *App) handleUserBookmarks(w http.ResponseWriter, r *http.Request) {
+	u, _ := a.currentUser(r)
+	items, err := a.store.ListProblemBookmarks(r.Context(), u.ID, u.Role != "ADMIN")
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// captchaProvider verifies a client captcha token with the vendor's
+// siteverify API. Turnstile, hCaptcha and reCAPTCHA share the same form
+// request; they differ in endpoint, env fallbacks and response details.
+type captchaProvider struct {
+	name       string
+	verifyURL  string
+	siteKeyEnv string
+	secretEnv  string
+	parse      func(body io.Reader) (bool, []string)
+}
+
+const defaultCaptchaProvider = "turnstile"
+
+var captchaProviders = map[string]captchaProvider{
+	"turnstile": {
+		name:       "turnstile",
+		verifyURL:  "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+		siteKeyEnv: "CLOUDFLARE_TURNSTILE_SITE_KEY",
+		secretEnv:  "CLOUDFLARE_TURNSTILE_SECRET_KEY",
+		parse:      parseSiteverifyResponse,
+	},
+	"hcaptcha": {
+		name:       "hcaptcha",
+		verifyURL:  "https://api.hcaptcha.com/siteverify",
+		siteKeyEnv: "HCAPTCHA_SITE_KEY",
+		secretEnv:  "HCAPTCHA_SECRET_KEY",
+		parse:      parseSiteverifyResponse,
+	},
+	"recaptcha": {
+		name:       "recaptcha",
+		verifyURL:  "https://www.google.com/recaptcha/api/siteverify",
+		siteKeyEnv: "RECAPTCHA_SITE_KEY",
+		secretEnv:  "RECAPTCHA_SECRET_KEY",
+		parse:      parseRecaptchaResponse,
+	},
+}
+
+var captchaHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// verify posts the token to the provider and returns success plus any error codes.
+func (p captchaProvider) verify(secret, token, remoteIP string) (bool, []string) {
+	if secret == "" || strings.TrimSpace(token) == "" {
+		return false, []string{"missing-input"}
+	}
+	resp, err := captchaHTTPClient.PostForm(p.verifyURL, url.Values{
+		"secret":   {secret},
+		"response": {token},
+		"remoteip": {remoteIP},
+	})
+	if err != nil {
+		return false, []string{"verify-request-failed"}
+	}
+	defer resp.Body.Close()
+	return p.parse(resp.Body)
+}
+
+// parseSiteverifyResponse handles the Turnstile/hCaptcha response shape.
+func parseSiteverifyResponse(body io.Reader) (bool, []string) {
+	var out struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(body).Decode(&out); err != nil {
+		return false, []string{"invalid-verify-response"}
+	}
+	return out.Success, out.ErrorCodes
+}
+
+// recaptchaMinScore is the lowest reCAPTCHA v3 score accepted as human.
+const recaptchaMinScore = 0.5
+
+// parseRecaptchaResponse additionally enforces the v3 score when present.
+func parseRecaptchaResponse(body io.Reader) (bool, []string) {
+	var out struct {
+		Success    bool     `json:"success"`
+		Score      *float64 `json:"score"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(body).Decode(&out); err != nil {
+		return false, []string{"invalid-verify-response"}
+	}
+	if out.Success && out.Score != nil && *out.Score < recaptchaMinScore {
+		return false, append(out.ErrorCodes, "low-score")
+	}
+	return out.Success, out.ErrorCodes
+}
+
+// captchaProvider returns the configured provider, defaulting to Turnstile.
+func (a *App) captchaProvider(ctx context.Context) captchaProvider {
+	name, _ := a.store.GetCaptchaProvider(ctx)
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = strings.ToLower(strings.TrimSpace(os.Getenv("CAPTCHA_PROVIDER")))
+	}
+	if p, ok := captchaProviders[name]; ok {
+		return p
+	}
+	return captchaProviders[defaultCaptchaProvider]
+}
+
+func (a *App) captchaEnabled(ctx context.Context) bool {
+	enabled, _ := a.store.GetTurnstileEnabled(ctx)
+	if !enabled {
+		if v := strings.TrimSpace(os.Getenv("TURNSTILE_ENABLED")); v == "1" || strings.EqualFold(v, "true") {
+			enabled = true
+		}
+	}
+	return enabled
+}
+
+// Actions that can additionally be gated by a captcha; register/login use
+// the main enabled toggle.
+const (
+	captchaActionPasswordChange = "password_change"
+	captchaActionSubmission     = "submission"
+)
+
+func (a *App) captchaActionEnabled(ctx context.Context, action string) bool {
+	enabled, _ := a.store.GetCaptchaActionEnabled(ctx, action)
+	return enabled
+}
+
+// captchaSiteKey returns p's site key from settings, falling back to p's env var.
+func (a *App) captchaSiteKey(ctx context.Context, p captchaProvider) string {
+	siteKey, _ := a.store.GetCaptchaSiteKey(ctx, p.name)
+	if strings.TrimSpace(siteKey) == "" {
+		return strings.TrimSpace(os.Getenv(p.siteKeyEnv))
+	}
+	return siteKey
+}
+
+// captchaSecret returns p's secret configured through the admin settings,
+// falling back to p's env var.
+func (a *App) captchaSecret(ctx context.Context, p captchaProvider) string {
+	if secret, err := a.store.GetCaptchaSecretKey(ctx, p.name); err == nil && strings.TrimSpace(secret) != "" {
+		return strings.TrimSpace(secret)
+	}
+	return strings.TrimSpace(os.Getenv(p.secretEnv))
+}
+
+func (a *App) verifyCaptcha(r *http.Request, token string) (bool, []string) {
+	p := a.captchaProvider(r.Context())
+	return p.verify(a.captchaSecret(r.Context(), p), token, a.clientIP(r))
+}
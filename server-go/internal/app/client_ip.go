@@ -0,0 +1,106 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+// parseTrustedProxies parses proxy entries given as CIDRs or single IPs.
+func parseTrustedProxies(entries []string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if strings.Contains(e, "/") {
+			p, err := netip.ParsePrefix(e)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", e, err)
+			}
+			out = append(out, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", e, err)
+		}
+		out = append(out, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return out, nil
+}
+
+func (a *App) isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range a.trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the normalized address of the client that sent r.
+func (a *App) clientIP(r *http.Request) string {
+	return store.NormalizeIP(a.rawClientIP(r))
+}
+
+// rawClientIP returns the client address as observed. Forwarding headers
+// are honored only when the direct peer is a trusted proxy; the
+// X-Forwarded-For chain is then walked from the right and the first hop that
+// is not a trusted proxy wins, so entries a client prepends are ignored.
+func (a *App) rawClientIP(r *http.Request) string {
+	remote := remoteHost(r)
+	if !a.isTrustedProxy(remote) {
+		return remote
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(v, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) > 0 {
+		ip := remote
+		for i := len(hops) - 1; i >= 0; i-- {
+			if _, err := netip.ParseAddr(hops[i]); err != nil {
+				// Garbage cannot have been written by a trusted proxy.
+				break
+			}
+			ip = hops[i]
+			if !a.isTrustedProxy(ip) {
+				break
+			}
+		}
+		return ip
+	}
+
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+		if _, err := netip.ParseAddr(xri); err == nil {
+			return xri
+		}
+	}
+	return remote
+}
+
+// remoteHost returns the host part of r.RemoteAddr, which may be "ip:port",
+// "[ipv6]:port" or a bare address.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	got, err := parseTrustedProxies([]string{"10.0.0.1", " 192.168.0.0/16 ", "", "10.1.2.3/8", "::1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.1/32", "192.168.0.0/16", "10.0.0.0/8", "::1/128", "2001:db8::/32"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != netip.MustParsePrefix(want[i]) {
+			t.Errorf("entry %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"proxy", "300.1.1.1", "10.0.0.0/33", "10.0.0.1:80", "10.0.0.0/", "localhost"} {
+		if _, err := parseTrustedProxies([]string{"10.0.0.1", bad}); err == nil {
+			t.Errorf("parseTrustedProxies(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestRawClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &App{trustedProxies: proxies}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		xri    string
+		want   string
+	}{
+		{name: "no headers", remote: "203.0.113.5:4000", want: "203.0.113.5"},
+		{name: "untrusted peer ignores forwarded for", remote: "203.0.113.5:4000", xff: []string{"1.2.3.4"}, want: "203.0.113.5"},
+		{name: "untrusted peer ignores real ip", remote: "203.0.113.5:4000", xri: "1.2.3.4", want: "203.0.113.5"},
+		{name: "single proxy", remote: "10.0.0.2:4000", xff: []string{"1.2.3.4"}, want: "1.2.3.4"},
+		{name: "proxy chain", remote: "10.0.0.2:4000", xff: []string{"1.2.3.4, 10.0.0.3"}, want: "1.2.3.4"},
+		{name: "client-prepended entries ignored", remote: "10.0.0.2:4000", xff: []string{"9.9.9.9, 1.2.3.4, 10.0.0.3"}, want: "1.2.3.4"},
+		{name: "headers concatenated in order", remote: "10.0.0.2:4000", xff: []string{"9.9.9.9", "1.2.3.4"}, want: "1.2.3.4"},
+		{name: "all hops trusted", remote: "10.0.0.2:4000", xff: []string{"10.0.0.4, 10.0.0.3"}, want: "10.0.0.4"},
+		{name: "garbage hop stops the walk", remote: "10.0.0.2:4000", xff: []string{"1.2.3.4, garbage, 10.0.0.3"}, want: "10.0.0.3"},
+		{name: "forwarded for wins over real ip", remote: "10.0.0.2:4000", xff: []string{"1.2.3.4"}, xri: "5.6.7.8", want: "1.2.3.4"},
+		{name: "real ip from trusted peer", remote: "10.0.0.2:4000", xri: "5.6.7.8", want: "5.6.7.8"},
+		{name: "invalid real ip", remote: "10.0.0.2:4000", xri: "not-an-ip", want: "10.0.0.2"},
+		{name: "ipv6 trusted peer", remote: "[::1]:4000", xff: []string{"2001:db8::7"}, want: "2001:db8::7"},
+		{name: "ipv6 untrusted peer", remote: "[2001:db8::9]:4000", xff: []string{"1.2.3.4"}, want: "2001:db8::9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.xri != "" {
+				r.Header.Set("X-Real-IP", tt.xri)
+			}
+			if got := a.rawClientIP(r); got != tt.want {
+				t.Errorf("rawClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteHost(t *testing.T) {
+	tests := []struct{ remote, want string }{
+		{"203.0.113.5:4000", "203.0.113.5"},
+		{"[::1]:8080", "::1"},
+		{"[fe80::1%eth0]:80", "fe80::1%eth0"},
+		{"203.0.113.5", "203.0.113.5"},
+		{"::1", "::1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		if got := remoteHost(r); got != tt.want {
+			t.Errorf("remoteHost(%q) = %q, want %q", tt.remote, got, tt.want)
+		}
+	}
+}
+
+func TestClientIPMatchesStoredKey(t *testing.T) {
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &App{trustedProxies: proxies}
+
+	// Each group holds spellings of one address as a peer or a forwarded hop;
+	// all must map to the key the store uses for bans and access history.
+	groups := []struct {
+		key     string
+		remotes []string
+		xff     []string
+	}{
+		{key: "203.0.113.5", remotes: []string{"203.0.113.5:1", "[::ffff:203.0.113.5]:1"}, xff: []string{"203.0.113.5", "::ffff:203.0.113.5"}},
+		{key: "fe80::1", remotes: []string{"[fe80::1%eth0]:1", "[fe80::1]:1", "[FE80:0::1]:1"}, xff: []string{"fe80::1%eth0", "fe80:0:0::1"}},
+		{key: "2001:db8::1", remotes: []string{"[2001:db8:0:0::1]:1", "2001:db8::1"}, xff: []string{"2001:DB8::1"}},
+	}
+	for _, g := range groups {
+		if got := store.NormalizeIP(g.key); got != g.key {
+			t.Fatalf("NormalizeIP(%q) = %q, want it unchanged", g.key, got)
+		}
+		for _, remote := range g.remotes {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = remote
+			if got := a.clientIP(r); got != g.key {
+				t.Errorf("clientIP with peer %q = %q, want %q", remote, got, g.key)
+			}
+		}
+		for _, hop := range g.xff {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = "10.0.0.2:1"
+			r.Header.Set("X-Forwarded-For", hop)
+			if got := a.clientIP(r); got != g.key {
+				t.Errorf("clientIP forwarded for %q = %q, want %q", hop, got, g.key)
+			}
+			if got := store.NormalizeIP(hop); got != g.key {
+				t.Errorf("NormalizeIP(%q) = %q, want %q", hop, got, g.key)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"onlinejudge-server-go/internal/store"
+	"onlinejudge-server-go/internal/testdb"
+)
+
+func newCodeRunApp() *App {
+	return &App{
+		codeRunHistory:   make(map[int][]time.Time),
+		codeRunIPHistory: make(map[string][]time.Time),
+		whitelistCache:   make(map[string]whitelistEntry),
+	}
+}
+
+func TestTakeCodeRunSlotUserWindow(t *testing.T) {
+	a := newCodeRunApp()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 3; i++ {
+		c := a.takeCodeRunSlot(1, "203.0.113.1", 3, 100, now.Add(time.Duration(i)*time.Second))
+		if !c.Allowed || c.Used != i {
+			t.Fatalf("run %d: %+v", i, c)
+		}
+	}
+	c := a.takeCodeRunSlot(1, "203.0.113.2", 3, 100, now.Add(10*time.Second))
+	if c.Allowed || c.Scope != "user" || c.Limit != 3 || c.Used != 3 {
+		t.Fatalf("fourth run from another IP = %+v, want the user window to reject it", c)
+	}
+	// The oldest run, at +1s, leaves the window at +61s.
+	if c.RetryAfter != 51*time.Second {
+		t.Errorf("RetryAfter = %s, want 51s", c.RetryAfter)
+	}
+	// Another user behind the same IP has a window of their own.
+	if c := a.takeCodeRunSlot(2, "203.0.113.1", 3, 100, now.Add(10*time.Second)); !c.Allowed {
+		t.Errorf("other user rejected: %+v", c)
+	}
+	// The rejected run was not counted, so one slot frees at +61s.
+	if c := a.takeCodeRunSlot(1, "203.0.113.1", 3, 100, now.Add(61*time.Second)); !c.Allowed || c.Used != 3 {
+		t.Errorf("run after the oldest expired = %+v", c)
+	}
+}
+
+func TestTakeCodeRunSlotIPWindow(t *testing.T) {
+	a := newCodeRunApp()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	const ip = "203.0.113.1"
+
+	for user := 1; user <= 4; user++ {
+		if c := a.takeCodeRunSlot(user, ip, 3, 4, now.Add(time.Duration(user)*time.Second)); !c.Allowed {
+			t.Fatalf("user %d rejected: %+v", user, c)
+		}
+	}
+	c := a.takeCodeRunSlot(5, ip, 3, 4, now.Add(20*time.Second))
+	if c.Allowed || c.Scope != "ip" || c.Limit != 4 || c.Used != 4 {
+		t.Fatalf("fifth account behind the IP = %+v, want the IP window to reject it", c)
+	}
+	if c.RetryAfter != 41*time.Second {
+		t.Errorf("RetryAfter = %s, want 41s", c.RetryAfter)
+	}
+	// The rejected run did not count against user 5's own window.
+	if got := len(a.codeRunHistory[5]); got != 0 {
+		t.Errorf("user 5 window holds %d runs, want 0", got)
+	}
+	if c := a.takeCodeRunSlot(5, "203.0.113.9", 3, 4, now.Add(20*time.Second)); !c.Allowed || c.Used != 1 {
+		t.Errorf("same user from another IP = %+v", c)
+	}
+}
+
+func TestCheckCodeRunLimit(t *testing.T) {
+	db := testdb.Open(t)
+	a := newCodeRunApp()
+	a.store = store.New(db)
+	ctx := context.Background()
+	if _, err := a.store.UpsertCodeRunRateLimit(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.store.UpsertCodeRunIPRateLimit(ctx, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(ip string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/run", nil)
+		if a.checkCodeRunLimit(w, r, 1, ip) != (w.Code == http.StatusOK) {
+			t.Fatalf("checkCodeRunLimit result disagrees with status %d", w.Code)
+		}
+		return w
+	}
+	for i := 0; i < 2; i++ {
+		if w := run("203.0.113.1"); w.Code != http.StatusOK {
+			t.Fatalf("run %d: status %d", i+1, w.Code)
+		}
+	}
+	w := run("203.0.113.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third run: status %d, want 429", w.Code)
+	}
+	secs, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || secs < 1 || secs > 60 {
+		t.Errorf("Retry-After = %q, want 1-60 seconds", w.Header().Get("Retry-After"))
+	}
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["scope"] != "user" || body["limit"] != float64(2) || body["used"] != float64(2) {
+		t.Errorf("body = %v", body)
+	}
+
+	// A whitelisted IP bypasses the limit and is not counted.
+	if err := a.store.UpsertIPMark(ctx, "203.0.113.7", "WHITELIST", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if w := run("203.0.113.7"); w.Code != http.StatusOK {
+			t.Fatalf("whitelisted run %d: status %d", i+1, w.Code)
+		}
+	}
+	if got := len(a.codeRunIPHistory["203.0.113.7"]); got != 0 {
+		t.Errorf("whitelisted IP window holds %d runs, want 0", got)
+	}
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+const maxCodeTemplateBytes = 64 << 10
+
+// codeTemplateErrors checks that templates only name supported languages and
+// stay within maxCodeTemplateBytes. field prefixes each message.
+func codeTemplateErrors(field string, templates map[string]string) []string {
+	langs := make([]string, 0, len(templates))
+	for lang := range templates {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	var errs []string
+	for _, lang := range langs {
+		if _, ok := supportedLanguages[lang]; !ok {
+			errs = append(errs, field+"."+lang+": unsupported language")
+			continue
+		}
+		if len(templates[lang]) > maxCodeTemplateBytes {
+			errs = append(errs, fmt.Sprintf("%s.%s must be at most %d bytes", field, lang, maxCodeTemplateBytes))
+		}
+	}
+	return errs
+}
+
+// problemTemplates returns the starter code for each supported language,
+// taking the problem's own template when set and the global one otherwise.
+// Languages with neither are left out.
+func (a *App) problemTemplates(ctx context.Context, rawConfig json.RawMessage) (map[string]string, error) {
+	global, err := a.store.GetCodeTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _ := store.ParseProblemConfig(rawConfig)
+	out := map[string]string{}
+	for lang := range supportedLanguages {
+		if t := cfg.Templates[lang]; t != "" {
+			out[lang] = t
+		} else if t := global[lang]; t != "" {
+			out[lang] = t
+		}
+	}
+	return out, nil
+}
+
+func (a *App) handleCodeTemplatesGet(w http.ResponseWriter, r *http.Request) {
+	templates, err := a.store.GetCodeTemplates(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, templates)
+}
+
+func (a *App) handleCodeTemplatesPut(w http.ResponseWriter, r *http.Request) {
+	var body map[string]string
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if body == nil {
+		body = map[string]string{}
+	}
+	if errs := codeTemplateErrors("templates", body); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid templates", "details": errs})
+		return
+	}
+	saved, err := a.store.UpsertCodeTemplates(r.Context(), body)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const maxCompileOptionsLen = 200
+
+// allowedCompileFlags lists the compiler flags problems and the global
+// defaults may use. Anything else is rejected when saved, which keeps out
+// flags that load code or touch files outside the sandbox's working
+// directory, such as -fplugin, -B, -specs or @file.
+var allowedCompileFlags = []*regexp.Regexp{
+	regexp.MustCompile(`^-O[0-3sgz]?$`),
+	regexp.MustCompile(`^-Ofast$`),
+	regexp.MustCompile(`^-g[0-3]?$`),
+	regexp.MustCompile(`^-std=(c|gnu)\+\+(11|14|17|20|23|2[a-c])$`),
+	regexp.MustCompile(`^-W[a-z][a-z0-9+-]*(=[0-9]+)?$`),
+	regexp.MustCompile(`^-(w|pedantic|pedantic-errors|pthread|static|lm)$`),
+	regexp.MustCompile(`^-D[A-Za-z_][A-Za-z0-9_]*(=[A-Za-z0-9_.]*)?$`),
+	regexp.MustCompile(`^-U[A-Za-z_][A-Za-z0-9_]*$`),
+	regexp.MustCompile(`^-m(arch|tune)=[a-z0-9-]+$`),
+	regexp.MustCompile(`^-fsanitize=[a-z,]+$`),
+	regexp.MustCompile(`^-f(no-)?(omit-frame-pointer|exceptions|rtti|strict-aliasing|stack-protector(-strong|-all)?|unroll-loops|inline-functions|trapv|wrapv)$`),
+}
+
+// compileOptionErrors checks each whitespace-separated flag in opts against
+// allowedCompileFlags. field prefixes each message.
+func compileOptionErrors(field, opts string) []string {
+	if len(opts) > maxCompileOptionsLen {
+		return []string{field + " must be at most 200 characters"}
+	}
+	var errs []string
+	for _, flag := range strings.Fields(opts) {
+		if !compileFlagAllowed(flag) {
+			errs = append(errs, field+": flag "+flag+" is not allowed")
+		}
+	}
+	return errs
+}
+
+func compileFlagAllowed(flag string) bool {
+	for _, re := range allowedCompileFlags {
+		if re.MatchString(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileOptionsFor picks the compile options for a problem: its own when
+// set, otherwise the global default for the language. An empty result lets
+// the judger fall back to its built-in default.
+func (a *App) compileOptionsFor(ctx context.Context, problemOpts, language string) string {
+	if strings.TrimSpace(problemOpts) != "" {
+		return problemOpts
+	}
+	defaults, err := a.store.GetDefaultCompileOptions(ctx)
+	if err != nil {
+		return ""
+	}
+	return defaults[language]
+}
+
+func (a *App) handleCompileOptionsGet(w http.ResponseWriter, r *http.Request) {
+	defaults, err := a.store.GetDefaultCompileOptions(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, defaults)
+}
+
+func (a *App) handleCompileOptionsPut(w http.ResponseWriter, r *http.Request) {
+	var body map[string]string
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if body == nil {
+		body = map[string]string{}
+	}
+	langs := make([]string, 0, len(body))
+	for lang := range body {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	var errs []string
+	for _, lang := range langs {
+		if _, ok := supportedLanguages[lang]; !ok {
+			errs = append(errs, "compileOptions."+lang+": unsupported language")
+			continue
+		}
+		body[lang] = strings.TrimSpace(body[lang])
+		errs = append(errs, compileOptionErrors("compileOptions."+lang, body[lang])...)
+	}
+	if len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid compile options", "details": errs})
+		return
+	}
+	saved, err := a.store.UpsertDefaultCompileOptions(r.Context(), body)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
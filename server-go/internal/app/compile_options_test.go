@@ -0,0 +1,41 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileOptionErrors(t *testing.T) {
+	tests := []struct {
+		opts string
+		ok   bool
+	}{
+		{"", true},
+		{"-O2", true},
+		{"-O2 -std=gnu++17 -Wall -Wextra -DLOCAL -DN=10 -fsanitize=address,undefined", true},
+		{"-march=native -fno-omit-frame-pointer -pthread -lm", true},
+		{"; rm -rf /", false},
+		{"-O2 ;rm -rf /", false},
+		{"$(id)", false},
+		{"-DX=$(id)", false},
+		{"`id`", false},
+		{"-DX=`id`", false},
+		{"-O2 && id", false},
+		{"-O2 | id", false},
+		{"-fplugin=/tmp/evil.so", false},
+		{"-fplugin-arg-evil-x=1", false},
+		{"@/etc/passwd", false},
+		{"-wrapper /bin/sh,-c,id", false},
+		{"-B/tmp", false},
+		{"-specs=/tmp/evil.specs", false},
+		{"-o /etc/passwd", false},
+		{"-include /etc/shadow", false},
+		{"-Wl,-rpath,/tmp", false},
+		{strings.Repeat("-O2 ", 60), false},
+	}
+	for _, tt := range tests {
+		if got := len(compileOptionErrors("compileOptions", tt.opts)) == 0; got != tt.ok {
+			t.Errorf("compileOptionErrors(%q): accepted = %v, want %v", tt.opts, got, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, GZIP", true},
+		{"br;q=1.0, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"gzip;q=bad", false},
+		{"identity", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", tt.header)
+		if got := acceptsGzip(r); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestGzipJSON(t *testing.T) {
+	large := `{"data":"` + strings.Repeat("x", 2*gzipMinBytes) + `"}`
+	small := `{"ok":true}`
+	tests := []struct {
+		name        string
+		contentType string
+		status      int
+		body        string
+		chunked     bool
+		wantGzip    bool
+	}{
+		{"large json", "application/json", http.StatusOK, large, false, true},
+		{"large json in small writes", "application/json", http.StatusOK, large, true, true},
+		{"large json error", "application/json", http.StatusNotFound, large, false, true},
+		{"small json", "application/json", http.StatusOK, small, false, false},
+		{"zip download", "application/zip", http.StatusOK, large, false, false},
+		{"no body", "", http.StatusNoContent, "", false, false},
+	}
+	for _, tt := range tests {
+		h := gzipJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.contentType != "" {
+				w.Header().Set("Content-Type", tt.contentType)
+			}
+			w.WriteHeader(tt.status)
+			if !tt.chunked {
+				_, _ = io.WriteString(w, tt.body)
+				return
+			}
+			for i := 0; i < len(tt.body); i += 100 {
+				_, _ = io.WriteString(w, tt.body[i:min(i+100, len(tt.body))])
+			}
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		gzipped := rec.Header().Get("Content-Encoding") == "gzip"
+		if gzipped != tt.wantGzip {
+			t.Errorf("%s: gzipped = %v, want %v", tt.name, gzipped, tt.wantGzip)
+			continue
+		}
+		body := rec.Body.String()
+		if gzipped {
+			zr, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			b, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			body = string(b)
+		}
+		if body != tt.body {
+			t.Errorf("%s: body of %d bytes, want %d", tt.name, len(body), len(tt.body))
+		}
+	}
+}
+
+func TestGzipJSONWithoutAcceptEncoding(t *testing.T) {
+	body := `{"data":"` + strings.Repeat("x", 2*gzipMinBytes) + `"}`
+	h := gzipJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"data": strings.Repeat("x", 2*gzipMinBytes)})
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("Vary") != "" {
+		t.Fatalf("unexpected headers %v", rec.Header())
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != body {
+		t.Fatalf("body of %d bytes, want %d", len(got), len(body))
+	}
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"onlinejudge-server-go/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// handleContestSummary reports submission volume, verdicts, languages and
+// solve times for a contest, per problem and overall.
+func (a *App) handleContestSummary(w http.ResponseWriter, r *http.Request) {
+	contestID, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok || contestID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid contest id"})
+		return
+	}
+	if _, err := a.store.GetContestByID(r.Context(), contestID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Contest not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	sum, err := a.store.GetContestSummary(r.Context(), contestID)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sum)
+}
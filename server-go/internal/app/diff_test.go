@@ -0,0 +1,98 @@
+package app
+
+import (
+	"testing"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", ""},
+		{"crlf only", "a\r\nb\r\n", "a\nb", ""},
+		{
+			"changed line",
+			"a\nb\nc\n",
+			"a\nB\nc\n",
+			"--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			"from empty",
+			"",
+			"x\ny\n",
+			"--- old\n+++ new\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			"to empty",
+			"x\n",
+			"",
+			"--- old\n+++ new\n@@ -1 +0,0 @@\n-x\n",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			"--- old\n+++ new\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			"nearby changes share a hunk",
+			"1\n2\n3\n4\n5\n6\n7\n8\n",
+			"one\n2\n3\n4\n5\n6\n7\neight\n",
+			"--- old\n+++ new\n@@ -1,8 +1,8 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := unifiedDiff("old", "new", tt.old, tt.new); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiffLinesTooManyEdits(t *testing.T) {
+	a := make([]string, maxDiffEdits)
+	b := make([]string, maxDiffEdits)
+	for i := range a {
+		a[i] = "a"
+		b[i] = "b"
+	}
+	ops := diffLines(a, b)
+	if len(ops) != len(a)+len(b) {
+		t.Fatalf("got %d ops, want %d", len(ops), len(a)+len(b))
+	}
+	for i, op := range ops {
+		want := byte('-')
+		if i >= len(a) {
+			want = '+'
+		}
+		if op.kind != want {
+			t.Fatalf("op %d is %q, want %q", i, op.kind, want)
+		}
+	}
+}
+
+func TestCanDiffSubmission(t *testing.T) {
+	owner := 7
+	tests := []struct {
+		name string
+		user userClaims
+		sub  store.SubmissionCode
+		want bool
+	}{
+		{"owner", userClaims{ID: 7, Role: "STUDENT"}, store.SubmissionCode{UserID: &owner}, true},
+		{"other user", userClaims{ID: 8, Role: "STUDENT"}, store.SubmissionCode{UserID: &owner}, false},
+		{"admin", userClaims{ID: 8, Role: "ADMIN"}, store.SubmissionCode{UserID: &owner}, true},
+		{"no owner", userClaims{ID: 7, Role: "STUDENT"}, store.SubmissionCode{}, false},
+		{"no owner, admin", userClaims{ID: 7, Role: "ADMIN"}, store.SubmissionCode{}, true},
+	}
+	for _, tt := range tests {
+		if got := canDiffSubmission(tt.user, tt.sub); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
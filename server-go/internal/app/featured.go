@@ -0,0 +1,104 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"onlinejudge-server-go/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (a *App) handleSubmissionFeature(w http.ResponseWriter, r *http.Request) {
+	a.setSubmissionFeatured(w, r, true)
+}
+
+func (a *App) handleSubmissionUnfeature(w http.ResponseWriter, r *http.Request) {
+	a.setSubmissionFeatured(w, r, false)
+}
+
+// setSubmissionFeatured features or unfeatures one of the caller's own
+// submissions. Featuring replaces the caller's previous choice for the same
+// problem and is only allowed for submissions that solved it.
+func (a *App) setSubmissionFeatured(w http.ResponseWriter, r *http.Request, featured bool) {
+	subID, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid submission id"})
+		return
+	}
+	u, _ := a.currentUser(r)
+
+	sub, err := a.store.GetSubmissionCode(r.Context(), subID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Submission not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	if sub.UserID == nil || *sub.UserID != u.ID {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Access denied"})
+		return
+	}
+
+	if featured {
+		err = a.store.SetFeaturedSolution(r.Context(), u.ID, subID)
+	} else {
+		err = a.store.UnsetFeaturedSolution(r.Context(), u.ID, subID)
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNotAccepted) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Only accepted submissions can be featured"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": subID, "problemId": sub.ProblemID, "featured": featured})
+}
+
+// handlePublicUserFeatured lists a user's featured solutions on visible
+// problems. To avoid spoilers the code is only included for problems the
+// viewer has solved, unless the viewer is the user or an admin.
+func (a *App) handlePublicUserFeatured(w http.ResponseWriter, r *http.Request) {
+	username := strings.TrimSpace(chi.URLParam(r, "username"))
+	user, err := a.store.GetUserByUsername(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "User not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	items, err := a.store.ListFeaturedSolutions(r.Context(), user.ID, true)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+
+	viewer, ok := a.tryUserFromAuthHeader(r)
+	if ok && (viewer.ID == user.ID || viewer.Role == "ADMIN") {
+		writeJSON(w, http.StatusOK, items)
+		return
+	}
+	solved := map[int]bool{}
+	if ok {
+		list, err := a.store.ListSolvedProblems(r.Context(), viewer.ID, true)
+		if err != nil {
+			a.writeInternalError(w, r, err)
+			return
+		}
+		for _, sp := range list {
+			solved[sp.ID] = true
+		}
+	}
+	for i := range items {
+		if !solved[items[i].ProblemID] {
+			items[i].Code = ""
+		}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+// allowGuestRun applies the per-IP guest run limit over a one-minute sliding
+// window. Whitelisted IPs are not limited.
+func (a *App) allowGuestRun(ctx context.Context, clientIP string, limit int) (bool, int, time.Duration) {
+	if a.isIPWhitelisted(ctx, clientIP) {
+		return true, 0, 0
+	}
+	now := time.Now()
+	windowStart := now.Add(-time.Minute)
+
+	a.guestRunMu.Lock()
+	defer a.guestRunMu.Unlock()
+
+	if len(a.guestRunHistory) >= runHistoryIPLimit {
+		sweepRunHistory(a.guestRunHistory, windowStart)
+	}
+	times := pruneRunWindow(a.guestRunHistory[clientIP], windowStart)
+	if len(times) >= limit {
+		a.guestRunHistory[clientIP] = times
+		return false, len(times), windowRetryAfter(times, now)
+	}
+	times = append(times, now)
+	a.guestRunHistory[clientIP] = times
+	return true, len(times), 0
+}
+
+// maxGuestCodeBytes and maxGuestInputBytes bound an anonymous run's source
+// and stdin.
+const (
+	maxGuestCodeBytes  = 64 << 10
+	maxGuestInputBytes = 64 << 10
+)
+
+// runHistoryIPLimit bounds how many IPs a per-IP run window tracks before
+// sweepRunHistory drops the idle ones.
+const runHistoryIPLimit = 10000
+
+// pruneRunWindow drops the timestamps in times that are not after
+// windowStart, reusing the backing array.
+func pruneRunWindow(times []time.Time, windowStart time.Time) []time.Time {
+	pruned := times[:0]
+	for _, ts := range times {
+		if ts.After(windowStart) {
+			pruned = append(pruned, ts)
+		}
+	}
+	return pruned
+}
+
+// windowRetryAfter returns how long until the oldest of the pruned,
+// chronologically ordered times leaves the one-minute window.
+func windowRetryAfter(times []time.Time, now time.Time) time.Duration {
+	if len(times) == 0 {
+		return 0
+	}
+	return times[0].Add(time.Minute).Sub(now)
+}
+
+// sweepRunHistory removes keys whose latest run is not after windowStart.
+func sweepRunHistory(history map[string][]time.Time, windowStart time.Time) {
+	for key, times := range history {
+		if len(times) == 0 || !times[len(times)-1].After(windowStart) {
+			delete(history, key)
+		}
+	}
+}
+
+// handleGuestRunCode is the unauthenticated counterpart of handleRunCode for
+// a public sandbox. It is off unless an admin enables it, only accepts the
+// problems listed in the guest run settings and is limited per IP.
+func (a *App) handleGuestRunCode(w http.ResponseWriter, r *http.Request) {
+	settings, err := a.store.GetGuestRunSettings(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	if !settings.Enabled {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Guest code runs are disabled"})
+		return
+	}
+
+	clientIP := a.clientIP(r)
+	isBanned, err := a.store.IsIPBanned(r.Context(), clientIP)
+	if err == nil && isBanned {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Your IP has been banned"})
+		return
+	}
+
+	if a.isMemoryThrottled() {
+		w.Header().Set("X-System-Status", "memory_throttle")
+		log.Printf("[memory-throttle] 内存限流拒绝 guest ip=%s path=%s", clientIP, r.URL.Path)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"error": "System is under memory pressure. Please try test run later.",
+		})
+		return
+	}
+
+	var body struct {
+		ProblemID int    `json:"problemId"`
+		Language  string `json:"language"`
+		Code      string `json:"code"`
+		Input     string `json:"input"`
+	}
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if body.ProblemID <= 0 || strings.TrimSpace(body.Code) == "" || strings.TrimSpace(body.Language) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid payload"})
+		return
+	}
+	if _, ok := supportedLanguages[body.Language]; !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Unsupported language"})
+		return
+	}
+	if len(body.Code) > maxGuestCodeBytes {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Code must be at most 64 KB"})
+		return
+	}
+	if len(body.Input) > maxGuestInputBytes {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Input must be at most 64 KB"})
+		return
+	}
+	if !slices.Contains(settings.ProblemIDs, body.ProblemID) {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Guest runs are not allowed for this problem"})
+		return
+	}
+
+	// Only a well-formed request for an allowed problem uses up a run.
+	if allowed, used, retryAfter := a.allowGuestRun(r.Context(), clientIP, settings.RateLimit); !allowed {
+		setRetryAfter(w, retryAfter)
+		writeJSON(w, http.StatusTooManyRequests, map[string]any{
+			"error":  "Code run rate limit exceeded. Please wait before testing again.",
+			"scope":  "ip",
+			"limit":  settings.RateLimit,
+			"used":   used,
+			"window": "1 minute",
+		})
+		return
+	}
+
+	p, err := a.store.GetProblemByID(r.Context(), body.ProblemID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	if err != nil || !p.IsPublic() {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+	a.runCodeInput(ctx, w, r, body.Language, body.Code, body.Input, a.judgeOptions(ctx, p, body.Language))
+}
+
+// handleGuestRunSettingsGet is public so the client can tell whether to
+// offer the sandbox and for which problems.
+func (a *App) handleGuestRunSettingsGet(w http.ResponseWriter, r *http.Request) {
+	settings, err := a.store.GetGuestRunSettings(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+func (a *App) handleGuestRunSettingsPut(w http.ResponseWriter, r *http.Request) {
+	var body store.GuestRunSettings
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if body.RateLimit < 1 || body.RateLimit > 60 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "rateLimit must be between 1 and 60"})
+		return
+	}
+	ids := make([]int, 0, len(body.ProblemIDs))
+	for _, id := range body.ProblemIDs {
+		if id <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "problemIds must be positive"})
+			return
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	body.ProblemIDs = ids
+	saved, err := a.store.UpsertGuestRunSettings(r.Context(), body)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
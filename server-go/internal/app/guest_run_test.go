@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"onlinejudge-server-go/internal/store"
+	"onlinejudge-server-go/internal/testdb"
+)
+
+func TestPruneRunWindow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	windowStart := now.Add(-time.Minute)
+	times := []time.Time{
+		now.Add(-2 * time.Minute),
+		windowStart, // exactly at the start is already outside
+		now.Add(-30 * time.Second),
+		now,
+	}
+	got := pruneRunWindow(times, windowStart)
+	want := []time.Time{now.Add(-30 * time.Second), now}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+	if len(pruneRunWindow(nil, windowStart)) != 0 {
+		t.Fatal("pruning nil should give an empty window")
+	}
+}
+
+func TestWindowRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	if got := windowRetryAfter(nil, now); got != 0 {
+		t.Errorf("empty window: got %s, want 0", got)
+	}
+	times := []time.Time{now.Add(-40 * time.Second), now.Add(-10 * time.Second)}
+	if got, want := windowRetryAfter(times, now), 20*time.Second; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestSweepRunHistory(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	windowStart := now.Add(-time.Minute)
+	history := map[string][]time.Time{
+		"stale":  {now.Add(-3 * time.Minute), now.Add(-2 * time.Minute)},
+		"active": {now.Add(-2 * time.Minute), now.Add(-time.Second)},
+		"empty":  nil,
+	}
+	sweepRunHistory(history, windowStart)
+	if _, ok := history["active"]; !ok || len(history) != 1 {
+		t.Fatalf("got keys %v, want only active", history)
+	}
+}
+
+func TestGuestRunValidatesBeforeRateLimit(t *testing.T) {
+	db := testdb.Open(t)
+	a := &App{
+		store:           store.New(db),
+		guestRunHistory: make(map[string][]time.Time),
+		whitelistCache:  make(map[string]whitelistEntry),
+	}
+	if _, err := a.store.UpsertGuestRunSettings(context.Background(), store.GuestRunSettings{Enabled: true, RateLimit: 1, ProblemIDs: []int{1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(body string) int {
+		r := httptest.NewRequest("POST", "/api/run/guest", strings.NewReader(body))
+		r.RemoteAddr = "203.0.113.1:4000"
+		w := httptest.NewRecorder()
+		a.handleGuestRunCode(w, r)
+		return w.Code
+	}
+	invalid := []struct {
+		name, body string
+		want       int
+	}{
+		{"malformed", `{"problemId":`, http.StatusBadRequest},
+		{"missing code", `{"problemId":1,"language":"python"}`, http.StatusBadRequest},
+		{"unsupported language", `{"problemId":1,"language":"cobol","code":"x"}`, http.StatusBadRequest},
+		{"code too large", `{"problemId":1,"language":"python","code":"` + strings.Repeat("x", maxGuestCodeBytes+1) + `"}`, http.StatusBadRequest},
+		{"input too large", `{"problemId":1,"language":"python","code":"x","input":"` + strings.Repeat("x", maxGuestInputBytes+1) + `"}`, http.StatusBadRequest},
+		{"problem not allowed", `{"problemId":2,"language":"python","code":"x"}`, http.StatusForbidden},
+	}
+	for _, tt := range invalid {
+		// Each is sent twice: with a limit of one, a counted rejection
+		// would turn the second into a 429.
+		for i := 0; i < 2; i++ {
+			if got := post(tt.body); got != tt.want {
+				t.Fatalf("%s (attempt %d): status %d, want %d", tt.name, i+1, got, tt.want)
+			}
+		}
+	}
+	if n := len(a.guestRunHistory["203.0.113.1"]); n != 0 {
+		t.Fatalf("invalid requests used %d runs, want 0", n)
+	}
+
+	// A valid request uses the slot even though problem 1 does not exist.
+	valid := `{"problemId":1,"language":"python","code":"print(1)"}`
+	if got := post(valid); got != http.StatusNotFound {
+		t.Fatalf("valid request: status %d, want 404", got)
+	}
+	if got := post(valid); got != http.StatusTooManyRequests {
+		t.Fatalf("second valid request: status %d, want 429", got)
+	}
+}
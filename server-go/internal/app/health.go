@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// handleHealthLive only reports that the process is serving requests.
+// Orchestrators should restart the node when it fails.
+func (a *App) handleHealthLive(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// handleHealthReady reports whether the node should receive traffic: the
+// database and Docker must be reachable and the memory throttle off.
+// Otherwise it returns 503 with the failing checks. Maintenance mode is
+// reported but does not make the node unready, since reads and admin
+// requests are still served.
+func (a *App) handleHealthReady(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{"database": "ok", "docker": "ok", "memory": "ok"}
+	ready := true
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	if err := a.store.Ping(ctx); err != nil {
+		checks["database"] = "unavailable"
+		ready = false
+	}
+	if ok, _ := a.cachedDockerStatus(r.Context()); !ok {
+		checks["docker"] = "unavailable"
+		ready = false
+	}
+	if a.isMemoryThrottled() {
+		w.Header().Set("X-System-Status", "memory_throttle")
+		checks["memory"] = "throttled"
+		ready = false
+	}
+
+	maintenance, _ := a.store.IsMaintenanceMode(ctx)
+
+	if !ready {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "unavailable", "checks": checks, "maintenance": maintenance})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "checks": checks, "maintenance": maintenance})
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+// markIP records an IP mark and applies the auto-ban policy to the result.
+func (a *App) markIP(ctx context.Context, ip, markType string, reason *string, expireAt *time.Time, operator *string) error {
+	err := a.store.UpsertIPMark(ctx, ip, markType, reason, expireAt, operator)
+	a.forgetWhitelist(ip)
+	if err != nil {
+		return err
+	}
+	a.autoBanIP(ctx, ip, markType)
+	return nil
+}
+
+// autoBanIP bans ip for the configured duration when its new mark crosses
+// the auto-ban policy. Failures are logged; the mark itself already stands.
+func (a *App) autoBanIP(ctx context.Context, ip, markType string) {
+	settings, err := a.store.GetIPAutoBanSettings(ctx)
+	if err != nil {
+		log.Printf("[auto-ban] load settings failed: %v", err)
+		return
+	}
+	var suspiciousCount int
+	if markType == "SUSPICIOUS" {
+		// Hits are recorded even while auto-ban is off, so enabling it
+		// later counts marks that are still within the window.
+		since := time.Now().Add(-time.Duration(settings.SuspiciousWindowHours) * time.Hour)
+		suspiciousCount, err = a.store.AddIPSuspiciousHit(ctx, ip, since)
+		if err != nil {
+			log.Printf("[auto-ban] record suspicious mark for %s failed: %v", ip, err)
+			return
+		}
+	}
+	if !settings.Enabled {
+		return
+	}
+	var reason string
+	switch {
+	case markType == "MALICIOUS" && settings.BanOnMalicious:
+		reason = "Auto-ban: IP marked MALICIOUS"
+	case markType == "SUSPICIOUS" && settings.SuspiciousThreshold > 0 && suspiciousCount >= settings.SuspiciousThreshold:
+		reason = fmt.Sprintf("Auto-ban: IP marked SUSPICIOUS %d times within %d hours", suspiciousCount, settings.SuspiciousWindowHours)
+	default:
+		return
+	}
+	// Never shorten or replace an existing (possibly permanent) ban.
+	if banned, err := a.store.IsIPBanned(ctx, ip); err != nil || banned {
+		return
+	}
+	expiresAt := time.Now().Add(time.Duration(settings.BanHours) * time.Hour)
+	if err := a.store.BanIP(ctx, ip, nil, reason, &expiresAt); err != nil {
+		log.Printf("[auto-ban] ban %s failed: %v", ip, err)
+		return
+	}
+	log.Printf("[auto-ban] banned %s until %s: %s", ip, expiresAt.Format(time.RFC3339), reason)
+}
+
+func (a *App) handleAutoBanGet(w http.ResponseWriter, r *http.Request) {
+	settings, err := a.store.GetIPAutoBanSettings(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+func (a *App) handleAutoBanPut(w http.ResponseWriter, r *http.Request) {
+	var body store.IPAutoBanSettings
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if body.SuspiciousThreshold < 0 || body.SuspiciousThreshold > 1000 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "suspiciousThreshold must be between 0 and 1000"})
+		return
+	}
+	if body.SuspiciousWindowHours < 1 || body.SuspiciousWindowHours > 24*365 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "suspiciousWindowHours must be between 1 and 8760"})
+		return
+	}
+	if body.BanHours < 1 || body.BanHours > 24*365 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "banHours must be between 1 and 8760"})
+		return
+	}
+	saved, err := a.store.UpsertIPAutoBanSettings(r.Context(), body)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
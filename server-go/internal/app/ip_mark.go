@@ -0,0 +1,44 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const ipMarkCleanupInterval = 10 * time.Minute
+
+// startIPMarkCleanup periodically deletes expired IP marks, and SUSPICIOUS
+// hits older than the auto-ban window. Readers already ignore both; this only
+// keeps the tables small.
+func (a *App) startIPMarkCleanup() {
+	go func() {
+		ticker := time.NewTicker(ipMarkCleanupInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			a.runIPMarkCleanup()
+		}
+	}()
+}
+
+func (a *App) runIPMarkCleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	n, err := a.store.CleanupExpiredIPMarks(ctx, time.Now())
+	if err != nil {
+		log.Printf("[ip-mark] cleanup expired marks failed: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[ip-mark] removed %d expired marks", n)
+	}
+	settings, err := a.store.GetIPAutoBanSettings(ctx)
+	if err != nil {
+		log.Printf("[ip-mark] load auto-ban settings failed: %v", err)
+		return
+	}
+	before := time.Now().Add(-time.Duration(settings.SuspiciousWindowHours) * time.Hour)
+	if _, err := a.store.CleanupIPSuspiciousHits(ctx, before); err != nil {
+		log.Printf("[ip-mark] cleanup suspicious hits failed: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+const (
+	whitelistCacheTTL   = 30 * time.Second
+	whitelistCacheLimit = 10000
+)
+
+type whitelistEntry struct {
+	whitelisted bool
+	checkedAt   time.Time
+}
+
+// isIPWhitelisted reports whether ip has an active WHITELIST mark, caching
+// the answer for whitelistCacheTTL. Lookup errors count as not whitelisted.
+func (a *App) isIPWhitelisted(ctx context.Context, ip string) bool {
+	a.whitelistMu.Lock()
+	e, ok := a.whitelistCache[ip]
+	a.whitelistMu.Unlock()
+	if ok && time.Since(e.checkedAt) < whitelistCacheTTL {
+		return e.whitelisted
+	}
+
+	whitelisted, err := a.store.IsIPWhitelisted(ctx, ip)
+	if err != nil {
+		return false
+	}
+	a.whitelistMu.Lock()
+	if len(a.whitelistCache) >= whitelistCacheLimit {
+		a.whitelistCache = make(map[string]whitelistEntry)
+	}
+	a.whitelistCache[ip] = whitelistEntry{whitelisted: whitelisted, checkedAt: time.Now()}
+	a.whitelistMu.Unlock()
+	return whitelisted
+}
+
+// forgetWhitelist drops the cached answer for ip after its mark changes.
+func (a *App) forgetWhitelist(ip string) {
+	a.whitelistMu.Lock()
+	delete(a.whitelistCache, store.NormalizeIP(ip))
+	a.whitelistMu.Unlock()
+}
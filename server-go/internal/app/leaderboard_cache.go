@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+// leaderboardCacheTTL bounds how stale a public leaderboard page may be.
+// Judged contest submissions and disqualifications drop a contest's pages
+// sooner; the TTL covers everything else that moves the ranking.
+const leaderboardCacheTTL = 10 * time.Second
+
+// leaderboardCacheMax caps the cached pages across all contests.
+const leaderboardCacheMax = 1024
+
+// leaderboardKey identifies one leaderboard page. scoreVisible is part of
+// the key so a page computed while OI scores were hidden is never served
+// once they are shown, and the other way round.
+type leaderboardKey struct {
+	contestID    int
+	page         int
+	pageSize     int
+	sortBy       string
+	asc          bool
+	scoreVisible bool
+}
+
+type leaderboardEntry struct {
+	items []store.ContestLeaderboardItem
+	total int
+	at    time.Time
+}
+
+// contestLeaderboardPage returns a leaderboard page from the cache while it
+// is fresh, otherwise from the store. Entries are shared between requests
+// and must not be modified.
+func (a *App) contestLeaderboardPage(ctx context.Context, contest store.Contest, key leaderboardKey) ([]store.ContestLeaderboardItem, int, error) {
+	a.leaderboardMu.Lock()
+	e, ok := a.leaderboardCache[key]
+	a.leaderboardMu.Unlock()
+	if ok && time.Since(e.at) < leaderboardCacheTTL {
+		return e.items, e.total, nil
+	}
+
+	start := time.Now()
+	items, total, err := a.store.ListContestLeaderboardPaged(ctx, contest, key.page, key.pageSize, key.sortBy, key.asc)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	a.leaderboardMu.Lock()
+	defer a.leaderboardMu.Unlock()
+	// If the contest was invalidated while the query ran, the result may
+	// predate the change: serve it but do not cache it.
+	if a.leaderboardInvalidated[key.contestID].After(start) {
+		return items, total, nil
+	}
+	if len(a.leaderboardCache) >= leaderboardCacheMax {
+		for k, v := range a.leaderboardCache {
+			if time.Since(v.at) >= leaderboardCacheTTL {
+				delete(a.leaderboardCache, k)
+			}
+		}
+		if len(a.leaderboardCache) >= leaderboardCacheMax {
+			clear(a.leaderboardCache)
+		}
+	}
+	a.leaderboardCache[key] = leaderboardEntry{items: items, total: total, at: start}
+	return items, total, nil
+}
+
+// invalidateContestLeaderboard drops every cached page of a contest.
+func (a *App) invalidateContestLeaderboard(contestID int) {
+	a.leaderboardMu.Lock()
+	defer a.leaderboardMu.Unlock()
+	for k := range a.leaderboardCache {
+		if k.contestID == contestID {
+			delete(a.leaderboardCache, k)
+		}
+	}
+	// Only invalidations newer than a running query matter, and the store
+	// cancels leaderboard queries well within a minute.
+	for id, at := range a.leaderboardInvalidated {
+		if time.Since(at) >= time.Minute {
+			delete(a.leaderboardInvalidated, id)
+		}
+	}
+	a.leaderboardInvalidated[contestID] = time.Now()
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"log"
+	"net/http"
+)
+
+// blockDuringMaintenance rejects the request with 503 while maintenance mode
+// is on, unless the caller is an admin. It guards the write endpoints that
+// students use (register, run, submit); reads stay available. If the setting
+// cannot be read the request is let through.
+func (a *App) blockDuringMaintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		on, err := a.store.IsMaintenanceMode(r.Context())
+		if err != nil {
+			log.Printf("[maintenance] 读取维护模式失败: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !on || a.isAdminRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writeError(w, http.StatusServiceUnavailable, errCodeMaintenance, "The site is under maintenance. Please try again later.")
+	})
+}
+
+// isAdminRequest checks the authenticated user, or the bearer token on
+// routes that do not require authentication.
+func (a *App) isAdminRequest(r *http.Request) bool {
+	u, ok := a.currentUser(r)
+	if !ok {
+		u, ok = a.tryUserFromAuthHeader(r)
+	}
+	return ok && u.Role == "ADMIN"
+}
+
+func (a *App) handleMaintenanceGet(w http.ResponseWriter, r *http.Request) {
+	enabled, err := a.store.IsMaintenanceMode(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"enabled": enabled})
+}
+
+func (a *App) handleMaintenancePut(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if body.Enabled == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "enabled must be boolean"})
+		return
+	}
+	enabled, err := a.store.UpsertMaintenanceMode(r.Context(), *body.Enabled)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"enabled": enabled})
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -51,26 +52,78 @@ func readHostMemory() (used, total uint64) {
 	return memTotal - memAvailable, memTotal
 }
 
-func readCgroupMemory() (used, limit uint64) {
-	usageBytes, errU := os.ReadFile("/sys/fs/cgroup/memory/memory.usage_in_bytes")
-	limitBytes, errL := os.ReadFile("/sys/fs/cgroup/memory/memory.limit_in_bytes")
-	if errU == nil && errL == nil {
-		u, okU := parseUint(string(usageBytes))
-		l, okL := parseUint(string(limitBytes))
-		if okU && okL && l > 0 {
-			return u, l
+// cgroupRoot is where the cgroup filesystem is mounted.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupMemory is a memory sample from the container's cgroup. Version is 0
+// when no cgroup memory controller could be read, and Limit is 0 when the
+// cgroup has no memory limit.
+type cgroupMemory struct {
+	Version int
+	Used    uint64
+	Limit   uint64
+}
+
+// cgroupV1Unlimited is the lower bound of what cgroup v1 reports as an
+// unlimited memory.limit_in_bytes (PAGE_COUNTER_MAX rounded to page size).
+const cgroupV1Unlimited = uint64(1) << 62
+
+func readCgroupMemory() cgroupMemory {
+	return readCgroupMemoryAt(cgroupRoot)
+}
+
+func readCgroupMemoryAt(root string) cgroupMemory {
+	// cgroup.controllers only exists on the unified (v2) hierarchy.
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		return readCgroupV2Memory(root)
+	}
+	if m := readCgroupV1Memory(root); m.Version != 0 {
+		return m
+	}
+	return readCgroupV2Memory(root)
+}
+
+func readCgroupV1Memory(root string) cgroupMemory {
+	usageBytes, err := os.ReadFile(filepath.Join(root, "memory", "memory.usage_in_bytes"))
+	if err != nil {
+		return cgroupMemory{}
+	}
+	u, ok := parseUint(string(usageBytes))
+	if !ok {
+		return cgroupMemory{}
+	}
+	out := cgroupMemory{Version: 1, Used: u}
+	if limitBytes, err := os.ReadFile(filepath.Join(root, "memory", "memory.limit_in_bytes")); err == nil {
+		if l, ok := parseUint(string(limitBytes)); ok && l > 0 && l < cgroupV1Unlimited {
+			out.Limit = l
 		}
 	}
+	return out
+}
 
-	usageBytes, errU = os.ReadFile("/sys/fs/cgroup/memory.current")
-	limitBytes, errL = os.ReadFile("/sys/fs/cgroup/memory.max")
-	if errU == nil && errL == nil {
-		u, okU := parseUint(string(usageBytes))
-		l, okL := parseUint(string(limitBytes))
-		if okU && okL && l > 0 {
-			return u, l
+func readCgroupV2Memory(root string) cgroupMemory {
+	usageBytes, err := os.ReadFile(filepath.Join(root, "memory.current"))
+	if err != nil {
+		return cgroupMemory{}
+	}
+	u, ok := parseUint(string(usageBytes))
+	if !ok {
+		return cgroupMemory{}
+	}
+	out := cgroupMemory{Version: 2, Used: u}
+	// memory.max holds "max" when unlimited, which parseUint rejects.
+	if limitBytes, err := os.ReadFile(filepath.Join(root, "memory.max")); err == nil {
+		if l, ok := parseUint(string(limitBytes)); ok && l > 0 {
+			out.Limit = l
 		}
 	}
-	return 0, 0
+	return out
 }
 
+// Ratio returns used/limit, or 0 when the cgroup is unlimited.
+func (m cgroupMemory) Ratio() float64 {
+	if m.Limit == 0 {
+		return 0
+	}
+	return float64(m.Used) / float64(m.Limit)
+}
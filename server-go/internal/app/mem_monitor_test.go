@@ -0,0 +1,82 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestReadCgroupMemoryAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  cgroupMemory
+	}{
+		{
+			name:  "v2 limited",
+			files: map[string]string{"cgroup.controllers": "memory", "memory.current": "104857600\n", "memory.max": "536870912\n"},
+			want:  cgroupMemory{Version: 2, Used: 100 << 20, Limit: 512 << 20},
+		},
+		{
+			name:  "v2 unlimited",
+			files: map[string]string{"cgroup.controllers": "memory", "memory.current": "4096\n", "memory.max": "max\n"},
+			want:  cgroupMemory{Version: 2, Used: 4096},
+		},
+		{
+			name:  "v2 without memory.max",
+			files: map[string]string{"cgroup.controllers": "", "memory.current": "4096"},
+			want:  cgroupMemory{Version: 2, Used: 4096},
+		},
+		{
+			name:  "v2 unreadable usage",
+			files: map[string]string{"cgroup.controllers": "", "memory.current": "max", "memory.max": "1024"},
+			want:  cgroupMemory{},
+		},
+		{
+			name:  "v1 limited",
+			files: map[string]string{"memory/memory.usage_in_bytes": "2048\n", "memory/memory.limit_in_bytes": "1073741824\n"},
+			want:  cgroupMemory{Version: 1, Used: 2048, Limit: 1 << 30},
+		},
+		{
+			name:  "v1 unlimited",
+			files: map[string]string{"memory/memory.usage_in_bytes": "2048", "memory/memory.limit_in_bytes": strconv.FormatUint(9223372036854771712, 10)},
+			want:  cgroupMemory{Version: 1, Used: 2048},
+		},
+		{
+			name:  "v2 files without cgroup.controllers",
+			files: map[string]string{"memory.current": "4096", "memory.max": "8192"},
+			want:  cgroupMemory{Version: 2, Used: 4096, Limit: 8192},
+		},
+		{
+			name: "nothing readable",
+			want: cgroupMemory{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := readCgroupMemoryAt(root); got != tt.want {
+				t.Errorf("readCgroupMemoryAt = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCgroupMemoryRatio(t *testing.T) {
+	if got := (cgroupMemory{Version: 2, Used: 256, Limit: 1024}).Ratio(); got != 0.25 {
+		t.Errorf("Ratio = %v, want 0.25", got)
+	}
+	if got := (cgroupMemory{Version: 2, Used: 256}).Ratio(); got != 0 {
+		t.Errorf("unlimited Ratio = %v, want 0", got)
+	}
+}
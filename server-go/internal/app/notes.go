@@ -0,0 +1,83 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"onlinejudge-server-go/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxProblemNoteLen = 10000
+
+// handleProblemNoteGet returns the caller's private note on {id}. A problem
+// without a note yields empty content rather than 404 so clients can render
+// the editor unconditionally.
+func (a *App) handleProblemNoteGet(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid problem id"})
+		return
+	}
+	u, _ := a.currentUser(r)
+	if !a.checkProblemVisible(w, r, u, id) {
+		return
+	}
+	note, err := a.store.GetProblemNote(r.Context(), u.ID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusOK, map[string]any{"problemId": id, "content": "", "updatedAt": nil})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
+
+// handleProblemNotePut replaces the caller's note on {id}; blank content
+// deletes it.
+func (a *App) handleProblemNotePut(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid problem id"})
+		return
+	}
+	var body struct {
+		Content *string `json:"content"`
+	}
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if body.Content == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Content is required"})
+		return
+	}
+	if utf8.RuneCountInString(*body.Content) > maxProblemNoteLen {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Note must be at most 10000 characters"})
+		return
+	}
+	u, _ := a.currentUser(r)
+	if !a.checkProblemVisible(w, r, u, id) {
+		return
+	}
+
+	if strings.TrimSpace(*body.Content) == "" {
+		if err := a.store.DeleteProblemNote(r.Context(), u.ID, id); err != nil {
+			a.writeInternalError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"problemId": id, "content": "", "updatedAt": nil})
+		return
+	}
+	note, err := a.store.UpsertProblemNote(r.Context(), u.ID, id, *body.Content)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"onlinejudge-server-go/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	contestReminderInterval = time.Minute
+	// contestReminderWindow is how long before the start participants are
+	// reminded.
+	contestReminderWindow = 15 * time.Minute
+)
+
+// startContestReminders notifies contest participants shortly before their
+// contests start.
+func (a *App) startContestReminders() {
+	go func() {
+		ticker := time.NewTicker(contestReminderInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			a.runContestReminders()
+		}
+	}()
+}
+
+func (a *App) runContestReminders() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	n, err := a.store.NotifyContestStarting(ctx, contestReminderWindow)
+	if err != nil {
+		log.Printf("[notify] contest reminders failed: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[notify] sent %d contest reminders", n)
+	}
+}
+
+// notifyBanned tells a user their account was banned. Failures are only
+// logged since the ban itself already succeeded.
+func (a *App) notifyBanned(ctx context.Context, userID int, reason string) {
+	msg := "Your account has been banned"
+	if reason = strings.TrimSpace(reason); reason != "" {
+		msg += ": " + reason
+	}
+	err := a.store.CreateNotification(ctx, store.CreateNotificationParams{
+		UserID:  userID,
+		Type:    store.NotificationBan,
+		Message: msg,
+	})
+	if err != nil {
+		log.Printf("[notify] ban notification for user %d failed: %v", userID, err)
+	}
+}
+
+// handleUserNotifications lists the caller's newest notifications
+// (?unread=true for unread only, ?limit= up to 100, default 20).
+func (a *App) handleUserNotifications(w http.ResponseWriter, r *http.Request) {
+	u, _ := a.currentUser(r)
+	q := r.URL.Query()
+	limit := 20
+	if raw := strings.TrimSpace(q.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid limit"})
+			return
+		}
+		limit = min(n, 100)
+	}
+	items, err := a.store.ListNotifications(r.Context(), u.ID, q.Get("unread") == "true", limit)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	unread, err := a.store.CountUnreadNotifications(r.Context(), u.ID)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items, "unread": unread})
+}
+
+func (a *App) handleUserNotificationsUnreadCount(w http.ResponseWriter, r *http.Request) {
+	u, _ := a.currentUser(r)
+	unread, err := a.store.CountUnreadNotifications(r.Context(), u.ID)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"unread": unread})
+}
+
+func (a *App) handleUserNotificationRead(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid notification id"})
+		return
+	}
+	u, _ := a.currentUser(r)
+	if err := a.store.MarkNotificationRead(r.Context(), u.ID, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Notification not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": id, "read": true})
+}
+
+func (a *App) handleUserNotificationsReadAll(w http.ResponseWriter, r *http.Request) {
+	u, _ := a.currentUser(r)
+	n, err := a.store.MarkAllNotificationsRead(r.Context(), u.ID)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"marked": n})
+}
@@ -0,0 +1,311 @@
+package app
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// The OpenAPI document is derived from the chi router so every registered
+// route shows up automatically, including whether it needs a token or an
+// admin role. Summaries and request/response shapes for the main endpoints
+// are maintained by hand in openAPIOperations.
+
+type openAPIOperation struct {
+	Summary     string
+	Tag         string
+	RequestBody map[string]any
+	Response    map[string]any
+}
+
+func jsonObject(props map[string]any, required ...string) map[string]any {
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+var (
+	oaString  = map[string]any{"type": "string"}
+	oaInt     = map[string]any{"type": "integer"}
+	oaBool    = map[string]any{"type": "boolean"}
+	oaTime    = map[string]any{"type": "string", "format": "date-time"}
+	oaSuccess = jsonObject(map[string]any{"success": oaBool})
+)
+
+var openAPIUserSchema = jsonObject(map[string]any{
+	"id":           oaInt,
+	"username":     oaString,
+	"role":         map[string]any{"type": "string", "enum": []string{"ADMIN", "STUDENT"}},
+	"isBanned":     oaBool,
+	"bannedAt":     oaTime,
+	"bannedReason": oaString,
+	"preferences":  map[string]any{"type": "object"},
+})
+
+var openAPISubmissionSchema = jsonObject(map[string]any{
+	"id":         oaInt,
+	"language":   oaString,
+	"status":     oaString,
+	"timeUsed":   oaInt,
+	"memoryUsed": oaInt,
+	"score":      oaInt,
+	"createdAt":  oaTime,
+	"problemId":  oaInt,
+	"userId":     oaInt,
+	"contestId":  oaInt,
+})
+
+var openAPIProblemSchema = jsonObject(map[string]any{
+	"id":          oaInt,
+	"title":       oaString,
+	"description": oaString,
+	"timeLimit":   oaInt,
+	"memoryLimit": oaInt,
+	"difficulty":  oaString,
+	"tags":        map[string]any{"type": "array", "items": oaString},
+	"visible":     oaBool,
+})
+
+var openAPICredentials = jsonObject(map[string]any{
+	"username": oaString,
+	"password": oaString,
+	"cfToken":  map[string]any{"type": "string", "description": "captcha token when captcha is enabled"},
+}, "username", "password")
+
+var openAPIOperations = map[string]openAPIOperation{
+	"POST /api/auth/register": {
+		Summary:     "Register a new user",
+		RequestBody: openAPICredentials,
+		Response:    jsonObject(map[string]any{"message": oaString}),
+	},
+	"POST /api/auth/login": {
+		Summary:     "Log in and obtain a JWT",
+		RequestBody: openAPICredentials,
+		Response:    jsonObject(map[string]any{"token": oaString, "role": oaString, "username": oaString}),
+	},
+	"GET /api/auth/me": {
+		Summary:  "Get the current user as stored, including ban status and preferences",
+		Response: openAPIUserSchema,
+	},
+	"POST /api/auth/change-password": {
+		Summary: "Change the current user's password",
+		RequestBody: jsonObject(map[string]any{
+			"currentPassword": oaString,
+			"newPassword":     oaString,
+			"cfToken":         oaString,
+		}, "currentPassword", "newPassword"),
+		Response: oaSuccess,
+	},
+	"GET /api/problems": {
+		Summary:  "List visible problems",
+		Response: map[string]any{"type": "array", "items": openAPIProblemSchema},
+	},
+	"GET /api/problems/{id}": {
+		Summary:  "Get a visible problem",
+		Response: openAPIProblemSchema,
+	},
+	"GET /api/submissions": {
+		Summary:  "List submissions",
+		Response: map[string]any{"type": "array", "items": openAPISubmissionSchema},
+	},
+	"GET /api/submissions/{id}": {
+		Summary:  "Get a submission with its problem and per-case results",
+		Response: openAPISubmissionSchema,
+	},
+	"POST /api/submissions": {
+		Summary: "Submit code for judging",
+		RequestBody: jsonObject(map[string]any{
+			"problemId": oaInt,
+			"code":      oaString,
+			"language":  oaString,
+			"contestId": oaInt,
+			"cfToken":   oaString,
+		}, "problemId", "code", "language"),
+		Response: openAPISubmissionSchema,
+	},
+	"POST /api/run": {
+		Summary: "Run code against custom input without judging",
+		RequestBody: jsonObject(map[string]any{
+			"code":     oaString,
+			"language": oaString,
+			"input":    oaString,
+		}, "code", "language"),
+		Response: jsonObject(map[string]any{
+			"status":     oaString,
+			"output":     oaString,
+			"timeUsed":   oaInt,
+			"memoryUsed": oaInt,
+		}),
+	},
+	"POST /api/contests/{id}/join": {
+		Summary:     "Join a contest",
+		RequestBody: jsonObject(map[string]any{"password": oaString}),
+		Response:    oaSuccess,
+	},
+	"GET /api/settings/registration": {
+		Summary:  "Get whether registration is open",
+		Response: jsonObject(map[string]any{"enabled": oaBool}),
+	},
+}
+
+// handlerOperationID turns "(*App).handleSubmissionCreate-fm" into "submissionCreate".
+func handlerOperationID(h http.Handler) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(h).Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	name = strings.TrimPrefix(name, "handle")
+	if name == "" || strings.HasPrefix(name, "func") {
+		return ""
+	}
+	r := []rune(name)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+func sameFunc(a, b any) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+func (a *App) buildOpenAPI(routes chi.Routes) (map[string]any, error) {
+	paths := map[string]map[string]any{}
+	err := chi.Walk(routes, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if !strings.HasPrefix(route, "/api/") || strings.Contains(route, "*") {
+			return nil
+		}
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		var authed, admin bool
+		for _, mw := range middlewares {
+			if sameFunc(mw, a.authenticateToken) {
+				authed = true
+			}
+			if sameFunc(mw, a.authorizeAdmin) {
+				admin = true
+			}
+		}
+		meta := openAPIOperations[method+" "+route]
+		tag := meta.Tag
+		if tag == "" {
+			tag = strings.SplitN(strings.TrimPrefix(route, "/api/"), "/", 2)[0]
+		}
+		op := map[string]any{"tags": []string{tag}}
+		if id := handlerOperationID(handler); id != "" {
+			op["operationId"] = id
+		}
+		if meta.Summary != "" {
+			op["summary"] = meta.Summary
+		}
+		var params []map[string]any
+		for _, seg := range strings.Split(route, "/") {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				params = append(params, map[string]any{
+					"name":     strings.Trim(seg, "{}"),
+					"in":       "path",
+					"required": true,
+					"schema":   oaString,
+				})
+			}
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if meta.RequestBody != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": meta.RequestBody}},
+			}
+		}
+		okResp := map[string]any{"description": "OK"}
+		if meta.Response != nil {
+			okResp["content"] = map[string]any{"application/json": map[string]any{"schema": meta.Response}}
+		}
+		responses := map[string]any{
+			"200":     okResp,
+			"default": map[string]any{"$ref": "#/components/responses/Error"},
+		}
+		if authed {
+			op["security"] = []map[string][]string{{"bearerAuth": {}}}
+			responses["401"] = map[string]any{"$ref": "#/components/responses/Error"}
+		}
+		if admin {
+			op["x-admin-only"] = true
+			responses["403"] = map[string]any{"$ref": "#/components/responses/Error"}
+		}
+		op["responses"] = responses
+		if paths[route] == nil {
+			paths[route] = map[string]any{}
+		}
+		paths[route][strings.ToLower(method)] = op
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tagSet := map[string]bool{}
+	for _, item := range paths {
+		for _, op := range item {
+			for _, t := range op.(map[string]any)["tags"].([]string) {
+				tagSet[t] = true
+			}
+		}
+	}
+	tags := make([]map[string]any, 0, len(tagSet))
+	for t := range tagSet {
+		tags = append(tags, map[string]any{"name": t})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i]["name"].(string) < tags[j]["name"].(string) })
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Online Judge API",
+			"version": "1.0.0",
+		},
+		"tags":  tags,
+		"paths": paths,
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+			"responses": map[string]any{
+				"Error": map[string]any{
+					"description": "Error",
+					"content": map[string]any{"application/json": map[string]any{
+						"schema": jsonObject(map[string]any{"error": oaString, "code": oaString}, "error"),
+					}},
+				},
+			},
+		},
+	}, nil
+}
+
+func (a *App) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	a.openAPIOnce.Do(func() {
+		routes, ok := a.httpRouter.(chi.Routes)
+		if !ok {
+			a.openAPIErr = http.ErrNotSupported
+			return
+		}
+		a.openAPIDoc, a.openAPIErr = a.buildOpenAPI(routes)
+	})
+	if a.openAPIErr != nil {
+		a.writeInternalError(w, r, a.openAPIErr)
+		return
+	}
+	writeJSON(w, http.StatusOK, a.openAPIDoc)
+}
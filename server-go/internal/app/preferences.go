@@ -0,0 +1,192 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+// maxPreferencesBytes caps the stored preferences object.
+const maxPreferencesBytes = 8 << 10
+
+// preferenceCheck validates one known preference value and returns an error
+// message, or "" when the value is acceptable.
+type preferenceCheck func(v any) string
+
+var preferenceSchema = map[string]preferenceCheck{
+	"theme":         prefOneOf("system", "light", "dark"),
+	"fontFamily":    prefString(100),
+	"fontSize":      prefInt(8, 72),
+	"tabSize":       prefInt(1, 16),
+	"indentUnit":    prefInt(1, 16),
+	"lineNumbers":   prefBool,
+	"foldGutter":    prefBool,
+	"matchBrackets": prefBool,
+	"language":      prefLanguage,
+}
+
+// validatePreferences parses raw as a preferences object. Known keys must
+// have valid values; unknown keys are kept unless strict is set. A missing
+// or null payload yields an empty object.
+func validatePreferences(raw json.RawMessage, strict bool) (map[string]any, string) {
+	if len(raw) > maxPreferencesBytes {
+		return nil, "Preferences must be at most 8 KB"
+	}
+	prefs := map[string]any{}
+	if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return prefs, ""
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&prefs); err != nil || prefs == nil {
+		return nil, "Preferences must be a JSON object"
+	}
+	for key, v := range prefs {
+		check, ok := preferenceSchema[key]
+		if !ok {
+			if strict {
+				return nil, "Unknown preference: " + key
+			}
+			continue
+		}
+		if msg := check(v); msg != "" {
+			return nil, key + " " + msg
+		}
+	}
+	return prefs, ""
+}
+
+func prefOneOf(values ...string) preferenceCheck {
+	return func(v any) string {
+		s, _ := v.(string)
+		for _, allowed := range values {
+			if s == allowed {
+				return ""
+			}
+		}
+		return "is not a valid option"
+	}
+}
+
+func prefString(maxLen int) preferenceCheck {
+	return func(v any) string {
+		s, ok := v.(string)
+		if !ok || utf8.RuneCountInString(s) > maxLen {
+			return "must be a string of at most " + strconv.Itoa(maxLen) + " characters"
+		}
+		return ""
+	}
+}
+
+func prefInt(lo, hi int64) preferenceCheck {
+	return func(v any) string {
+		n, ok := v.(json.Number)
+		if ok {
+			if i, err := n.Int64(); err == nil && i >= lo && i <= hi {
+				return ""
+			}
+		}
+		return "must be an integer between " + strconv.FormatInt(lo, 10) + " and " + strconv.FormatInt(hi, 10)
+	}
+}
+
+func prefBool(v any) string {
+	if _, ok := v.(bool); !ok {
+		return "must be a boolean"
+	}
+	return ""
+}
+
+func prefLanguage(v any) string {
+	s, _ := v.(string)
+	if _, ok := supportedLanguages[s]; !ok {
+		return "is not a supported language"
+	}
+	return ""
+}
+
+// mergePreferences applies patch to current as a JSON merge patch (RFC 7396):
+// objects are merged recursively and a null value removes the key.
+func mergePreferences(current, patch map[string]any) map[string]any {
+	for key, v := range patch {
+		if v == nil {
+			delete(current, key)
+			continue
+		}
+		if sub, ok := v.(map[string]any); ok {
+			if cur, ok := current[key].(map[string]any); ok {
+				current[key] = mergePreferences(cur, sub)
+				continue
+			}
+			current[key] = mergePreferences(map[string]any{}, sub)
+			continue
+		}
+		current[key] = v
+	}
+	return current
+}
+
+// errInvalidPreferences carries a validation message out of the
+// MergeUserPreferences callback.
+type errInvalidPreferences string
+
+func (e errInvalidPreferences) Error() string { return string(e) }
+
+// handlePatchPreferences merges the given keys into the stored preferences,
+// leaving the others untouched. The merged object is validated like a PUT.
+func (a *App) handlePatchPreferences(w http.ResponseWriter, r *http.Request) {
+	u, _ := a.currentUser(r)
+	var body struct {
+		Preferences json.RawMessage `json:"preferences"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	patch := map[string]any{}
+	if err := json.Unmarshal(body.Preferences, &patch); err != nil || patch == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Preferences must be a JSON object"})
+		return
+	}
+
+	var prefs map[string]any
+	_, err := a.store.MergeUserPreferences(r.Context(), u.ID, func(current json.RawMessage) (json.RawMessage, error) {
+		base := map[string]any{}
+		if len(current) > 0 {
+			// Stored preferences that are not an object are replaced.
+			_ = json.Unmarshal(current, &base)
+			if base == nil {
+				base = map[string]any{}
+			}
+		}
+		merged, err := json.Marshal(mergePreferences(base, patch))
+		if err != nil {
+			return nil, err
+		}
+		var msg string
+		prefs, msg = validatePreferences(merged, a.strictPrefs)
+		if msg != "" {
+			return nil, errInvalidPreferences(msg)
+		}
+		return merged, nil
+	})
+	if err != nil {
+		var invalid errInvalidPreferences
+		if errors.As(err, &invalid) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": string(invalid)})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "User not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"success": true, "preferences": prefs})
+}
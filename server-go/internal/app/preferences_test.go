@@ -0,0 +1,89 @@
+package app
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidatePreferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		strict  bool
+		wantErr string // prefix of the message, "" for success
+		keys    int
+	}{
+		{name: "empty", raw: "", keys: 0},
+		{name: "null", raw: "null", keys: 0},
+		{name: "empty object", raw: "{}", keys: 0},
+		{name: "known keys", raw: `{"theme":"dark","fontSize":14,"lineNumbers":false,"language":"cpp","fontFamily":"Fira Code"}`, keys: 5},
+		{name: "bounds inclusive", raw: `{"fontSize":8,"tabSize":16}`, keys: 2},
+		{name: "unknown key kept when not strict", raw: `{"theme":"light","sidebar":{"open":true}}`, keys: 2},
+		{name: "unknown key rejected when strict", raw: `{"theme":"light","sidebar":true}`, strict: true, wantErr: "Unknown preference: sidebar"},
+		{name: "known keys pass when strict", raw: `{"tabSize":4}`, strict: true, keys: 1},
+
+		{name: "not an object", raw: `[1,2]`, wantErr: "Preferences must be a JSON object"},
+		{name: "malformed", raw: `{"theme":`, wantErr: "Preferences must be a JSON object"},
+		{name: "string instead of int", raw: `{"fontSize":"14"}`, wantErr: "fontSize must be an integer"},
+		{name: "fractional int", raw: `{"tabSize":2.5}`, wantErr: "tabSize must be an integer"},
+		{name: "int out of range", raw: `{"fontSize":100}`, wantErr: "fontSize must be an integer between 8 and 72"},
+		{name: "string instead of bool", raw: `{"foldGutter":"true"}`, wantErr: "foldGutter must be a boolean"},
+		{name: "unknown option", raw: `{"theme":"blue"}`, wantErr: "theme is not a valid option"},
+		{name: "number instead of option", raw: `{"theme":1}`, wantErr: "theme is not a valid option"},
+		{name: "unsupported language", raw: `{"language":"cobol"}`, wantErr: "language is not a supported language"},
+		{name: "string too long", raw: `{"fontFamily":"` + strings.Repeat("a", 101) + `"}`, wantErr: "fontFamily must be a string"},
+		{name: "too large", raw: `{"x":"` + strings.Repeat("a", maxPreferencesBytes) + `"}`, wantErr: "Preferences must be at most 8 KB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs, msg := validatePreferences(json.RawMessage(tt.raw), tt.strict)
+			if tt.wantErr != "" {
+				if !strings.HasPrefix(msg, tt.wantErr) || prefs != nil {
+					t.Fatalf("got %v, %q; want an error starting with %q", prefs, msg, tt.wantErr)
+				}
+				return
+			}
+			if msg != "" {
+				t.Fatalf("unexpected error %q", msg)
+			}
+			if prefs == nil || len(prefs) != tt.keys {
+				t.Errorf("got %v, want %d keys", prefs, tt.keys)
+			}
+		})
+	}
+}
+
+func TestMergePreferences(t *testing.T) {
+	decode := func(s string) map[string]any {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	tests := []struct {
+		name                 string
+		current, patch, want string
+	}{
+		{name: "adds and replaces", current: `{"theme":"light","fontSize":14}`, patch: `{"theme":"dark","tabSize":4}`, want: `{"theme":"dark","fontSize":14,"tabSize":4}`},
+		{name: "null deletes", current: `{"theme":"light","fontSize":14}`, patch: `{"fontSize":null}`, want: `{"theme":"light"}`},
+		{name: "null for a missing key", current: `{"theme":"light"}`, patch: `{"tabSize":null}`, want: `{"theme":"light"}`},
+		{name: "nested merge", current: `{"editor":{"vim":true,"wrap":false}}`, patch: `{"editor":{"wrap":true,"minimap":null}}`, want: `{"editor":{"vim":true,"wrap":true}}`},
+		{name: "nested null deletes", current: `{"editor":{"vim":true,"wrap":false}}`, patch: `{"editor":{"vim":null}}`, want: `{"editor":{"wrap":false}}`},
+		{name: "object replaces scalar", current: `{"editor":"vim"}`, patch: `{"editor":{"vim":true,"x":null}}`, want: `{"editor":{"vim":true}}`},
+		{name: "scalar replaces object", current: `{"editor":{"vim":true}}`, patch: `{"editor":"plain"}`, want: `{"editor":"plain"}`},
+		{name: "arrays replaced", current: `{"recent":["a","b"]}`, patch: `{"recent":["c"]}`, want: `{"recent":["c"]}`},
+		{name: "keys the client does not send are kept", current: `{"theme":"dark","layout":{"split":0.5},"custom":1}`, patch: `{"theme":"light"}`, want: `{"theme":"light","layout":{"split":0.5},"custom":1}`},
+		{name: "empty patch", current: `{"theme":"dark"}`, patch: `{}`, want: `{"theme":"dark"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := json.Marshal(mergePreferences(decode(tt.current), decode(tt.patch)))
+			want, _ := json.Marshal(decode(tt.want))
+			if string(got) != string(want) {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+}
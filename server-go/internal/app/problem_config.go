@@ -0,0 +1,195 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"onlinejudge-server-go/internal/judger"
+	"onlinejudge-server-go/internal/store"
+)
+
+// supportedLanguages are the languages the judger can compile and run.
+var supportedLanguages = map[string]struct{}{"cpp": {}, "python": {}}
+
+// limitBounds is the accepted range for problem time limits (ms) and memory
+// limits (MB).
+type limitBounds struct {
+	minTime, maxTime int
+	minMem, maxMem   int
+}
+
+func (b limitBounds) timeError(field string, v int) string {
+	if v < b.minTime || v > b.maxTime {
+		return fmt.Sprintf("%s must be between %d and %d ms", field, b.minTime, b.maxTime)
+	}
+	return ""
+}
+
+func (b limitBounds) memoryError(field string, v int) string {
+	if v < b.minMem || v > b.maxMem {
+		return fmt.Sprintf("%s must be between %d and %d MB", field, b.minMem, b.maxMem)
+	}
+	return ""
+}
+
+// problemLimitErrors checks a problem's limits and its config against the
+// configured bounds, returning one message per problem found.
+func (a *App) problemLimitErrors(timeLimit, memoryLimit int, rawConfig json.RawMessage) []string {
+	var errs []string
+	if msg := a.limitBounds.timeError("timeLimit", timeLimit); msg != "" {
+		errs = append(errs, msg)
+	}
+	if msg := a.limitBounds.memoryError("memoryLimit", memoryLimit); msg != "" {
+		errs = append(errs, msg)
+	}
+	return append(errs, problemConfigErrors(rawConfig, a.limitBounds)...)
+}
+
+// problemConfigErrors checks a submitted problem config and returns one
+// message per problem found. An empty or absent config is valid.
+func problemConfigErrors(raw json.RawMessage, bounds limitBounds) []string {
+	cfg, err := store.ParseProblemConfig(raw)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var errs []string
+	if cfg.CompareMode != "" && !slices.Contains(judger.CompareModes, cfg.CompareMode) {
+		errs = append(errs, "config.compareMode must be one of "+strings.Join(judger.CompareModes, ", "))
+	}
+	if c := cfg.Checker; c != nil {
+		if _, ok := supportedLanguages[c.Language]; !ok {
+			errs = append(errs, "config.checker.language: unsupported language")
+		}
+		if strings.TrimSpace(c.Code) == "" {
+			errs = append(errs, "config.checker.code is required")
+		}
+	}
+	errs = append(errs, codeTemplateErrors("config.templates", cfg.Templates)...)
+	errs = append(errs, scoringConfigErrors(cfg)...)
+	langs := make([]string, 0, len(cfg.Languages))
+	for lang := range cfg.Languages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		if _, ok := supportedLanguages[lang]; !ok {
+			errs = append(errs, "config."+lang+": unsupported language")
+			continue
+		}
+		// Zero leaves the problem default in place.
+		limits := cfg.Languages[lang]
+		if limits.TimeLimit != 0 {
+			if msg := bounds.timeError("config."+lang+".timeLimit", limits.TimeLimit); msg != "" {
+				errs = append(errs, msg)
+			}
+		}
+		if limits.MemoryLimit != 0 {
+			if msg := bounds.memoryError("config."+lang+".memoryLimit", limits.MemoryLimit); msg != "" {
+				errs = append(errs, msg)
+			}
+		}
+	}
+	return errs
+}
+
+// scoringConfigErrors checks the scoring policy and, for subtask scoring,
+// that every subtask has cases and the scores add up to 100.
+func scoringConfigErrors(cfg store.ProblemConfig) []string {
+	if cfg.Scoring != "" && !slices.Contains(store.ScoringPolicies, cfg.Scoring) {
+		return []string{"config.scoring must be one of " + strings.Join(store.ScoringPolicies, ", ")}
+	}
+	if cfg.Scoring != store.ScoringSubtask {
+		if len(cfg.Subtasks) > 0 {
+			return []string{"config.subtasks requires scoring subtask"}
+		}
+		return nil
+	}
+	if len(cfg.Subtasks) == 0 {
+		return []string{"config.subtasks is required for subtask scoring"}
+	}
+	var errs []string
+	total := 0
+	for i, st := range cfg.Subtasks {
+		if st.Cases < 1 {
+			errs = append(errs, fmt.Sprintf("config.subtasks[%d].cases must be positive", i))
+		}
+		if st.Score < 0 {
+			errs = append(errs, fmt.Sprintf("config.subtasks[%d].score must not be negative", i))
+		}
+		total += st.Score
+	}
+	if total != 100 {
+		errs = append(errs, "config.subtasks scores must add up to 100")
+	}
+	return errs
+}
+
+// submissionScore turns per-case results into a 0-100 score under the
+// problem's scoring policy. Subtask scoring falls back to proportional when
+// the subtasks do not cover exactly the problem's test cases, e.g. after
+// cases were added without updating the config.
+func submissionScore(cfg store.ProblemConfig, results []judger.CaseResult, total int) int {
+	if total == 0 {
+		return 0
+	}
+	passed := 0
+	for _, r := range results {
+		if r.Status == "Accepted" {
+			passed++
+		}
+	}
+	switch cfg.Scoring {
+	case store.ScoringBinary:
+		if passed == total {
+			return 100
+		}
+		return 0
+	case store.ScoringSubtask:
+		covered := 0
+		for _, st := range cfg.Subtasks {
+			covered += st.Cases
+		}
+		if covered != total || len(results) != total {
+			break
+		}
+		score, start := 0, 0
+		for _, st := range cfg.Subtasks {
+			ok := true
+			for _, r := range results[start : start+st.Cases] {
+				if r.Status != "Accepted" {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				score += st.Score
+			}
+			start += st.Cases
+		}
+		return score
+	}
+	return int(float64(passed) / float64(total) * 100.0)
+}
+
+// noTestCasesError is the message for saving a problem without test cases,
+// which would make every submission fail at judge time.
+const noTestCasesError = "At least one test case is required; set allowEmpty to save the problem without test cases"
+
+// testCaseErrors reports test cases without an expected output unless the
+// config sets a checker, which is the only way such cases can be judged.
+func testCaseErrors(testCases []store.TestCaseInput, rawConfig json.RawMessage) []string {
+	cfg, _ := store.ParseProblemConfig(rawConfig)
+	if cfg.Checker != nil {
+		return nil
+	}
+	var errs []string
+	for i, tc := range testCases {
+		if strings.TrimSpace(tc.ExpectedOutput) == "" {
+			errs = append(errs, fmt.Sprintf("testCases[%d].expectedOutput is required unless a checker is configured", i))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"testing"
+
+	"onlinejudge-server-go/internal/judger"
+	"onlinejudge-server-go/internal/store"
+)
+
+// caseResults builds judge results from a verdict pattern, one character per
+// case: 'A' for Accepted, anything else for Wrong Answer.
+func caseResults(pattern string) []judger.CaseResult {
+	out := make([]judger.CaseResult, 0, len(pattern))
+	for _, c := range pattern {
+		status := "Wrong Answer"
+		if c == 'A' {
+			status = "Accepted"
+		}
+		out = append(out, judger.CaseResult{Status: status})
+	}
+	return out
+}
+
+func TestSubmissionScore(t *testing.T) {
+	subtasks := store.ProblemConfig{
+		Scoring:  store.ScoringSubtask,
+		Subtasks: []store.Subtask{{Cases: 2, Score: 30}, {Cases: 1, Score: 20}, {Cases: 2, Score: 50}},
+	}
+	tests := []struct {
+		name    string
+		cfg     store.ProblemConfig
+		results string
+		total   int
+		want    int
+	}{
+		{"no cases", store.ProblemConfig{}, "", 0, 0},
+		{"proportional default", store.ProblemConfig{}, "AAW", 3, 66},
+		{"proportional explicit", store.ProblemConfig{Scoring: store.ScoringProportional}, "AAAA", 4, 100},
+		// Cases that never ran, e.g. after stopping on the first failure,
+		// still count towards the total.
+		{"proportional partial run", store.ProblemConfig{}, "AW", 4, 25},
+		{"binary all pass", store.ProblemConfig{Scoring: store.ScoringBinary}, "AAA", 3, 100},
+		{"binary one fails", store.ProblemConfig{Scoring: store.ScoringBinary}, "AAW", 3, 0},
+		{"binary partial run", store.ProblemConfig{Scoring: store.ScoringBinary}, "AA", 3, 0},
+		{"subtask all pass", subtasks, "AAAAA", 5, 100},
+		{"subtask first and last", subtasks, "AAWAA", 5, 80},
+		{"subtask one case fails each", subtasks, "WAAWA", 5, 20},
+		{"subtask none", subtasks, "WWWWW", 5, 0},
+		// Falls back to proportional when the subtasks do not line up.
+		{"subtask cases mismatch", subtasks, "AAAW", 4, 75},
+		{"subtask partial run", subtasks, "AAW", 5, 40},
+	}
+	for _, tt := range tests {
+		if got := submissionScore(tt.cfg, caseResults(tt.results), tt.total); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScoringConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  store.ProblemConfig
+		errs int
+	}{
+		{"default", store.ProblemConfig{}, 0},
+		{"binary", store.ProblemConfig{Scoring: store.ScoringBinary}, 0},
+		{"unknown policy", store.ProblemConfig{Scoring: "best-of"}, 1},
+		{"subtasks without policy", store.ProblemConfig{Subtasks: []store.Subtask{{Cases: 1, Score: 100}}}, 1},
+		{"subtask without subtasks", store.ProblemConfig{Scoring: store.ScoringSubtask}, 1},
+		{"valid subtasks", store.ProblemConfig{
+			Scoring:  store.ScoringSubtask,
+			Subtasks: []store.Subtask{{Cases: 3, Score: 40}, {Cases: 2, Score: 60}},
+		}, 0},
+		{"scores not 100", store.ProblemConfig{
+			Scoring:  store.ScoringSubtask,
+			Subtasks: []store.Subtask{{Cases: 3, Score: 40}, {Cases: 2, Score: 50}},
+		}, 1},
+		{"bad cases and score", store.ProblemConfig{
+			Scoring:  store.ScoringSubtask,
+			Subtasks: []store.Subtask{{Cases: 0, Score: 110}, {Cases: 2, Score: -10}},
+		}, 2},
+	}
+	for _, tt := range tests {
+		if got := scoringConfigErrors(tt.cfg); len(got) != tt.errs {
+			t.Errorf("%s: got %q, want %d errors", tt.name, got, tt.errs)
+		}
+	}
+}
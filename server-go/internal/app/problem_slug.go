@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+const maxProblemSlugLen = 64
+
+// problemSlugPattern allows lowercase words joined by single hyphens.
+var problemSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// parseProblemSlug validates the optional slug of a problem payload. An
+// absent, null or empty value yields nil, meaning the problem has no slug.
+// All-digit slugs are rejected because they would shadow problem ids.
+func parseProblemSlug(v any) (*string, string) {
+	if v == nil {
+		return nil, ""
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, "slug must be a string"
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, ""
+	}
+	if len(s) > maxProblemSlugLen {
+		return nil, "slug must be at most 64 characters"
+	}
+	if !problemSlugPattern.MatchString(s) {
+		return nil, "slug may only contain lowercase letters, digits and single hyphens"
+	}
+	if _, isID := parseIntParam(s); isID {
+		return nil, "slug must not be a number"
+	}
+	return &s, ""
+}
+
+// getProblemByRef resolves a URL segment that is either a numeric problem id
+// or a slug.
+func (a *App) getProblemByRef(ctx context.Context, ref string) (store.Problem, error) {
+	if id, ok := parseIntParam(ref); ok {
+		return a.store.GetProblemByID(ctx, id)
+	}
+	return a.store.GetProblemBySlug(ctx, ref)
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProblemSlug(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+		want string // "" with wantErr "" means no slug
+		err  string
+	}{
+		{name: "absent", in: nil},
+		{name: "empty", in: ""},
+		{name: "blank", in: "   "},
+		{name: "simple", in: "two-sum", want: "two-sum"},
+		{name: "trimmed", in: " a-plus-b ", want: "a-plus-b"},
+		{name: "digits with letters", in: "p1000", want: "p1000"},
+		{name: "max length", in: strings.Repeat("a", maxProblemSlugLen), want: strings.Repeat("a", maxProblemSlugLen)},
+
+		{name: "not a string", in: 12, err: "slug must be a string"},
+		{name: "too long", in: strings.Repeat("a", maxProblemSlugLen+1), err: "slug must be at most 64 characters"},
+		{name: "uppercase", in: "Two-Sum", err: "slug may only contain lowercase letters, digits and single hyphens"},
+		{name: "underscore", in: "two_sum", err: "slug may only contain lowercase letters, digits and single hyphens"},
+		{name: "space", in: "two sum", err: "slug may only contain lowercase letters, digits and single hyphens"},
+		{name: "slash", in: "a/b", err: "slug may only contain lowercase letters, digits and single hyphens"},
+		{name: "non-ascii", in: "résumé", err: "slug may only contain lowercase letters, digits and single hyphens"},
+		{name: "double hyphen", in: "a--b", err: "slug may only contain lowercase letters, digits and single hyphens"},
+		{name: "leading hyphen", in: "-ab", err: "slug may only contain lowercase letters, digits and single hyphens"},
+		{name: "trailing hyphen", in: "ab-", err: "slug may only contain lowercase letters, digits and single hyphens"},
+		{name: "numeric", in: "1001", err: "slug must not be a number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, msg := parseProblemSlug(tt.in)
+			if msg != tt.err {
+				t.Fatalf("error = %q, want %q", msg, tt.err)
+			}
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("slug = %q, want none", *got)
+			case tt.want != "" && (got == nil || *got != tt.want):
+				t.Errorf("slug = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"onlinejudge-server-go/internal/judger"
+	"onlinejudge-server-go/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultStabilityRuns = 3
+	maxStabilityRuns     = 10
+)
+
+// caseStability summarises one test case over repeated runs. Verdicts counts
+// each status seen; the case is stable when only one was.
+type caseStability struct {
+	Index     int            `json:"index"`
+	Stable    bool           `json:"stable"`
+	Verdicts  map[string]int `json:"verdicts"`
+	MinTime   int            `json:"minTime"`
+	MaxTime   int            `json:"maxTime"`
+	MinMemory int            `json:"minMemory"`
+	MaxMemory int            `json:"maxMemory"`
+}
+
+// summarizeStability folds the per-run case results into one entry per
+// case. Every run must cover the same cases.
+func summarizeStability(runs [][]judger.CaseResult) []caseStability {
+	if len(runs) == 0 {
+		return []caseStability{}
+	}
+	out := make([]caseStability, len(runs[0]))
+	for i := range out {
+		out[i] = caseStability{Index: i, Verdicts: map[string]int{}}
+	}
+	for n, results := range runs {
+		for i, res := range results {
+			if i >= len(out) {
+				break
+			}
+			cs := &out[i]
+			cs.Verdicts[res.Status]++
+			if n == 0 || res.TimeUsed < cs.MinTime {
+				cs.MinTime = res.TimeUsed
+			}
+			if n == 0 || res.MemoryUsed < cs.MinMemory {
+				cs.MinMemory = res.MemoryUsed
+			}
+			cs.MaxTime = max(cs.MaxTime, res.TimeUsed)
+			cs.MaxMemory = max(cs.MaxMemory, res.MemoryUsed)
+		}
+	}
+	for i := range out {
+		out[i].Stable = len(out[i].Verdicts) == 1
+	}
+	return out
+}
+
+// handleProblemStabilityCheck judges a reference solution against a saved
+// problem several times and reports the cases whose verdict changed between
+// runs, along with the spread of time and memory. It catches limits that
+// sit too close to the solution's running time before students hit them.
+// It shares the run-code rate limit and memory throttle with validate.
+func (a *App) handleProblemStabilityCheck(w http.ResponseWriter, r *http.Request) {
+	u, _ := a.currentUser(r)
+	id, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid problem id"})
+		return
+	}
+
+	if a.isMemoryThrottled() {
+		w.Header().Set("X-System-Status", "memory_throttle")
+		log.Printf("[memory-throttle] 内存限流拒绝 user=%d path=%s", u.ID, r.URL.Path)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"error": "System is under memory pressure. Please try again later.",
+		})
+		return
+	}
+
+	if !a.checkCodeRunLimit(w, r, u.ID, a.clientIP(r)) {
+		return
+	}
+
+	var body struct {
+		Language string `json:"language"`
+		Code     string `json:"code"`
+		Runs     int    `json:"runs"`
+	}
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if strings.TrimSpace(body.Code) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "code is required"})
+		return
+	}
+	if _, ok := supportedLanguages[body.Language]; !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Unsupported language"})
+		return
+	}
+	if body.Runs == 0 {
+		body.Runs = defaultStabilityRuns
+	}
+	if body.Runs < 2 || body.Runs > maxStabilityRuns {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "runs must be between 2 and 10"})
+		return
+	}
+
+	p, err := a.store.GetProblemWithTestCases(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	if len(p.TestCases) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Problem has no test cases"})
+		return
+	}
+
+	opts := a.judgeOptions(r.Context(), p.Problem, body.Language)
+	testCases := make([]judger.TestCase, 0, len(p.TestCases))
+	for _, tc := range p.TestCases {
+		testCases = append(testCases, judger.TestCase{Input: tc.Input, ExpectedOutput: tc.ExpectedOutput})
+	}
+
+	runs := make([][]judger.CaseResult, 0, body.Runs)
+	for n := 1; n <= body.Runs; n++ {
+		ctx, cancel := context.WithTimeout(r.Context(), judger.ContainerLifetime(len(testCases), opts))
+		judgeRes, err := a.docker.Judge(ctx, body.Language, body.Code, testCases, opts)
+		cancel()
+		if a.writeJudgeError(w, r, err) {
+			return
+		}
+		if judgeRes.Status != "Judged" {
+			// A compile error or system error ends the check; later runs
+			// would only repeat it.
+			writeJSON(w, http.StatusOK, map[string]any{
+				"status": judgeRes.Status,
+				"output": judgeRes.Output,
+				"run":    n,
+			})
+			return
+		}
+		runs = append(runs, judgeRes.Results)
+	}
+
+	cases := summarizeStability(runs)
+	unstable := []int{}
+	for _, cs := range cases {
+		if !cs.Stable {
+			unstable = append(unstable, cs.Index)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"runs":          body.Runs,
+		"stable":        len(unstable) == 0,
+		"unstableCases": unstable,
+		"timeLimit":     opts.TimeLimitMs,
+		"memoryLimit":   opts.MemoryLimitMB,
+		"cases":         cases,
+	})
+}
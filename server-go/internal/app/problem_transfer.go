@@ -0,0 +1,156 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"onlinejudge-server-go/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// problemBundleVersion is bumped whenever the bundle layout changes in a way
+// older servers cannot read.
+const problemBundleVersion = 1
+
+// problemBundle is the portable single-file form of a problem used to move
+// it between instances. Ids and authorship are deliberately left out since
+// they mean nothing on the importing server.
+type problemBundle struct {
+	Version               int                 `json:"version"`
+	ExportedAt            time.Time           `json:"exportedAt"`
+	Title                 string              `json:"title"`
+	Description           string              `json:"description"`
+	TimeLimit             int                 `json:"timeLimit"`
+	MemoryLimit           int                 `json:"memoryLimit"`
+	DefaultCompileOptions string              `json:"defaultCompileOptions"`
+	Difficulty            string              `json:"difficulty"`
+	Tags                  []string            `json:"tags"`
+	Slug                  *string             `json:"slug,omitempty"`
+	Config                json.RawMessage     `json:"config,omitempty"`
+	TestCases             []problemBundleCase `json:"testCases"`
+}
+
+type problemBundleCase struct {
+	Input          string `json:"input"`
+	ExpectedOutput string `json:"expectedOutput"`
+}
+
+func (a *App) handleProblemExport(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid problem id"})
+		return
+	}
+	p, err := a.store.GetProblemWithTestCases(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Problem not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+
+	bundle := problemBundle{
+		Version:               problemBundleVersion,
+		ExportedAt:            time.Now().UTC(),
+		Title:                 p.Title,
+		Description:           p.Description,
+		TimeLimit:             p.TimeLimit,
+		MemoryLimit:           p.MemoryLimit,
+		DefaultCompileOptions: p.DefaultCompileOptions,
+		Difficulty:            p.Difficulty,
+		Tags:                  p.Tags,
+		Slug:                  p.Slug,
+		Config:                p.Config,
+		TestCases:             make([]problemBundleCase, 0, len(p.TestCases)),
+	}
+	for _, tc := range p.TestCases {
+		bundle.TestCases = append(bundle.TestCases, problemBundleCase{Input: tc.Input, ExpectedOutput: tc.ExpectedOutput})
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="problem-`+strconv.Itoa(id)+`.json"`)
+	writeJSON(w, http.StatusOK, bundle)
+}
+
+// handleProblemImport creates a new problem from a bundle produced by
+// handleProblemExport, applying the same validation as handleProblemCreate.
+// Since the body is the bundle, allowEmpty is a query parameter here.
+func (a *App) handleProblemImport(w http.ResponseWriter, r *http.Request) {
+	var bundle problemBundle
+	if err := readJSON(r, &bundle); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if bundle.Version != problemBundleVersion {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error": fmt.Sprintf("Unsupported bundle version %d, expected %d", bundle.Version, problemBundleVersion),
+		})
+		return
+	}
+	if strings.TrimSpace(bundle.Title) == "" || strings.TrimSpace(bundle.Description) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid payload"})
+		return
+	}
+	var slug *string
+	if bundle.Slug != nil {
+		var slugErr string
+		if slug, slugErr = parseProblemSlug(*bundle.Slug); slugErr != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": slugErr})
+			return
+		}
+	}
+	if strings.TrimSpace(bundle.Difficulty) == "" {
+		bundle.Difficulty = "LEVEL2"
+	}
+	if errs := a.problemLimitErrors(bundle.TimeLimit, bundle.MemoryLimit, bundle.Config); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid limits or config", "details": errs})
+		return
+	}
+	if errs := compileOptionErrors("defaultCompileOptions", bundle.DefaultCompileOptions); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid compile options", "details": errs})
+		return
+	}
+
+	testCases := make([]store.TestCaseInput, 0, len(bundle.TestCases))
+	for _, tc := range bundle.TestCases {
+		testCases = append(testCases, store.TestCaseInput{Input: tc.Input, ExpectedOutput: tc.ExpectedOutput})
+	}
+	if allowEmpty, _ := strconv.ParseBool(r.URL.Query().Get("allowEmpty")); len(testCases) == 0 && !allowEmpty {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": noTestCasesError})
+		return
+	}
+	if errs := testCaseErrors(testCases, bundle.Config); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid test cases", "details": errs})
+		return
+	}
+
+	created, err := a.store.CreateProblem(r.Context(), store.CreateProblemParams{
+		Title:                 bundle.Title,
+		Description:           bundle.Description,
+		TimeLimit:             bundle.TimeLimit,
+		MemoryLimit:           bundle.MemoryLimit,
+		DefaultCompileOptions: bundle.DefaultCompileOptions,
+		Difficulty:            bundle.Difficulty,
+		Tags:                  uniqNonEmpty(bundle.Tags),
+		Slug:                  slug,
+		Config:                bundle.Config,
+		TestCases:             testCases,
+		CreatedBy:             a.currentUserID(r),
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrUniqueViolation) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Slug already exists"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, created)
+}
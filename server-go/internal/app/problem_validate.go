@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"onlinejudge-server-go/internal/judger"
+	"onlinejudge-server-go/internal/store"
+)
+
+// handleProblemValidate runs a reference solution against draft limits and
+// test cases so authors can check their data before saving. Nothing is
+// persisted. It shares the run-code rate limit and memory throttle.
+func (a *App) handleProblemValidate(w http.ResponseWriter, r *http.Request) {
+	u, _ := a.currentUser(r)
+
+	if a.isMemoryThrottled() {
+		w.Header().Set("X-System-Status", "memory_throttle")
+		log.Printf("[memory-throttle] 内存限流拒绝 user=%d path=%s", u.ID, r.URL.Path)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"error": "System is under memory pressure. Please try again later.",
+		})
+		return
+	}
+
+	if !a.checkCodeRunLimit(w, r, u.ID, a.clientIP(r)) {
+		return
+	}
+
+	var body struct {
+		TimeLimit             int             `json:"timeLimit"`
+		MemoryLimit           int             `json:"memoryLimit"`
+		Config                json.RawMessage `json:"config"`
+		DefaultCompileOptions string          `json:"defaultCompileOptions"`
+		Language              string          `json:"language"`
+		Code                  string          `json:"code"`
+		TestCases             []struct {
+			Input          string `json:"input"`
+			ExpectedOutput string `json:"expectedOutput"`
+		} `json:"testCases"`
+	}
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if strings.TrimSpace(body.Code) == "" || len(body.TestCases) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "code and at least one test case are required"})
+		return
+	}
+	if _, ok := supportedLanguages[body.Language]; !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Unsupported language"})
+		return
+	}
+	if errs := a.problemLimitErrors(body.TimeLimit, body.MemoryLimit, body.Config); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid limits or config", "details": errs})
+		return
+	}
+	if errs := compileOptionErrors("defaultCompileOptions", body.DefaultCompileOptions); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid compile options", "details": errs})
+		return
+	}
+
+	opts := a.judgeOptions(r.Context(), store.Problem{
+		TimeLimit:             body.TimeLimit,
+		MemoryLimit:           body.MemoryLimit,
+		Config:                body.Config,
+		DefaultCompileOptions: body.DefaultCompileOptions,
+	}, body.Language)
+	inputs := make([]store.TestCaseInput, 0, len(body.TestCases))
+	testCases := make([]judger.TestCase, 0, len(body.TestCases))
+	for _, tc := range body.TestCases {
+		inputs = append(inputs, store.TestCaseInput{Input: tc.Input, ExpectedOutput: tc.ExpectedOutput})
+		testCases = append(testCases, judger.TestCase{Input: tc.Input, ExpectedOutput: tc.ExpectedOutput})
+	}
+	if errs := testCaseErrors(inputs, body.Config); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid test cases", "details": errs})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), judger.ContainerLifetime(len(testCases), opts))
+	defer cancel()
+	judgeRes, err := a.docker.Judge(ctx, body.Language, body.Code, testCases, opts)
+	if a.writeJudgeError(w, r, err) {
+		return
+	}
+	if judgeRes.Status != "Judged" {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"status": judgeRes.Status,
+			"output": judgeRes.Output,
+			"total":  len(testCases),
+		})
+		return
+	}
+
+	status := "Accepted"
+	passed := 0
+	for _, res := range judgeRes.Results {
+		if res.Status == "Accepted" {
+			passed++
+		} else if status == "Accepted" {
+			status = res.Status
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":  status,
+		"passed":  passed,
+		"total":   len(testCases),
+		"results": judgeRes.Results,
+	})
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// rateLimitStatus describes one one-minute sliding window. ResetAt is when
+// the oldest counted event leaves the window, nil when nothing is counted.
+type rateLimitStatus struct {
+	Limit     int        `json:"limit"`
+	Used      int        `json:"used"`
+	Remaining int        `json:"remaining"`
+	ResetAt   *time.Time `json:"resetAt"`
+	Window    string     `json:"window"`
+}
+
+func newRateLimitStatus(limit, used int, oldest *time.Time) rateLimitStatus {
+	st := rateLimitStatus{Limit: limit, Used: used, Remaining: max(0, limit-used), Window: "1 minute"}
+	if oldest != nil {
+		resetAt := oldest.Add(time.Minute)
+		st.ResetAt = &resetAt
+	}
+	return st
+}
+
+// handleSubmissionRateLimitStatus reports the caller's submission budget for
+// practice, or for the contest given by ?contestId=, without consuming it.
+func (a *App) handleSubmissionRateLimitStatus(w http.ResponseWriter, r *http.Request) {
+	u, _ := a.currentUser(r)
+	var contestID *int
+	if raw := strings.TrimSpace(r.URL.Query().Get("contestId")); raw != "" {
+		id, ok := parseIntParam(raw)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid contest id"})
+			return
+		}
+		contestID = &id
+	}
+
+	limit, err := a.store.GetEffectiveSubmissionRateLimit(r.Context(), contestID)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	windowStart := time.Now().Add(-time.Minute)
+	used, err := a.store.CountUserSubmissionsInWindow(r.Context(), u.ID, contestID, windowStart)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	var oldest *time.Time
+	if used > 0 {
+		t, err := a.store.OldestUserSubmissionInWindow(r.Context(), u.ID, contestID, windowStart)
+		if err == nil {
+			oldest = &t
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"contestId": contestID,
+		"exempt":    a.isIPWhitelisted(r.Context(), a.clientIP(r)),
+		"status":    newRateLimitStatus(limit, used, oldest),
+	})
+}
+
+// handleCodeRunRateLimitStatus reports the caller's per-user and per-IP
+// code-run budgets without consuming them.
+func (a *App) handleCodeRunRateLimitStatus(w http.ResponseWriter, r *http.Request) {
+	u, _ := a.currentUser(r)
+	clientIP := a.clientIP(r)
+	user, ip, err := a.codeRunStatus(r.Context(), u.ID, clientIP)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"exempt": a.isIPWhitelisted(r.Context(), clientIP),
+		"user":   user,
+		"ip":     ip,
+	})
+}
+
+// codeRunStatus reads the code-run windows that allowCodeRun enforces.
+func (a *App) codeRunStatus(ctx context.Context, userID int, clientIP string) (rateLimitStatus, rateLimitStatus, error) {
+	limit, err := a.store.GetCodeRunRateLimit(ctx)
+	if err != nil {
+		return rateLimitStatus{}, rateLimitStatus{}, err
+	}
+	ipLimit, err := a.store.GetCodeRunIPRateLimit(ctx)
+	if err != nil {
+		return rateLimitStatus{}, rateLimitStatus{}, err
+	}
+	windowStart := time.Now().Add(-time.Minute)
+
+	a.codeRunMu.Lock()
+	defer a.codeRunMu.Unlock()
+
+	times := pruneRunWindow(a.codeRunHistory[userID], windowStart)
+	ipTimes := pruneRunWindow(a.codeRunIPHistory[clientIP], windowStart)
+	a.codeRunHistory[userID] = times
+	a.codeRunIPHistory[clientIP] = ipTimes
+	return newRateLimitStatus(limit, len(times), oldestRun(times)), newRateLimitStatus(ipLimit, len(ipTimes), oldestRun(ipTimes)), nil
+}
+
+func oldestRun(times []time.Time) *time.Time {
+	if len(times) == 0 {
+		return nil
+	}
+	t := times[0]
+	return &t
+}
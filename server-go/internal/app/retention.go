@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	retentionInterval  = time.Hour
+	retentionBatchSize = 1000
+	maxRetentionDays   = 3650
+)
+
+// startRetentionCleanup periodically trims heavy fields of submissions older
+// than the configured retention window. A zero window disables it.
+func (a *App) startRetentionCleanup() {
+	go func() {
+		ticker := time.NewTicker(retentionInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			a.runRetentionCleanup()
+		}
+	}()
+}
+
+func (a *App) runRetentionCleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	days, err := a.store.GetSubmissionRetentionDays(ctx)
+	if err != nil || days <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+	n, err := a.store.TrimOldSubmissions(ctx, cutoff, retentionBatchSize)
+	if err != nil {
+		log.Printf("[retention] trim submissions before %s failed after %d rows: %v", cutoff.Format(time.RFC3339), n, err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[retention] trimmed %d submissions older than %d days", n, days)
+	}
+}
+
+func (a *App) handleRetentionGet(w http.ResponseWriter, r *http.Request) {
+	days, err := a.store.GetSubmissionRetentionDays(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"days": days})
+}
+
+func (a *App) handleRetentionPut(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Days int `json:"days"`
+	}
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if body.Days < 0 || body.Days > maxRetentionDays {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Retention days must be between 0 and 3650"})
+		return
+	}
+	days, err := a.store.UpsertSubmissionRetentionDays(r.Context(), body.Days)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"days": days})
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+const (
+	sensitiveReloadInterval = time.Minute
+	// sensitiveCacheLimit bounds the per-path cache; paths embed ids, so
+	// the set of distinct paths is unbounded.
+	sensitiveCacheLimit = 10000
+
+	maxSensitivePatterns      = 100
+	maxSensitivePatternLength = 200
+)
+
+// pathMatcher is a compiled store.SensitivePathPatterns.
+type pathMatcher struct {
+	prefixes []string
+	contains []string
+	regexes  []*regexp.Regexp
+}
+
+// compilePathMatcher compiles patterns, returning the first invalid regex
+// as an error.
+func compilePathMatcher(p store.SensitivePathPatterns) (*pathMatcher, error) {
+	m := &pathMatcher{}
+	for _, s := range p.Prefixes {
+		m.prefixes = append(m.prefixes, strings.ToLower(s))
+	}
+	for _, s := range p.Contains {
+		m.contains = append(m.contains, strings.ToLower(s))
+	}
+	for _, s := range p.Regexes {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+		m.regexes = append(m.regexes, re)
+	}
+	return m, nil
+}
+
+func (m *pathMatcher) match(p string) bool {
+	l := strings.ToLower(p)
+	for _, s := range m.prefixes {
+		if strings.HasPrefix(l, s) {
+			return true
+		}
+	}
+	for _, s := range m.contains {
+		if strings.Contains(l, s) {
+			return true
+		}
+	}
+	for _, re := range m.regexes {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// currentSensitiveMatcher returns the active matcher, reloading it from
+// settings when stale. On a load error the previous matcher (or the
+// defaults) stays in effect.
+func (a *App) currentSensitiveMatcher() *pathMatcher {
+	a.sensitiveMu.RLock()
+	m, loadedAt := a.sensitiveMatcher, a.sensitiveLoadedAt
+	a.sensitiveMu.RUnlock()
+	if m != nil && time.Since(loadedAt) < sensitiveReloadInterval {
+		return m
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	patterns, err := a.store.GetSensitivePathPatterns(ctx)
+	var next *pathMatcher
+	if err == nil {
+		next, err = compilePathMatcher(patterns)
+	}
+	if err != nil {
+		log.Printf("[sensitive] failed to load path patterns: %v", err)
+		if m != nil {
+			next = m
+		} else {
+			next, _ = compilePathMatcher(store.DefaultSensitivePathPatterns())
+		}
+	}
+	a.setSensitiveMatcher(next)
+	return next
+}
+
+// setSensitiveMatcher installs m and drops cached results computed with the
+// previous patterns.
+func (a *App) setSensitiveMatcher(m *pathMatcher) {
+	a.sensitiveMu.Lock()
+	a.sensitiveMatcher = m
+	a.sensitiveLoadedAt = time.Now()
+	a.sensitiveMu.Unlock()
+	a.sensitiveCache.Clear()
+	atomic.StoreInt64(&a.sensitiveCacheSize, 0)
+}
+
+func (a *App) isSensitivePath(p string) bool {
+	m := a.currentSensitiveMatcher()
+	if v, ok := a.sensitiveCache.Load(p); ok {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	sensitive := m.match(p)
+	if atomic.AddInt64(&a.sensitiveCacheSize, 1) > sensitiveCacheLimit {
+		a.sensitiveCache.Clear()
+		atomic.StoreInt64(&a.sensitiveCacheSize, 0)
+	}
+	a.sensitiveCache.Store(p, sensitive)
+	return sensitive
+}
+
+func (a *App) handleSensitivePatternsGet(w http.ResponseWriter, r *http.Request) {
+	p, err := a.store.GetSensitivePathPatterns(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (a *App) handleSensitivePatternsPut(w http.ResponseWriter, r *http.Request) {
+	var body store.SensitivePathPatterns
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	body.Prefixes = uniqNonEmpty(body.Prefixes)
+	body.Contains = uniqNonEmpty(body.Contains)
+	body.Regexes = uniqNonEmpty(body.Regexes)
+	if msg := validateSensitivePatterns(body); msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": msg})
+		return
+	}
+	m, err := compilePathMatcher(body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid regex: " + err.Error()})
+		return
+	}
+	saved, err := a.store.UpsertSensitivePathPatterns(r.Context(), body)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	a.setSensitiveMatcher(m)
+	writeJSON(w, http.StatusOK, saved)
+}
+
+// validateSensitivePatterns returns a client-facing message for patterns
+// that exceed the size limits, or "" when they are acceptable.
+func validateSensitivePatterns(p store.SensitivePathPatterns) string {
+	if len(p.Prefixes)+len(p.Contains)+len(p.Regexes) > maxSensitivePatterns {
+		return "Too many patterns (max 100)"
+	}
+	for _, list := range [][]string{p.Prefixes, p.Contains, p.Regexes} {
+		for _, s := range list {
+			if len(s) > maxSensitivePatternLength {
+				return "Pattern too long (max 200 characters)"
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+const (
+	sensitiveAlertInterval = time.Minute
+
+	webhookEventSensitiveSpike = "security.sensitive_spike"
+)
+
+type sensitiveSpikePayload struct {
+	Event         string    `json:"event"`
+	IP            string    `json:"ip"`
+	Count         int       `json:"count"`
+	WindowMinutes int       `json:"windowMinutes"`
+	LastSeen      time.Time `json:"lastSeen"`
+	MarkedUntil   time.Time `json:"markedUntil"`
+}
+
+// startSensitiveAlerts periodically marks IPs whose sensitive-path hits in
+// the configured window reach the threshold as SUSPICIOUS.
+func (a *App) startSensitiveAlerts() {
+	go func() {
+		ticker := time.NewTicker(sensitiveAlertInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			a.evaluateSensitiveSpikes()
+		}
+	}()
+}
+
+func (a *App) evaluateSensitiveSpikes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	settings, err := a.store.GetSensitiveAlertSettings(ctx)
+	if err != nil || !settings.Enabled {
+		return
+	}
+	now := time.Now()
+	spikes, err := a.store.ListSensitiveIPCounts(ctx, now.Add(-time.Duration(settings.WindowMinutes)*time.Minute), settings.Threshold)
+	if err != nil {
+		log.Printf("[sensitive-alert] count sensitive hits failed: %v", err)
+		return
+	}
+	operator := "system"
+	for _, sp := range spikes {
+		expireAt := now.Add(time.Duration(settings.MarkHours) * time.Hour)
+		reason := fmt.Sprintf("%d sensitive-path requests within %d minutes", sp.Count, settings.WindowMinutes)
+		if err := a.markIP(ctx, sp.IP, "SUSPICIOUS", &reason, &expireAt, &operator); err != nil {
+			log.Printf("[sensitive-alert] mark %s failed: %v", sp.IP, err)
+			continue
+		}
+		log.Printf("[sensitive-alert] marked %s SUSPICIOUS: %s", sp.IP, reason)
+		if settings.Notify {
+			a.broadcastWebhook(webhookEventSensitiveSpike, sensitiveSpikePayload{
+				Event:         webhookEventSensitiveSpike,
+				IP:            sp.IP,
+				Count:         sp.Count,
+				WindowMinutes: settings.WindowMinutes,
+				LastSeen:      sp.LastSeen,
+				MarkedUntil:   expireAt,
+			})
+		}
+	}
+}
+
+func (a *App) handleSensitiveAlertGet(w http.ResponseWriter, r *http.Request) {
+	settings, err := a.store.GetSensitiveAlertSettings(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+func (a *App) handleSensitiveAlertPut(w http.ResponseWriter, r *http.Request) {
+	var body store.SensitiveAlertSettings
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if body.Threshold < 1 || body.Threshold > 100000 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "threshold must be between 1 and 100000"})
+		return
+	}
+	if body.WindowMinutes < 1 || body.WindowMinutes > 1440 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "windowMinutes must be between 1 and 1440"})
+		return
+	}
+	if body.MarkHours < 1 || body.MarkHours > 24*365 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "markHours must be between 1 and 8760"})
+		return
+	}
+	saved, err := a.store.UpsertSensitiveAlertSettings(r.Context(), body)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+func TestPathMatcher(t *testing.T) {
+	m, err := compilePathMatcher(store.SensitivePathPatterns{
+		Prefixes: []string{"/API/Admin", "/.git"},
+		Contains: []string{"Config"},
+		Regexes:  []string{`^/backup-\d+\.zip$`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/api/admin/users", true},
+		{"/API/ADMIN", true},
+		{"/.git/HEAD", true},
+		{"/static/app.config.js", true},
+		{"/CONFIG", true},
+		{"/backup-2024.zip", true},
+		// Regexes are case-sensitive and anchored as written.
+		{"/BACKUP-2024.zip", false},
+		{"/old/backup-2024.zip", false},
+		{"/api/problems", false},
+		{"/x/api/admin", false},
+	}
+	for _, tt := range tests {
+		if got := m.match(tt.path); got != tt.want {
+			t.Errorf("match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCompilePathMatcherInvalidRegex(t *testing.T) {
+	if _, err := compilePathMatcher(store.SensitivePathPatterns{Regexes: []string{"("}}); err == nil {
+		t.Fatal("expected an error for an invalid regex")
+	}
+}
+
+func TestIsSensitivePathCache(t *testing.T) {
+	a := &App{}
+	m, _ := compilePathMatcher(store.SensitivePathPatterns{Prefixes: []string{"/admin"}})
+	a.setSensitiveMatcher(m)
+	if !a.isSensitivePath("/admin/x") || a.isSensitivePath("/home") {
+		t.Fatal("wrong result with the first patterns")
+	}
+
+	// New patterns must not be answered from results cached for the old ones.
+	m, _ = compilePathMatcher(store.SensitivePathPatterns{Prefixes: []string{"/home"}})
+	a.setSensitiveMatcher(m)
+	if a.isSensitivePath("/admin/x") || !a.isSensitivePath("/home") {
+		t.Fatal("stale cached result after the patterns changed")
+	}
+
+	for i := range sensitiveCacheLimit + 10 {
+		a.isSensitivePath("/p/" + strconv.Itoa(i))
+	}
+	n := 0
+	a.sensitiveCache.Range(func(_, _ any) bool { n++; return true })
+	if n > sensitiveCacheLimit {
+		t.Fatalf("cache holds %d paths, limit is %d", n, sensitiveCacheLimit)
+	}
+}
+
+func TestValidateSensitivePatterns(t *testing.T) {
+	many := make([]string, maxSensitivePatterns+1)
+	for i := range many {
+		many[i] = "/p" + strconv.Itoa(i)
+	}
+	tests := []struct {
+		name string
+		p    store.SensitivePathPatterns
+		ok   bool
+	}{
+		{"defaults", store.DefaultSensitivePathPatterns(), true},
+		{"too many", store.SensitivePathPatterns{Contains: many}, false},
+		{"too long", store.SensitivePathPatterns{Regexes: []string{strings.Repeat("a", maxSensitivePatternLength+1)}}, false},
+	}
+	for _, tt := range tests {
+		if got := validateSensitivePatterns(tt.p) == ""; got != tt.ok {
+			t.Errorf("%s: valid = %v, want %v", tt.name, got, tt.ok)
+		}
+	}
+}
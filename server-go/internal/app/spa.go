@@ -0,0 +1,39 @@
+package app
+
+import (
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// spaFallback serves the frontend build in dir for requests no route
+// matched. Existing files are served as-is and any other path gets
+// index.html so the client router can handle it. Non-GET requests and paths
+// under /api or /static still get a 404.
+func (a *App) spaFallback(dir string) http.HandlerFunc {
+	root := http.Dir(dir)
+	files := http.FileServer(root)
+	index := filepath.Join(dir, "index.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+			p == "/api" || strings.HasPrefix(p, "/api/") ||
+			p == "/static" || strings.HasPrefix(p, "/static/") {
+			http.NotFound(w, r)
+			return
+		}
+		if f, err := root.Open(path.Clean("/" + p)); err == nil {
+			info, err := f.Stat()
+			f.Close()
+			if err == nil && !info.IsDir() {
+				files.ServeHTTP(w, r)
+				return
+			}
+		}
+		// index.html must not be cached, or clients keep stale asset names
+		// after a deploy.
+		w.Header().Set("Cache-Control", "no-cache")
+		http.ServeFile(w, r, index)
+	}
+}
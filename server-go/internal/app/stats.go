@@ -0,0 +1,162 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+// overviewCacheTTL bounds how often the homepage stats hit the database.
+const overviewCacheTTL = 30 * time.Second
+
+func (a *App) handleStatsOverview(w http.ResponseWriter, r *http.Request) {
+	a.overviewMu.Lock()
+	defer a.overviewMu.Unlock()
+	if a.overviewAt.IsZero() || time.Since(a.overviewAt) >= overviewCacheTTL {
+		o, err := a.store.GetSiteOverview(r.Context())
+		if err != nil {
+			a.writeInternalError(w, r, err)
+			return
+		}
+		a.overviewCache = o
+		a.overviewAt = time.Now()
+	}
+	writeJSON(w, http.StatusOK, struct {
+		store.SiteOverview
+		GeneratedAt time.Time `json:"generatedAt"`
+	}{a.overviewCache, a.overviewAt})
+}
+
+const (
+	defaultRecentAcceptedLimit = 20
+	maxRecentAcceptedLimit     = 50
+)
+
+func (a *App) handleStatsRecentAccepted(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRecentAcceptedLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = min(n, maxRecentAcceptedLimit)
+		}
+	}
+	items, err := a.store.ListRecentAccepted(r.Context(), limit)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// handleAdminLanguageStats aggregates submissions per language. from and to
+// are optional RFC3339 bounds.
+func (a *App) handleAdminLanguageStats(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var from, to *time.Time
+	for _, b := range []struct {
+		key string
+		dst **time.Time
+	}{{"from", &from}, {"to", &to}} {
+		v := strings.TrimSpace(q.Get(b.key))
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid from or to format, must be RFC3339"})
+			return
+		}
+		*b.dst = &t
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "to must be after from"})
+		return
+	}
+	stats, err := a.store.GetLanguageStats(r.Context(), from, to)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+type achievement struct {
+	ID         string     `json:"id"`
+	Achieved   bool       `json:"achieved"`
+	AchievedAt *time.Time `json:"achievedAt,omitempty"`
+}
+
+// hardDifficulties are the levels that count for the hard-solve achievement.
+var hardDifficulties = map[string]bool{"LEVEL5": true, "LEVEL6": true, "LEVEL7": true}
+
+const streakAchievementDays = 7
+
+// computeAchievements derives streaks and achievements from first-AC times,
+// which must be sorted oldest first. Days use the server's local time zone;
+// the current streak counts only if it reaches today or yesterday.
+func computeAchievements(firsts []store.FirstAccepted, now time.Time) (current, longest int, list []achievement) {
+	firstSolve := achievement{ID: "first_solve"}
+	hardSolve := achievement{ID: "hard_solve"}
+	streak := achievement{ID: "streak_7"}
+	solved10 := achievement{ID: "solved_10"}
+
+	var prevDay time.Time
+	run := 0
+	for i, fa := range firsts {
+		at := fa.AcceptedAt
+		if i == 0 {
+			firstSolve.Achieved, firstSolve.AchievedAt = true, &at
+		}
+		if i == 9 {
+			solved10.Achieved, solved10.AchievedAt = true, &at
+		}
+		if hardDifficulties[fa.Difficulty] && !hardSolve.Achieved {
+			hardSolve.Achieved, hardSolve.AchievedAt = true, &at
+		}
+		day := truncateDay(at)
+		switch {
+		case run == 0:
+			run = 1
+		case day.Equal(prevDay):
+			continue
+		case day.Equal(prevDay.AddDate(0, 0, 1)):
+			run++
+		default:
+			run = 1
+		}
+		prevDay = day
+		longest = max(longest, run)
+		if run >= streakAchievementDays && !streak.Achieved {
+			streak.Achieved, streak.AchievedAt = true, &at
+		}
+	}
+	today := truncateDay(now)
+	if run > 0 && (prevDay.Equal(today) || prevDay.Equal(today.AddDate(0, 0, -1))) {
+		current = run
+	}
+	return current, longest, []achievement{firstSolve, solved10, hardSolve, streak}
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func (a *App) handleUserAchievements(w http.ResponseWriter, r *http.Request) {
+	u, _ := a.currentUser(r)
+	firsts, err := a.store.ListFirstAccepted(r.Context(), u.ID)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	current, longest, list := computeAchievements(firsts, time.Now())
+	writeJSON(w, http.StatusOK, map[string]any{
+		"solvedCount":   len(firsts),
+		"currentStreak": current,
+		"longestStreak": longest,
+		"achievements":  list,
+		"firstAccepted": firsts,
+	})
+}
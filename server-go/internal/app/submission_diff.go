@@ -0,0 +1,68 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"onlinejudge-server-go/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// handleSubmissionDiff returns a unified diff from the code of submission
+// `against` to the code of submission {id}. Both must belong to the caller
+// unless the caller is an admin.
+func (a *App) handleSubmissionDiff(w http.ResponseWriter, r *http.Request) {
+	subID, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid submission id"})
+		return
+	}
+	otherID, ok := parseIntParam(r.URL.Query().Get("against"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid against submission id"})
+		return
+	}
+	u, _ := a.currentUser(r)
+
+	var subs [2]store.SubmissionCode
+	for i, id := range []int{otherID, subID} {
+		sub, err := a.store.GetSubmissionCode(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]any{"error": "Submission not found"})
+				return
+			}
+			a.writeInternalError(w, r, err)
+			return
+		}
+		if !canDiffSubmission(u, sub) {
+			writeJSON(w, http.StatusForbidden, map[string]any{"error": "Access denied"})
+			return
+		}
+		subs[i] = sub
+	}
+	old, cur := subs[0], subs[1]
+
+	diff := unifiedDiff(
+		fmt.Sprintf("submission/%d.%s", old.ID, old.Language),
+		fmt.Sprintf("submission/%d.%s", cur.ID, cur.Language),
+		old.Code, cur.Code,
+	)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":              cur.ID,
+		"against":         old.ID,
+		"language":        cur.Language,
+		"againstLanguage": old.Language,
+		"sameProblem":     old.ProblemID == cur.ProblemID,
+		"identical":       diff == "",
+		"diff":            diff,
+	})
+}
+
+// canDiffSubmission reports whether u may see sub's code in a diff: admins
+// may see any submission, everyone else only their own.
+func canDiffSubmission(u userClaims, sub store.SubmissionCode) bool {
+	return u.Role == "ADMIN" || (sub.UserID != nil && *sub.UserID == u.ID)
+}
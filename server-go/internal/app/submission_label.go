@@ -0,0 +1,67 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"onlinejudge-server-go/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxSubmissionLabelLen = 50
+
+// handleSubmissionLabel lets the owner of a submission attach a short label
+// such as "TLE fix" to it. A null or blank label removes it. Admins cannot
+// label other users' submissions.
+func (a *App) handleSubmissionLabel(w http.ResponseWriter, r *http.Request) {
+	subID, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid submission id"})
+		return
+	}
+	var body struct {
+		Label *string `json:"label"`
+	}
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	var label *string
+	if body.Label != nil {
+		if v := strings.TrimSpace(*body.Label); v != "" {
+			if utf8.RuneCountInString(v) > maxSubmissionLabelLen {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Label must be at most 50 characters"})
+				return
+			}
+			label = &v
+		}
+	}
+	u, _ := a.currentUser(r)
+
+	sub, err := a.store.GetSubmissionCode(r.Context(), subID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Submission not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	if sub.UserID == nil || *sub.UserID != u.ID {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Access denied"})
+		return
+	}
+
+	if err := a.store.SetSubmissionLabel(r.Context(), subID, u.ID, label); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Submission not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": subID, "label": label})
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"onlinejudge-server-go/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// handleSubmissionResubmit submits the code and language of one of the
+// caller's earlier submissions again as a new submission to the same
+// problem. It goes through the same checks and rate limit as a normal
+// submission; unlike rejudge it does not touch the original.
+func (a *App) handleSubmissionResubmit(w http.ResponseWriter, r *http.Request) {
+	subID, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid submission id"})
+		return
+	}
+	u, _ := a.currentUser(r)
+
+	prev, err := a.store.GetSubmissionCode(r.Context(), subID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Submission not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	if prev.UserID == nil || *prev.UserID != u.ID {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "Access denied"})
+		return
+	}
+	if prev.Code == "" {
+		writeJSON(w, http.StatusGone, map[string]any{"error": "Submission code is no longer available"})
+		return
+	}
+
+	if !a.checkCanSubmit(w, r, u) {
+		return
+	}
+
+	// The body is optional: a contest to submit into and the captcha token.
+	var body struct {
+		ContestID *int   `json:"contestId"`
+		CFToken   string `json:"cfToken"`
+	}
+	if err := readJSONStrict(r, &body); err != nil && !errors.Is(err, io.EOF) {
+		writeBodyError(w, err)
+		return
+	}
+	if !a.checkSubmissionCaptcha(w, r, body.CFToken) {
+		return
+	}
+	var contestID *int
+	if body.ContestID != nil && *body.ContestID > 0 {
+		contestID = body.ContestID
+	}
+
+	a.createSubmission(w, r, u, prev.ProblemID, prev.Code, prev.Language, contestID)
+}
@@ -1,84 +1,100 @@
 package app
 
 import (
-	"encoding/json"
 	"net/http"
-	"net/url"
-	"os"
 	"strings"
 )
 
 func (a *App) handleTurnstileGet(w http.ResponseWriter, r *http.Request) {
-	enabled, _ := a.store.GetTurnstileEnabled(r.Context())
-	if !enabled {
-		if v := strings.TrimSpace(os.Getenv("TURNSTILE_ENABLED")); v == "1" || strings.EqualFold(v, "true") {
-			enabled = true
-		}
-	}
-	siteKey, _ := a.store.GetTurnstileSiteKey(r.Context())
-	if strings.TrimSpace(siteKey) == "" {
-		fromEnv := strings.TrimSpace(os.Getenv("CLOUDFLARE_TURNSTILE_SITE_KEY"))
-		siteKey = fromEnv
-	}
-	secret := strings.TrimSpace(os.Getenv("CLOUDFLARE_TURNSTILE_SECRET_KEY"))
+	p := a.captchaProvider(r.Context())
 	writeJSON(w, http.StatusOK, map[string]any{
-		"enabled":          enabled,
-		"siteKey":          siteKey,
-		"secretConfigured": secret != "",
+		"enabled":          a.captchaEnabled(r.Context()),
+		"provider":         p.name,
+		"siteKey":          a.captchaSiteKey(r.Context(), p),
+		"secretConfigured": a.captchaSecret(r.Context(), p) != "",
+		"passwordChange":   a.captchaActionEnabled(r.Context(), captchaActionPasswordChange),
+		"submission":       a.captchaActionEnabled(r.Context(), captchaActionSubmission),
 	})
 }
 
 func (a *App) handleTurnstilePut(w http.ResponseWriter, r *http.Request) {
 	var body struct {
-		Enabled bool   `json:"enabled"`
-		SiteKey string `json:"siteKey"`
-		Secret  string `json:"secretKey"`
+		Enabled     bool   `json:"enabled"`
+		Provider    string `json:"provider"`
+		SiteKey     string `json:"siteKey"`
+		Secret      string `json:"secretKey"`
+		ClearSecret bool   `json:"clearSecret"`
+		// Omitted action toggles keep their stored value.
+		PasswordChange *bool `json:"passwordChange"`
+		Submission     *bool `json:"submission"`
 	}
-	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
 		return
 	}
+	provider := strings.ToLower(strings.TrimSpace(body.Provider))
+	if provider != "" {
+		if _, ok := captchaProviders[provider]; !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Unknown captcha provider"})
+			return
+		}
+		if _, err := a.store.UpsertCaptchaProvider(r.Context(), provider); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Update failed"})
+			return
+		}
+	}
 	if _, err := a.store.UpsertTurnstileEnabled(r.Context(), body.Enabled); err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Update failed"})
 		return
 	}
-	if _, err := a.store.UpsertTurnstileSiteKey(r.Context(), strings.TrimSpace(body.SiteKey)); err != nil {
+	// Keys belong to the provider in effect after this update.
+	p := a.captchaProvider(r.Context())
+	if _, err := a.store.UpsertCaptchaSiteKey(r.Context(), p.name, strings.TrimSpace(body.SiteKey)); err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Update failed"})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"enabled": body.Enabled, "siteKey": strings.TrimSpace(body.SiteKey)})
+	// An empty secret keeps the stored one, since GET never returns it.
+	if secret := strings.TrimSpace(body.Secret); secret != "" {
+		if err := a.store.UpsertCaptchaSecretKey(r.Context(), p.name, secret); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Update failed"})
+			return
+		}
+	} else if body.ClearSecret {
+		if err := a.store.DeleteCaptchaSecretKey(r.Context(), p.name); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Update failed"})
+			return
+		}
+	}
+	for action, v := range map[string]*bool{
+		captchaActionPasswordChange: body.PasswordChange,
+		captchaActionSubmission:     body.Submission,
+	} {
+		if v == nil {
+			continue
+		}
+		if _, err := a.store.UpsertCaptchaActionEnabled(r.Context(), action, *v); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Update failed"})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled":          body.Enabled,
+		"provider":         p.name,
+		"siteKey":          a.captchaSiteKey(r.Context(), p),
+		"secretConfigured": a.captchaSecret(r.Context(), p) != "",
+		"passwordChange":   a.captchaActionEnabled(r.Context(), captchaActionPasswordChange),
+		"submission":       a.captchaActionEnabled(r.Context(), captchaActionSubmission),
+	})
 }
 
 func (a *App) handleTurnstileVerify(w http.ResponseWriter, r *http.Request) {
 	var body struct {
 		Response string `json:"response"`
 	}
-	if err := readJSON(r, &body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid JSON"})
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
 		return
 	}
-	ok, errs := a.verifyTurnstile(r, body.Response)
+	ok, errs := a.verifyCaptcha(r, body.Response)
 	writeJSON(w, http.StatusOK, map[string]any{"success": ok, "errors": errs})
 }
-
-func (a *App) verifyTurnstile(r *http.Request, token string) (bool, []string) {
-	secret := strings.TrimSpace(os.Getenv("CLOUDFLARE_TURNSTILE_SECRET_KEY"))
-	if secret == "" || strings.TrimSpace(token) == "" {
-		return false, []string{"missing-input"}
-	}
-	resp, err := http.PostForm("https://challenges.cloudflare.com/turnstile/v0/siteverify", url.Values{
-		"secret":   {secret},
-		"response": {token},
-		"remoteip": {getClientIP(r)},
-	})
-	if err != nil {
-		return false, []string{"verify-request-failed"}
-	}
-	defer resp.Body.Close()
-	var out struct {
-		Success    bool     `json:"success"`
-		ErrorCodes []string `json:"error-codes"`
-	}
-	_ = json.NewDecoder(resp.Body).Decode(&out)
-	return out.Success, out.ErrorCodes
-}
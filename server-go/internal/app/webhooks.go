@@ -0,0 +1,347 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"onlinejudge-server-go/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	webhookEventSubmissionJudged = "submission.judged"
+	webhookMaxAttempts           = 5
+	webhookInitialBackoff        = 2 * time.Second
+)
+
+// errWebhookPrivateAddress is returned when a webhook host resolves to an
+// address that is not publicly routable.
+var errWebhookPrivateAddress = errors.New("webhook address is not public")
+
+// webhookSender delivers webhook requests, retrying with exponential backoff
+// on transport errors, 429 and 5xx responses.
+type webhookSender struct {
+	client         *http.Client
+	maxAttempts    int
+	initialBackoff time.Duration
+}
+
+// newWebhookSender returns the sender used in production. Its client only
+// connects to public addresses, checked after DNS resolution so a hostname
+// cannot point a webhook at the server's own network, and ignores proxy
+// environment variables for the same reason.
+func newWebhookSender() *webhookSender {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !publicIP(ip) {
+				return errWebhookPrivateAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &webhookSender{
+		client:         &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		maxAttempts:    webhookMaxAttempts,
+		initialBackoff: webhookInitialBackoff,
+	}
+}
+
+// cgnatPrefix is the shared address space of carrier-grade NAT (RFC 6598).
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicIP reports whether ip is publicly routable, i.e. not loopback,
+// private, link-local, multicast or unspecified.
+func publicIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() &&
+		!ip.IsUnspecified() && !cgnatPrefix.Contains(ip)
+}
+
+type submissionJudgedPayload struct {
+	Event        string    `json:"event"`
+	SubmissionID int       `json:"submissionId"`
+	UserID       *int      `json:"userId"`
+	Username     string    `json:"username"`
+	ProblemID    int       `json:"problemId"`
+	ContestID    *int      `json:"contestId"`
+	Language     string    `json:"language"`
+	Status       string    `json:"status"`
+	Score        int       `json:"score"`
+	JudgedAt     time.Time `json:"judgedAt"`
+}
+
+// signWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>". Receivers
+// recompute it from the X-OJ-Timestamp header and the raw request body.
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// notifySubmissionJudged fans the result out to every enabled webhook. It
+// returns immediately; delivery and retries run in the background so the
+// judge worker is never held up by a slow receiver.
+func (a *App) notifySubmissionJudged(submissionID, problemID int, language, status string, score int) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		hooks, err := a.store.ListEnabledWebhooks(ctx)
+		if err != nil {
+			log.Printf("[webhook] list webhooks failed: %v", err)
+			return
+		}
+		if len(hooks) == 0 {
+			return
+		}
+		owner, err := a.store.GetSubmissionOwner(ctx, submissionID)
+		if err != nil {
+			log.Printf("[webhook] load submission %d failed: %v", submissionID, err)
+			return
+		}
+		body, err := json.Marshal(submissionJudgedPayload{
+			Event:        webhookEventSubmissionJudged,
+			SubmissionID: submissionID,
+			UserID:       owner.UserID,
+			Username:     owner.Username,
+			ProblemID:    problemID,
+			ContestID:    owner.ContestID,
+			Language:     language,
+			Status:       status,
+			Score:        score,
+			JudgedAt:     time.Now().UTC(),
+		})
+		if err != nil {
+			return
+		}
+		for _, h := range hooks {
+			go a.webhooks.deliver(h, webhookEventSubmissionJudged, body)
+		}
+	}()
+}
+
+// broadcastWebhook sends payload to every enabled webhook in the background.
+func (a *App) broadcastWebhook(event string, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		hooks, err := a.store.ListEnabledWebhooks(ctx)
+		if err != nil {
+			log.Printf("[webhook] list webhooks failed: %v", err)
+			return
+		}
+		for _, h := range hooks {
+			go a.webhooks.deliver(h, event, body)
+		}
+	}()
+}
+
+// deliver POSTs body to the hook, retrying as described on webhookSender.
+func (s *webhookSender) deliver(h store.Webhook, event string, body []byte) {
+	backoff := s.initialBackoff
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		retry, err := s.post(h, event, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == s.maxAttempts {
+			log.Printf("[webhook] delivery to #%d %s failed after %d attempt(s): %v", h.ID, h.URL, attempt, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (s *webhookSender) post(h store.Webhook, event string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "onlinejudge-webhook/1")
+	req.Header.Set("X-OJ-Event", event)
+	req.Header.Set("X-OJ-Timestamp", ts)
+	req.Header.Set("X-OJ-Signature", "sha256="+signWebhook(h.Secret, ts, body))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, errors.New("unexpected status " + resp.Status)
+}
+
+// validWebhookURL accepts http(s) URLs whose host is not obviously internal:
+// localhost and non-public IP literals are refused. Hostnames that resolve
+// to internal addresses are caught when delivering.
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return publicIP(ip)
+	}
+	return true
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (a *App) handleWebhookList(w http.ResponseWriter, r *http.Request) {
+	items, err := a.store.ListWebhooks(r.Context())
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// handleWebhookCreate returns the signing secret once; it is never listed again.
+func (a *App) handleWebhookCreate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		URL     string `json:"url"`
+		Secret  string `json:"secret"`
+		Enabled *bool  `json:"enabled"`
+	}
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	hookURL := strings.TrimSpace(body.URL)
+	if !validWebhookURL(hookURL) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid webhook url"})
+		return
+	}
+	secret := strings.TrimSpace(body.Secret)
+	if secret == "" {
+		s, err := newWebhookSecret()
+		if err != nil {
+			a.writeInternalError(w, r, err)
+			return
+		}
+		secret = s
+	}
+	enabled := true
+	if body.Enabled != nil {
+		enabled = *body.Enabled
+	}
+	h, err := a.store.CreateWebhook(r.Context(), hookURL, secret, enabled)
+	if err != nil {
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"id":        h.ID,
+		"url":       h.URL,
+		"enabled":   h.Enabled,
+		"secret":    h.Secret,
+		"createdAt": h.CreatedAt,
+		"updatedAt": h.UpdatedAt,
+	})
+}
+
+func (a *App) handleWebhookUpdate(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid id"})
+		return
+	}
+	var body struct {
+		URL     *string `json:"url"`
+		Secret  *string `json:"secret"`
+		Enabled *bool   `json:"enabled"`
+	}
+	if err := readJSONStrict(r, &body); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if body.URL != nil {
+		v := strings.TrimSpace(*body.URL)
+		if !validWebhookURL(v) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid webhook url"})
+			return
+		}
+		body.URL = &v
+	}
+	if body.Secret != nil {
+		v := strings.TrimSpace(*body.Secret)
+		if v == "" {
+			body.Secret = nil
+		} else {
+			body.Secret = &v
+		}
+	}
+	h, err := a.store.UpdateWebhook(r.Context(), id, body.URL, body.Secret, body.Enabled)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Webhook not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h)
+}
+
+func (a *App) handleWebhookDelete(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIntParam(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid id"})
+		return
+	}
+	if err := a.store.DeleteWebhook(r.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "Webhook not found"})
+			return
+		}
+		a.writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"success": true})
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"crypto/hmac"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"onlinejudge-server-go/internal/store"
+)
+
+func TestDeliverWebhookSignsAndRetries(t *testing.T) {
+	const secret = "s3cret"
+	body := []byte(`{"event":"submission.judged","submissionId":1}`)
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&attempts, 1)
+		got, _ := io.ReadAll(r.Body)
+		ts := r.Header.Get("X-OJ-Timestamp")
+		want := "sha256=" + signWebhook(secret, ts, got)
+		if ts == "" || !hmac.Equal([]byte(r.Header.Get("X-OJ-Signature")), []byte(want)) {
+			t.Errorf("attempt %d: bad signature %q for timestamp %q", n, r.Header.Get("X-OJ-Signature"), ts)
+		}
+		if string(got) != string(body) {
+			t.Errorf("attempt %d: body %q, want %q", n, got, body)
+		}
+		if r.Header.Get("X-OJ-Event") != webhookEventSubmissionJudged {
+			t.Errorf("attempt %d: event %q", n, r.Header.Get("X-OJ-Event"))
+		}
+		if n == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := &webhookSender{client: srv.Client(), maxAttempts: webhookMaxAttempts, initialBackoff: 0}
+	s.deliver(store.Webhook{ID: 1, URL: srv.URL, Secret: secret}, webhookEventSubmissionJudged, body)
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Fatalf("got %d attempts, want 2", n)
+	}
+}
+
+func TestDeliverWebhookStopsOnClientError(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	s := &webhookSender{client: srv.Client(), maxAttempts: webhookMaxAttempts, initialBackoff: 0}
+	s.deliver(store.Webhook{URL: srv.URL, Secret: "x"}, webhookEventSubmissionJudged, []byte(`{}`))
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Fatalf("got %d attempts, want 1", n)
+	}
+}
+
+func TestWebhookSenderRefusesPrivateAddresses(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+	}))
+	defer srv.Close()
+
+	s := newWebhookSender()
+	s.initialBackoff = 0
+	retry, err := s.post(store.Webhook{URL: srv.URL, Secret: "x"}, webhookEventSubmissionJudged, []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), errWebhookPrivateAddress.Error()) {
+		t.Fatalf("post to %s: retry=%v err=%v, want %v", srv.URL, retry, err, errWebhookPrivateAddress)
+	}
+	if atomic.LoadInt32(&attempts) != 0 {
+		t.Fatal("the loopback receiver was reached")
+	}
+}
+
+func TestValidWebhookURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://hooks.example.com/oj", true},
+		{"http://203.0.113.7:8080/hook", true},
+		{"https://[2001:db8::1]/hook", true},
+		{"ftp://example.com/hook", false},
+		{"https:///hook", false},
+		{"not a url", false},
+		{"http://localhost:8080/hook", false},
+		{"http://api.localhost/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://172.16.1.1/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://[fe80::1%25eth0]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://100.64.0.1/hook", false},
+	}
+	for _, tt := range tests {
+		if got := validWebhookURL(tt.url); got != tt.ok {
+			t.Errorf("validWebhookURL(%q) = %v, want %v", tt.url, got, tt.ok)
+		}
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"8.8.8.8":         true,
+		"2001:4860::8888": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"fd00::1":         false,
+		"::ffff:10.0.0.1": false,
+		"224.0.0.1":       false,
+		"::":              false,
+		"100.127.255.255": false,
+		"100.128.0.1":     true,
+	} {
+		if got := publicIP(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("publicIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package judger
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// checkerTimeout 校验程序处理单个用例的超时
+const checkerTimeout = 10 * time.Second
+
+// Checker 自定义校验程序（Special Judge）
+// 以 `checker input.txt output.txt answer.txt` 的方式运行，退出码为 0 表示答案正确
+// 校验程序在独立的容器中运行，用户程序无法篡改它
+type Checker struct {
+	Language string // 校验程序语言：cpp 或 python
+	Code     string // 校验程序源代码
+}
+
+// outputChecker 判断某个用例的程序输出是否正确
+type outputChecker func(tc TestCase, stdout string) (bool, error)
+
+// checkerFileName 根据语言获取校验程序源文件名
+func checkerFileName(language string) string {
+	if language == "cpp" {
+		return "checker.cpp"
+	}
+	return "checker.py"
+}
+
+// checkerRunCommand 根据语言获取校验程序运行命令
+func checkerRunCommand(language string) string {
+	if language == "cpp" {
+		return "./checker"
+	}
+	return "python3 checker.py"
+}
+
+// prepareChecker 创建校验程序容器并写入、编译校验程序
+// 返回容器 ID（创建失败时为空）；校验程序编译失败时返回 System Error 结果
+func (r *DockerRunner) prepareChecker(ctx context.Context, opts Options, caseCount int) (string, *JudgeResult, error) {
+	checkerID, err := r.createAndStartContainer(ctx, Options{}, ContainerLifetime(caseCount, opts))
+	if err != nil {
+		return "", nil, err
+	}
+
+	lang := opts.Checker.Language
+	if err := r.writeFileToContainer(ctx, checkerID, containerWorkDir, checkerFileName(lang), opts.Checker.Code); err != nil {
+		return checkerID, nil, err
+	}
+	if lang != "cpp" {
+		return checkerID, nil, nil
+	}
+
+	compileTimeout := opts.CompileTimeout
+	if compileTimeout <= 0 {
+		compileTimeout = defaultCompileTimeout
+	}
+	compileRes, err := r.execCommand(ctx, checkerID, containerWorkDir, []string{"g++", "-std=c++23", "-O2", "checker.cpp", "-o", "checker"}, int(compileTimeout.Milliseconds()))
+	if err != nil {
+		return checkerID, nil, err
+	}
+	if compileRes.TimedOut {
+		return checkerID, &JudgeResult{Status: "System Error", Output: "校验程序编译超时"}, nil
+	}
+	if compileRes.ExitCode != 0 {
+		return checkerID, &JudgeResult{Status: "System Error", Output: "校验程序编译失败: " + compileRes.Stderr + compileRes.Stdout}, nil
+	}
+	return checkerID, nil, nil
+}
+
+// runChecker 在校验程序容器中判定一个用例的输出
+func (r *DockerRunner) runChecker(ctx context.Context, checkerID string, language string, tc TestCase, stdout string) (bool, error) {
+	if err := r.writeFilesToContainer(ctx, checkerID, containerWorkDir,
+		containerFile{name: "input.txt", content: tc.Input},
+		containerFile{name: "output.txt", content: stdout},
+		containerFile{name: "answer.txt", content: tc.ExpectedOutput},
+	); err != nil {
+		return false, err
+	}
+
+	cmd := checkerRunCommand(language) + " input.txt output.txt answer.txt"
+	res, err := r.execCommand(ctx, checkerID, containerWorkDir, []string{"/bin/bash", "-c", cmd}, int(checkerTimeout.Milliseconds()))
+	if err != nil {
+		return false, err
+	}
+	if res.TimedOut {
+		return false, errors.New("校验程序运行超时")
+	}
+	return res.ExitCode == 0, nil
+}
@@ -3,36 +3,131 @@
 package judger
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
-	"encoding/base64"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"log"
+	"math"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
+// ErrDockerUnavailable 表示无法连接 Docker 守护进程
+// 此时评测结果不可信，调用方应保留提交并稍后重试
+var ErrDockerUnavailable = errors.New("judge backend unavailable")
+
+// ErrJudgeFailed 表示评测因基础设施故障（创建容器、写入文件、执行编译等）中断
+// 与代码本身无关，返回的 System Error 结果不应作为最终判定
+var ErrJudgeFailed = errors.New("judge failed")
+
+// judgeFailure 将基础设施错误包装为 ErrJudgeFailed，并给出对应的 System Error 结果
+func judgeFailure(err error) (JudgeResult, error) {
+	return JudgeResult{Status: "System Error", Output: err.Error()}, fmt.Errorf("%w: %v", ErrJudgeFailed, err)
+}
+
+// timeOutputFile /usr/bin/time 统计结果的输出文件
+const timeOutputFile = "time.txt"
+
+// defaultCompileTimeout 默认编译超时
+const defaultCompileTimeout = 20 * time.Second
+
+// writeCodeTimeout 写入代码文件的超时
+const writeCodeTimeout = 10 * time.Second
+
+// containerWorkDir 评测镜像的工作目录，与 Dockerfile-runner 中的 WORKDIR 一致
+// 每次评测在其下创建独立的子目录，校验程序容器直接使用该目录
+const containerWorkDir = "/app"
+
+// minContainerLifetime 评测容器的最短存活时间
+const minContainerLifetime = 5 * time.Minute
+
+// containerOverhead 容器存活时间中为启动、写入代码等预留的固定开销
+const containerOverhead = 30 * time.Second
+
+// perCaseOverhead 每个测试用例写入输入和读取统计的预留开销
+const perCaseOverhead = 2 * time.Second
+
+// containerLabel 评测容器的标签，用于识别和清理遗留容器
+const containerLabel = "onlinejudge"
+
+// containerExpiresLabel 记录容器预计退出时间（Unix 秒）的标签
+const containerExpiresLabel = "onlinejudge.expires"
+
+// reaperInterval 定期清理遗留容器的间隔
+const reaperInterval = 10 * time.Minute
+
+// killTimeout 终止超时进程的最长等待时间
+const killTimeout = 5 * time.Second
+
+// wallTimeLimitFactor 墙钟时间上限相对时间限制的倍数
+// 超时判定以 CPU 时间为准，墙钟上限只用于终止 sleep 或阻塞等待的程序
+const wallTimeLimitFactor = 3
+
+// dockerUnavailableMessage 返回给用户的通用提示，避免泄露守护进程地址等内部信息
+const dockerUnavailableMessage = "评测服务暂时不可用"
+
+// dockerClient 评测用到的 Docker API，由 *client.Client 实现，测试中可替换为假实现
+type dockerClient interface {
+	Ping(ctx context.Context) (types.Ping, error)
+	ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error)
+	ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error)
+	ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error
+	ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error
+	ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error
+	ContainerUpdate(ctx context.Context, containerID string, updateConfig container.UpdateConfig) (container.ContainerUpdateOKBody, error)
+	CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, options container.CopyToContainerOptions) error
+	ContainerExecCreate(ctx context.Context, container string, options container.ExecOptions) (types.IDResponse, error)
+	ContainerExecAttach(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error)
+	ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error)
+}
+
 // DockerRunner Docker 评测运行器
 // 负责管理 Docker 容器来执行代码评测
 type DockerRunner struct {
 	imageName string         // Docker 镜像名称
-	cli       *client.Client // Docker 客户端
+	cli       dockerClient   // Docker 客户端
+	pool      *containerPool // 预启动的容器池，nil 表示每次评测新建容器
 }
 
 // Options 评测选项配置
 type Options struct {
-	TimeLimitMs    int    // 时间限制（毫秒）
-	MemoryLimitMB  int    // 内存限制（MB）
-	CompileOptions string // 编译选项
+	TimeLimitMs    int           // 时间限制（毫秒）
+	MemoryLimitMB  int           // 内存限制（MB）
+	CompileOptions string        // 编译选项
+	CompileTimeout time.Duration // 编译超时，0 表示使用默认值
+	CompareMode    string        // 输出比较方式，见 Compare* 常量，空表示 CompareTrim
+	Checker        *Checker      // 自定义校验程序，设置后不再比较输出字符串
 }
 
+// 输出比较方式
+const (
+	CompareTrim   = "trim"   // 忽略首尾空白（默认）
+	CompareExact  = "exact"  // 逐字节完全一致
+	CompareLines  = "lines"  // 忽略每行行尾空白及末尾空行
+	CompareTokens = "tokens" // 按空白分隔的记号逐一比较
+)
+
+// CompareModes 支持的全部比较方式
+var CompareModes = []string{CompareTrim, CompareExact, CompareLines, CompareTokens}
+
 // TestCase 测试用例
 type TestCase struct {
 	Input          string // 输入数据
@@ -42,9 +137,15 @@ type TestCase struct {
 // CaseResult 单个测试用例的评测结果
 type CaseResult struct {
 	Status     string `json:"status"`     // 状态：Accepted, Wrong Answer, Time Limit Exceeded, Runtime Error
-	TimeUsed   int    `json:"timeUsed"`   // 使用时间（毫秒）
+	TimeUsed   int    `json:"timeUsed"`   // 使用时间（毫秒），即 CPU 时间
+	CPUTime    int    `json:"cpuTime"`    // CPU 时间（用户态 + 内核态，毫秒）
+	WallTime   int    `json:"wallTime"`   // 墙钟时间（毫秒）
 	MemoryUsed int    `json:"memoryUsed"` // 使用内存（KB）
 	Output     string `json:"output"`     // 实际输出
+	// 以下字段仅在运行时错误时填写，只应展示给管理员
+	Stderr   string `json:"stderr,omitempty"`   // 用户程序的标准错误输出
+	ExitCode int    `json:"exitCode,omitempty"` // 退出码
+	Signal   string `json:"signal,omitempty"`   // 终止程序的信号名，如 SIGSEGV
 }
 
 // JudgeResult 完整的评测结果
@@ -75,8 +176,9 @@ type execAttachReader interface {
 
 // NewDockerRunner 创建新的 Docker 评测运行器
 // imageName: Docker 镜像名称
+// poolSize: 预启动容器池的大小，0 表示不使用容器池
 // 返回: DockerRunner 实例和可能的错误
-func NewDockerRunner(imageName string) (*DockerRunner, error) {
+func NewDockerRunner(imageName string, poolSize int) (*DockerRunner, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, err
@@ -84,9 +186,69 @@ func NewDockerRunner(imageName string) (*DockerRunner, error) {
 	r := &DockerRunner{imageName: imageName, cli: cli}
 	// 确保镜像存在
 	_ = r.ensureImage(context.Background())
+	// 清理上次进程异常退出时遗留的评测容器，并定期回收过期容器
+	if n, err := r.reapContainers(context.Background(), true); err == nil && n > 0 {
+		log.Printf("[judger] removed %d leftover judge containers", n)
+	}
+	go r.startReaper()
+	if poolSize > 0 {
+		r.pool = newContainerPool(r, poolSize)
+	}
 	return r, nil
 }
 
+// startReaper 定期删除已退出或超过存活时间的评测容器
+func (r *DockerRunner) startReaper() {
+	ticker := time.NewTicker(reaperInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if n, err := r.reapContainers(ctx, false); err == nil && n > 0 {
+			log.Printf("[judger] reaped %d stale judge containers", n)
+		}
+		cancel()
+	}
+}
+
+// reapContainers 删除带有评测标签的容器
+// all 为 true 时删除全部（仅在启动时使用，假定 Docker 主机只服务于本实例），
+// 否则只删除已退出或已超过 expires 标签时间的容器
+func (r *DockerRunner) reapContainers(ctx context.Context, all bool) (int, error) {
+	list, err := r.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", containerLabel)),
+	})
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().Unix()
+	removed := 0
+	for _, c := range list {
+		if !all && c.State == "running" {
+			expires, err := strconv.ParseInt(c.Labels[containerExpiresLabel], 10, 64)
+			if err != nil || expires > now {
+				continue
+			}
+		}
+		if err := r.cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Ping 检查 Docker 守护进程是否可用
+// 连接失败时返回 ErrDockerUnavailable
+func (r *DockerRunner) Ping(ctx context.Context) error {
+	if _, err := r.cli.Ping(ctx); err != nil {
+		if client.IsErrConnectionFailed(err) {
+			return ErrDockerUnavailable
+		}
+		return err
+	}
+	return nil
+}
+
 // ensureImage 确保 Docker 镜像存在
 // 如果镜像不存在，则尝试拉取
 func (r *DockerRunner) ensureImage(ctx context.Context) error {
@@ -113,49 +275,112 @@ func (r *DockerRunner) Judge(ctx context.Context, language string, code string,
 		return JudgeResult{Status: "System Error", Output: "缺少语言参数"}, nil
 	}
 
-	// 创建并启动容器
-	containerID, err := r.createAndStartContainer(ctx, opts)
+	// 取得评测容器（来自容器池或新建）
+	judgeCtr, err := r.acquireContainer(ctx, opts, ContainerLifetime(len(testCases), opts))
+	if err != nil {
+		if client.IsErrConnectionFailed(err) {
+			return JudgeResult{Status: "System Error", Output: dockerUnavailableMessage}, ErrDockerUnavailable
+		}
+		return judgeFailure(err)
+	}
+	// 确保容器在函数结束时被归还或清理
+	defer r.releaseContainer(judgeCtr)
+	return r.judgeInContainer(ctx, judgeCtr.id, language, code, testCases, opts)
+}
+
+// judgeInContainer 在已启动的容器中完成一次评测
+// 本次评测的文件都放在独立的工作目录中并在结束时删除，
+// 同一容器中先后或同时进行的评测不会读到彼此的文件
+func (r *DockerRunner) judgeInContainer(ctx context.Context, containerID string, language string, code string, testCases []TestCase, opts Options) (JudgeResult, error) {
+	workDir, err := r.createWorkDir(ctx, containerID)
 	if err != nil {
-		return JudgeResult{Status: "System Error", Output: err.Error()}, nil
+		return judgeFailure(err)
 	}
-	// 确保容器在函数结束时被清理
-	defer r.cleanupContainer(containerID)
+	defer r.removeWorkDir(containerID, workDir)
 
 	// 将代码写入容器
-	if err := r.writeCodeToContainer(ctx, containerID, language, code); err != nil {
-		return JudgeResult{Status: "System Error", Output: err.Error()}, nil
+	if err := r.writeCodeToContainer(ctx, containerID, workDir, language, code); err != nil {
+		return judgeFailure(err)
 	}
 
 	// 如果是 C++，需要先编译
 	if language == "cpp" {
-		if result, err := r.compileCode(ctx, containerID, opts); err != nil || result != nil {
+		if result, err := r.compileCode(ctx, containerID, workDir, opts); err != nil || result != nil {
 			if err != nil {
-				return JudgeResult{Status: "System Error", Output: err.Error()}, nil
+				return judgeFailure(err)
 			}
 			return *result, nil
 		}
 	}
 
+	// 准备输出校验方式
+	check := func(tc TestCase, stdout string) (bool, error) {
+		return outputsMatch(opts.CompareMode, stdout, tc.ExpectedOutput), nil
+	}
+	if opts.Checker != nil {
+		checkerID, result, err := r.prepareChecker(ctx, opts, len(testCases))
+		if checkerID != "" {
+			defer r.cleanupContainer(checkerID)
+		}
+		if err != nil {
+			if client.IsErrConnectionFailed(err) {
+				return JudgeResult{Status: "System Error", Output: dockerUnavailableMessage}, ErrDockerUnavailable
+			}
+			return judgeFailure(err)
+		}
+		if result != nil {
+			return *result, nil
+		}
+		check = func(tc TestCase, stdout string) (bool, error) {
+			return r.runChecker(ctx, checkerID, opts.Checker.Language, tc, stdout)
+		}
+	}
+
 	// 运行所有测试用例
-	results := r.runTestCases(ctx, containerID, language, testCases, opts)
+	results := r.runTestCases(ctx, containerID, workDir, language, testCases, opts, check)
 
 	return JudgeResult{Status: "Judged", Results: results}, nil
 }
 
-// createAndStartContainer 创建并启动评测容器
-func (r *DockerRunner) createAndStartContainer(ctx context.Context, opts Options) (string, error) {
-	// 计算内存限制
-	memoryBytes := int64(128 * 1024 * 1024) // 默认 128MB
+// ContainerLifetime 估算一次评测所需的容器存活时间，调用方可据此设置评测的截止时间
+// 容器在评测结束后会被主动删除，这里的上限只用于进程异常退出时让容器自行结束，
+// 因此按每个用例的墙钟上限加上固定开销计算，并且不低于 minContainerLifetime
+func ContainerLifetime(caseCount int, opts Options) time.Duration {
+	compileTimeout := opts.CompileTimeout
+	if compileTimeout <= 0 {
+		compileTimeout = defaultCompileTimeout
+	}
+	perCase := time.Duration(opts.TimeLimitMs*wallTimeLimitFactor)*time.Millisecond + perCaseOverhead
+	if opts.Checker != nil {
+		perCase += checkerTimeout
+	}
+	lifetime := containerOverhead + writeCodeTimeout + compileTimeout + time.Duration(caseCount)*perCase
+	return max(lifetime, minContainerLifetime)
+}
+
+// memoryLimitBytes 计算容器内存限制，默认 128MB
+func memoryLimitBytes(opts Options) int64 {
 	if opts.MemoryLimitMB > 0 {
-		memoryBytes = int64(opts.MemoryLimitMB) * 1024 * 1024
+		return int64(opts.MemoryLimitMB) * 1024 * 1024
 	}
+	return 128 * 1024 * 1024
+}
+
+// createAndStartContainer 创建并启动评测容器
+// lifetime: 容器内 sleep 的时长，超过后容器自动退出
+func (r *DockerRunner) createAndStartContainer(ctx context.Context, opts Options, lifetime time.Duration) (string, error) {
+	memoryBytes := memoryLimitBytes(opts)
 
 	// 创建容器
 	created, err := r.cli.ContainerCreate(ctx, &container.Config{
 		Image: r.imageName,
-		Cmd:   []string{"/bin/bash", "-c", "sleep 300"},
+		Cmd:   []string{"/bin/bash", "-c", "sleep " + strconv.Itoa(int(math.Ceil(lifetime.Seconds())))},
 		Tty:   false,
 		User:  "runner",
+		Labels: map[string]string{
+			containerLabel:        "judge",
+			containerExpiresLabel: strconv.FormatInt(time.Now().Add(lifetime).Unix(), 10),
+		},
 	}, &container.HostConfig{
 		Resources: container.Resources{
 			Memory: memoryBytes,
@@ -179,21 +404,87 @@ func (r *DockerRunner) cleanupContainer(containerID string) {
 	_ = r.cli.ContainerRemove(context.Background(), containerID, container.RemoveOptions{Force: true})
 }
 
-// writeCodeToContainer 将代码写入容器
-func (r *DockerRunner) writeCodeToContainer(ctx context.Context, containerID string, language string, code string) error {
+// createWorkDir 在容器工作目录下创建本次评测专用的随机命名目录，返回其绝对路径
+// 目录由评测用户创建，编译产物可以写入；评测结束后由 removeWorkDir 删除
+func (r *DockerRunner) createWorkDir(ctx context.Context, containerID string) (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	dir := containerWorkDir + "/judge-" + hex.EncodeToString(b[:])
+	res, err := r.execCommand(ctx, containerID, containerWorkDir, []string{"mkdir", dir}, int(writeCodeTimeout.Milliseconds()))
+	if err != nil {
+		return "", err
+	}
+	if res.TimedOut || res.ExitCode != 0 {
+		return "", errors.New("创建评测目录失败: " + strings.TrimSpace(res.Stderr))
+	}
+	return dir, nil
+}
+
+// removeWorkDir 删除 createWorkDir 创建的评测目录，只影响本次评测
+// 失败时只记录日志：池中容器在放回前还会被整体重置，其余容器随后即被删除
+func (r *DockerRunner) removeWorkDir(containerID string, dir string) {
+	ctx, cancel := context.WithTimeout(context.Background(), writeCodeTimeout)
+	defer cancel()
+	res, err := r.execCommand(ctx, containerID, containerWorkDir, []string{"rm", "-rf", "--", dir}, int(writeCodeTimeout.Milliseconds()))
+	if err == nil && (res.TimedOut || res.ExitCode != 0) {
+		err = errors.New(strings.TrimSpace(res.Stderr))
+	}
+	if err != nil {
+		log.Printf("[judger] 删除评测目录 %s 失败: %v", dir, err)
+	}
+}
+
+// writeCodeToContainer 将代码写入容器中的评测目录
+func (r *DockerRunner) writeCodeToContainer(ctx context.Context, containerID string, dir string, language string, code string) error {
 	// 根据语言确定文件名
-	fileName := r.getSourceFileName(language)
+	return r.writeFileToContainer(ctx, containerID, dir, r.getSourceFileName(language), code)
+}
 
-	// 使用 base64 编码避免特殊字符问题
-	codeB64 := base64.StdEncoding.EncodeToString([]byte(code))
-	writeCmd := `echo "` + codeB64 + `" | base64 -d > ` + fileName
+// containerFile 要写入容器工作目录的文件
+type containerFile struct {
+	name    string
+	content string
+}
 
-	writeRes, err := r.execCommand(ctx, containerID, []string{"/bin/bash", "-c", writeCmd}, 0)
-	if err != nil {
+// writeFileToContainer 将内容写入容器中 dir 目录下的文件
+func (r *DockerRunner) writeFileToContainer(ctx context.Context, containerID string, dir string, fileName string, content string) error {
+	return r.writeFilesToContainer(ctx, containerID, dir, containerFile{name: fileName, content: content})
+}
+
+// writeFilesToContainer 将多个文件打包为一个 tar 归档，通过 Docker 归档接口一次写入容器
+// 不经过 shell 解析，也不必为每次写入在容器内启动进程
+// 文件属主为 root，评测用户只能读取，用户程序因此无法改写后续用例的输入
+// dir 必须已经存在
+func (r *DockerRunner) writeFilesToContainer(ctx context.Context, containerID string, dir string, files ...containerFile) error {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	now := time.Now()
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    f.name,
+			Mode:    0o644,
+			Size:    int64(len(f.content)),
+			ModTime: now,
+		}); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(tw, f.content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
 		return err
 	}
-	if writeRes.ExitCode != 0 {
-		return errors.New("写入代码到容器失败: " + writeRes.Stderr)
+
+	copyCtx, cancel := context.WithTimeout(ctx, writeCodeTimeout)
+	defer cancel()
+	if err := r.cli.CopyToContainer(copyCtx, containerID, dir, &buf, container.CopyToContainerOptions{}); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errors.New("写入代码到容器超时")
+		}
+		return errors.New("写入代码到容器失败: " + err.Error())
 	}
 	return nil
 }
@@ -214,23 +505,52 @@ func (r *DockerRunner) getRunCommand(language string) string {
 	return "python3 main.py"
 }
 
+// maxCompileArgs 编译选项最多拆分出的参数个数
+const maxCompileArgs = 32
+
+// compileCommand 构建 C++ 编译命令的参数列表
+// 编译选项按空白拆分后作为独立参数传给 g++，不经过 shell，
+// 因此选项中的 ;、$()、反引号等字符不会被解释执行
+func compileCommand(compileOptions string) ([]string, error) {
+	args := strings.Fields(compileOptions)
+	if len(args) == 0 {
+		args = []string{"-O2"}
+	}
+	if len(args) > maxCompileArgs {
+		return nil, errors.New("编译选项过多，最多 " + strconv.Itoa(maxCompileArgs) + " 个")
+	}
+	cmd := make([]string, 0, len(args)+4)
+	cmd = append(cmd, "g++", "-std=c++23")
+	cmd = append(cmd, args...)
+	return append(cmd, "main.cpp", "-o", "main"), nil
+}
+
 // compileCode 编译 C++ 代码
 // 返回: 如果编译失败返回 JudgeResult，否则返回 nil
-func (r *DockerRunner) compileCode(ctx context.Context, containerID string, opts Options) (*JudgeResult, error) {
-	// 获取编译选项
-	compileOpts := strings.TrimSpace(opts.CompileOptions)
-	if compileOpts == "" {
-		compileOpts = "-O2"
+func (r *DockerRunner) compileCode(ctx context.Context, containerID string, dir string, opts Options) (*JudgeResult, error) {
+	compileCmd, err := compileCommand(opts.CompileOptions)
+	if err != nil {
+		return &JudgeResult{Status: "Compilation Error", Output: err.Error()}, nil
 	}
 
-	// 构建编译命令
-	compileCmd := `g++ -std=c++23 ` + compileOpts + ` main.cpp -o main`
+	compileTimeout := opts.CompileTimeout
+	if compileTimeout <= 0 {
+		compileTimeout = defaultCompileTimeout
+	}
 
-	compileRes, err := r.execCommand(ctx, containerID, []string{"/bin/bash", "-c", compileCmd}, 0)
+	compileRes, err := r.execCommand(ctx, containerID, dir, compileCmd, int(compileTimeout.Milliseconds()))
 	if err != nil {
 		return nil, err
 	}
 
+	// 编译超时
+	if compileRes.TimedOut {
+		return &JudgeResult{
+			Status: "Compilation Error",
+			Output: "Compile Timeout: 编译超过 " + compileTimeout.String(),
+		}, nil
+	}
+
 	// 检查编译是否成功
 	if compileRes.ExitCode != 0 {
 		return &JudgeResult{
@@ -243,31 +563,34 @@ func (r *DockerRunner) compileCode(ctx context.Context, containerID string, opts
 }
 
 // runTestCases 运行所有测试用例
-func (r *DockerRunner) runTestCases(ctx context.Context, containerID string, language string, testCases []TestCase, opts Options) []CaseResult {
+// check 判断某个用例的程序输出是否正确
+func (r *DockerRunner) runTestCases(ctx context.Context, containerID string, dir string, language string, testCases []TestCase, opts Options, check outputChecker) []CaseResult {
 	results := make([]CaseResult, 0, len(testCases))
 	runCmd := r.getRunCommand(language)
 
 	for _, tc := range testCases {
-		result := r.runSingleTestCase(ctx, containerID, runCmd, tc, opts)
+		result := r.runSingleTestCase(ctx, containerID, dir, runCmd, tc, opts, check)
 		results = append(results, result)
 	}
 
 	return results
 }
 
-// runSingleTestCase 运行单个测试用例
-func (r *DockerRunner) runSingleTestCase(ctx context.Context, containerID string, runCmd string, tc TestCase, opts Options) CaseResult {
+// runSingleTestCase 在评测目录 dir 中运行单个测试用例
+func (r *DockerRunner) runSingleTestCase(ctx context.Context, containerID string, dir string, runCmd string, tc TestCase, opts Options, check outputChecker) CaseResult {
 	// 写入输入数据
-	inputB64 := base64.StdEncoding.EncodeToString([]byte(tc.Input))
-	_, _ = r.execCommand(ctx, containerID, []string{"/bin/bash", "-c", `echo "` + inputB64 + `" | base64 -d > input.txt`}, 0)
+	if err := r.writeFileToContainer(ctx, containerID, dir, "input.txt", tc.Input); err != nil {
+		return CaseResult{Status: "System Error", Output: err.Error()}
+	}
 
 	// 构建带时间统计的运行命令
-	timeCmd := `/usr/bin/time -f "%M %e"`
+	// time 的统计写入单独的文件，避免与用户程序的 stderr 混在一起
+	timeCmd := `rm -f ` + timeOutputFile + `; /usr/bin/time -o ` + timeOutputFile + ` -f "%M %e %U %S"`
 	runCmdWithTime := timeCmd + " " + runCmd + " < input.txt"
 
 	// 执行并计时
 	start := time.Now()
-	runRes, err := r.execCommand(ctx, containerID, []string{"/bin/bash", "-c", runCmdWithTime}, opts.TimeLimitMs)
+	runRes, err := r.execCommand(ctx, containerID, dir, []string{"/bin/bash", "-c", runCmdWithTime}, opts.TimeLimitMs*wallTimeLimitFactor)
 	elapsed := time.Since(start)
 
 	if err != nil {
@@ -278,14 +601,24 @@ func (r *DockerRunner) runSingleTestCase(ctx context.Context, containerID string
 		}
 	}
 
+	// 读取 time 的统计结果
+	timeOutput := ""
+	if !runRes.TimedOut {
+		if timeRes, err := r.execCommand(ctx, containerID, dir, []string{"cat", timeOutputFile}, 0); err == nil && timeRes.ExitCode == 0 {
+			timeOutput = timeRes.Stdout
+		}
+	}
+
 	// 解析并返回结果
-	return r.parseTestCaseResult(runRes, tc, opts, int(elapsed.Milliseconds()))
+	return r.parseTestCaseResult(runRes, timeOutput, tc, opts, int(elapsed.Milliseconds()), check)
 }
 
 // parseTestCaseResult 解析测试用例执行结果
-func (r *DockerRunner) parseTestCaseResult(runRes execResult, tc TestCase, opts Options, timeUsed int) CaseResult {
+// timeOutput 为 time 命令写入统计文件的内容，elapsed 为外部测得的墙钟时间
+func (r *DockerRunner) parseTestCaseResult(runRes execResult, timeOutput string, tc TestCase, opts Options, elapsed int, check outputChecker) CaseResult {
 	result := CaseResult{
-		TimeUsed:   timeUsed,
+		TimeUsed:   elapsed,
+		WallTime:   elapsed,
 		MemoryUsed: 0,
 		Output:     strings.TrimSpace(runRes.Stdout),
 	}
@@ -299,18 +632,43 @@ func (r *DockerRunner) parseTestCaseResult(runRes execResult, tc TestCase, opts
 		return result
 	}
 
-	// 检查是否运行时错误
+	// 解析内存和时间使用量
+	if stats, ok := parseTimeOutput(timeOutput); ok {
+		result.MemoryUsed = stats.memoryKB
+		result.WallTime = stats.wallMs
+		result.CPUTime = stats.cpuMs
+		result.TimeUsed = stats.cpuMs
+	}
+
+	// CPU 时间超过限制同样判为超时
+	if opts.TimeLimitMs > 0 && result.CPUTime > opts.TimeLimitMs {
+		result.Status = "Time Limit Exceeded"
+		return result
+	}
+
+	// 检查是否运行时错误，此时 stderr 只包含用户程序自身的输出
+	// stderr 可能泄露测试数据，Output 只保留概要，详情放在仅管理员可见的字段中
 	if runRes.ExitCode != 0 {
 		result.Status = "Runtime Error"
-		result.Output = runRes.Stderr
+		result.Stderr = strings.TrimSpace(runRes.Stderr)
+		result.ExitCode = runRes.ExitCode
+		if runRes.ExitCode > 128 {
+			result.Signal = signalName(runRes.ExitCode - 128)
+			result.Output = "Terminated by signal " + result.Signal
+		} else {
+			result.Output = "Exited with code " + strconv.Itoa(runRes.ExitCode)
+		}
 		return result
 	}
 
-	// 解析内存使用量
-	result.MemoryUsed = r.parseMemoryUsage(runRes.Stderr)
-
 	// 比较输出结果
-	if strings.TrimSpace(result.Output) != strings.TrimSpace(tc.ExpectedOutput) {
+	ok, err := check(tc, runRes.Stdout)
+	if err != nil {
+		result.Status = "System Error"
+		result.Output = err.Error()
+		return result
+	}
+	if !ok {
 		result.Status = "Wrong Answer"
 	} else {
 		result.Status = "Accepted"
@@ -319,24 +677,97 @@ func (r *DockerRunner) parseTestCaseResult(runRes execResult, tc TestCase, opts
 	return result
 }
 
-// parseMemoryUsage 从 time 命令的输出中解析内存使用量
-func (r *DockerRunner) parseMemoryUsage(stderr string) int {
-	stderrLines := strings.Split(strings.TrimSpace(stderr), "\n")
-	if len(stderrLines) > 0 {
-		lastLine := strings.TrimSpace(stderrLines[len(stderrLines)-1])
-		parts := strings.Fields(lastLine)
-		if len(parts) >= 2 {
-			if mem, err := parsePositiveInt(parts[0]); err == nil {
-				return mem
-			}
+// outputsMatch 按比较方式判断实际输出与期望输出是否一致
+func outputsMatch(mode string, actual, expected string) bool {
+	switch mode {
+	case CompareExact:
+		return actual == expected
+	case CompareLines:
+		return slices.Equal(normalizeLines(actual), normalizeLines(expected))
+	case CompareTokens:
+		return slices.Equal(strings.Fields(actual), strings.Fields(expected))
+	default:
+		return strings.TrimSpace(actual) == strings.TrimSpace(expected)
+	}
+}
+
+// normalizeLines 去除每行行尾空白（含 \r）以及末尾的空行
+func normalizeLines(s string) []string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// timeStats time 命令统计的资源使用
+type timeStats struct {
+	memoryKB int // 最大常驻内存（KB）
+	wallMs   int // 墙钟时间（毫秒）
+	cpuMs    int // 用户态 + 内核态 CPU 时间（毫秒）
+}
+
+// signalNames 常见的导致用户程序终止的信号
+var signalNames = map[int]string{
+	1:  "SIGHUP",
+	2:  "SIGINT",
+	4:  "SIGILL",
+	5:  "SIGTRAP",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	9:  "SIGKILL",
+	11: "SIGSEGV",
+	13: "SIGPIPE",
+	14: "SIGALRM",
+	15: "SIGTERM",
+	24: "SIGXCPU",
+	25: "SIGXFSZ",
+	31: "SIGSYS",
+}
+
+// signalName 返回信号编号对应的名称，未知信号返回 SIG 加编号
+// shell 以 128+N 的退出码表示进程被信号 N 终止
+func signalName(sig int) string {
+	if name, ok := signalNames[sig]; ok {
+		return name
+	}
+	return "SIG" + strconv.Itoa(sig)
+}
+
+// parseTimeOutput 解析 time 命令以 "%M %e %U %S" 格式写入统计文件的内容
+// 程序异常退出时 time 会在统计行之前写入 "Command exited with non-zero status"，因此取最后一行
+func parseTimeOutput(timeOutput string) (timeStats, bool) {
+	lines := strings.Split(strings.TrimSpace(timeOutput), "\n")
+	parts := strings.Fields(lines[len(lines)-1])
+	if len(parts) < 4 {
+		return timeStats{}, false
+	}
+	mem, err := parsePositiveInt(parts[0])
+	if err != nil {
+		return timeStats{}, false
+	}
+	var secs [3]float64
+	for i, p := range parts[1:4] {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v < 0 {
+			return timeStats{}, false
 		}
+		secs[i] = v
 	}
-	return 0
+	return timeStats{
+		memoryKB: mem,
+		wallMs:   int(math.Round(secs[0] * 1000)),
+		cpuMs:    int(math.Round((secs[1] + secs[2]) * 1000)),
+	}, true
 }
 
-// execCommand 在容器中执行命令
+// execCommand 在容器中以 dir 为工作目录执行命令
 // timeoutMs: 超时时间（毫秒），0 表示不限制
-func (r *DockerRunner) execCommand(ctx context.Context, containerID string, cmd []string, timeoutMs int) (execResult, error) {
+func (r *DockerRunner) execCommand(ctx context.Context, containerID string, dir string, cmd []string, timeoutMs int) (execResult, error) {
 	// 设置超时上下文
 	execCtx := ctx
 	var cancel context.CancelFunc
@@ -348,6 +779,7 @@ func (r *DockerRunner) execCommand(ctx context.Context, containerID string, cmd
 	// 创建执行实例
 	created, err := r.cli.ContainerExecCreate(execCtx, containerID, container.ExecOptions{
 		Cmd:          cmd,
+		WorkingDir:   dir,
 		AttachStdout: true,
 		AttachStderr: true,
 	})
@@ -369,12 +801,37 @@ func (r *DockerRunner) execCommand(ctx context.Context, containerID string, cmd
 // handleExecError 处理执行错误
 func (r *DockerRunner) handleExecError(err error, containerID string) (execResult, error) {
 	if errors.Is(err, context.DeadlineExceeded) {
-		_ = r.cli.ContainerStop(context.Background(), containerID, container.StopOptions{})
+		r.killContainerProcesses(containerID)
 		return execResult{ExitCode: -1, TimedOut: true}, nil
 	}
 	return execResult{}, err
 }
 
+// killContainerProcesses 结束容器内除 PID 1 以外的所有进程
+// 超时后只终止用户程序而不停止容器，后续测试用例仍可在同一容器中运行
+// 容器内的 PID 1 不会被同一命名空间内发出的 SIGKILL 终止，kill 也不会终止自身
+func (r *DockerRunner) killContainerProcesses(containerID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), killTimeout)
+	defer cancel()
+	created, err := r.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          []string{"kill", "-KILL", "-1"},
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err == nil {
+		var attach types.HijackedResponse
+		attach, err = r.cli.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{})
+		if err == nil {
+			_, _ = io.Copy(io.Discard, attach.Reader)
+			attach.Close()
+		}
+	}
+	if err != nil {
+		// 无法单独终止进程时退回到停止整个容器，之后的用例会以 System Error 结束
+		_ = r.cli.ContainerStop(context.Background(), containerID, container.StopOptions{})
+	}
+}
+
 // readExecOutput 读取命令执行的输出
 func (r *DockerRunner) readExecOutput(ctx context.Context, execCtx context.Context, containerID string, execID string, attach types.HijackedResponse) (execResult, error) {
 	var stdoutBuf bytes.Buffer
@@ -398,8 +855,14 @@ func (r *DockerRunner) readExecOutput(ctx context.Context, execCtx context.Conte
 			return execResult{}, err
 		}
 	case <-execCtx.Done():
-		// 超时，停止容器
-		_ = r.cli.ContainerStop(context.Background(), containerID, container.StopOptions{})
+		// 超时，只终止容器内的进程，并等待输出流随进程结束而关闭
+		r.killContainerProcesses(containerID)
+		select {
+		case <-copyDone:
+		case <-time.After(killTimeout):
+			attach.Close()
+			<-copyDone
+		}
 		return execResult{
 			ExitCode: -1,
 			Stdout:   stdoutBuf.String(),
@@ -0,0 +1,219 @@
+package judger
+
+import (
+	"archive/tar"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCompileCommand(t *testing.T) {
+	tests := []struct {
+		opts string
+		want []string
+	}{
+		{"", []string{"g++", "-std=c++23", "-O2", "main.cpp", "-o", "main"}},
+		{"  -O3   -Wall\t-DLOCAL ", []string{"g++", "-std=c++23", "-O3", "-Wall", "-DLOCAL", "main.cpp", "-o", "main"}},
+		// 即使校验被绕过，注入的内容也只是 g++ 的普通参数，不会经过 shell
+		{"; rm -rf /", []string{"g++", "-std=c++23", ";", "rm", "-rf", "/", "main.cpp", "-o", "main"}},
+		{"-DX=$(id)", []string{"g++", "-std=c++23", "-DX=$(id)", "main.cpp", "-o", "main"}},
+		{"-DX=`id`;id", []string{"g++", "-std=c++23", "-DX=`id`;id", "main.cpp", "-o", "main"}},
+	}
+	for _, tt := range tests {
+		got, err := compileCommand(tt.opts)
+		if err != nil {
+			t.Fatalf("compileCommand(%q): %v", tt.opts, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("compileCommand(%q) = %q, want %q", tt.opts, got, tt.want)
+		}
+		for _, shell := range []string{"sh", "bash", "/bin/sh", "/bin/bash", "-c"} {
+			if slices.Contains(got, shell) {
+				t.Errorf("compileCommand(%q) runs through a shell: %q", tt.opts, got)
+			}
+		}
+	}
+}
+
+func TestCompileCommandArgLimit(t *testing.T) {
+	if _, err := compileCommand(strings.Repeat("-O2 ", maxCompileArgs)); err != nil {
+		t.Fatalf("%d options: %v", maxCompileArgs, err)
+	}
+	if _, err := compileCommand(strings.Repeat("-O2 ", maxCompileArgs+1)); err == nil {
+		t.Fatalf("%d options: expected an error", maxCompileArgs+1)
+	}
+}
+
+func TestJudgeInContainerConcurrent(t *testing.T) {
+	fake := newFakeDocker()
+	r := &DockerRunner{imageName: "test", cli: fake}
+	ctr := fake.addContainer()
+
+	const judges = 8
+	results := make([]JudgeResult, judges)
+	errs := make([]error, judges)
+	var wg sync.WaitGroup
+	for i := range judges {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var cases []TestCase
+			for j := range 3 {
+				in := "judge " + strconv.Itoa(i) + " case " + strconv.Itoa(j)
+				cases = append(cases, TestCase{Input: in, ExpectedOutput: in})
+			}
+			code := "# judge " + strconv.Itoa(i)
+			results[i], errs[i] = r.judgeInContainer(context.Background(), ctr, "python", code, cases, Options{TimeLimitMs: 1000})
+		}()
+	}
+	wg.Wait()
+
+	for i := range judges {
+		if errs[i] != nil {
+			t.Fatalf("judge %d: %v", i, errs[i])
+		}
+		for j, res := range results[i].Results {
+			if res.Status != "Accepted" {
+				t.Errorf("judge %d case %d: status %q, output %q", i, j, res.Status, res.Output)
+			}
+		}
+	}
+
+	// 每次评测的代码和输入只写入各自的目录
+	dirOf := make(map[string]string) // 代码 -> 目录
+	owner := make(map[string]string) // 目录 -> 代码
+	for _, cp := range fake.copies {
+		if code, ok := cp.contents["main.py"]; ok {
+			if other, dup := owner[cp.dir]; dup {
+				t.Fatalf("%q and %q share directory %s", other, code, cp.dir)
+			}
+			dirOf[code], owner[cp.dir] = cp.dir, code
+		}
+	}
+	if len(dirOf) != judges {
+		t.Fatalf("got %d work directories, want %d", len(dirOf), judges)
+	}
+	for _, cp := range fake.copies {
+		in, ok := cp.contents["input.txt"]
+		if !ok {
+			continue
+		}
+		judge := strings.SplitN(in, " case ", 2)[0]
+		if want := dirOf["# "+judge]; cp.dir != want {
+			t.Errorf("input %q written to %s, want %s", in, cp.dir, want)
+		}
+	}
+
+	// 每次清理只删除自己的目录，且恰好一次
+	removed := make(map[string]int)
+	for _, e := range fake.execLog {
+		if e.cmd[0] == "rm" {
+			removed[e.cmd[len(e.cmd)-1]]++
+		}
+	}
+	for code, dir := range dirOf {
+		if removed[dir] != 1 {
+			t.Errorf("%q: directory %s removed %d times, want 1", code, dir, removed[dir])
+		}
+	}
+	if len(removed) != judges {
+		t.Errorf("removed %d directories, want %d: %v", len(removed), judges, removed)
+	}
+	c := fake.containers[ctr]
+	if len(c.files) != 0 || len(c.dirs) != 1 {
+		t.Errorf("container not clean after judging: files %v, dirs %v", c.files, c.dirs)
+	}
+}
+
+func TestJudgeInContainerKeepsOtherWorkDir(t *testing.T) {
+	fake := newFakeDocker()
+	r := &DockerRunner{imageName: "test", cli: fake}
+	ctr := fake.addContainer()
+	ctx := context.Background()
+
+	// 另一评测正在使用的目录
+	other, err := r.createWorkDir(ctx, ctr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.writeFileToContainer(ctx, ctr, other, "input.txt", "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := r.judgeInContainer(ctx, ctr, "python", "print(input())", []TestCase{{Input: "1", ExpectedOutput: "1"}}, Options{TimeLimitMs: 1000})
+	if err != nil || len(res.Results) != 1 || res.Results[0].Status != "Accepted" {
+		t.Fatalf("judgeInContainer = %+v, %v", res, err)
+	}
+	c := fake.containers[ctr]
+	if got := c.files[other+"/input.txt"]; got != "other" || !c.dirs[other] {
+		t.Fatalf("cleanup removed another judge's directory %s", other)
+	}
+	if len(c.dirs) != 2 {
+		t.Errorf("judge directory left behind: %v", c.dirs)
+	}
+}
+
+func TestWriteFilesToContainer(t *testing.T) {
+	fake := newFakeDocker()
+	r := &DockerRunner{imageName: "test", cli: fake}
+	ctr := fake.addContainer()
+	ctx := context.Background()
+	dir, err := r.createWorkDir(ctx, ctr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 内容中的引号、换行和 shell 元字符都应原样写入
+	files := []containerFile{
+		{name: "main.py", content: "print('$(id)')\n`id`; rm -rf /\n"},
+		{name: "input.txt", content: "1 2\n"},
+		{name: "empty.txt", content: ""},
+	}
+	if err := r.writeFilesToContainer(ctx, ctr, dir, files...); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.copies) != 1 {
+		t.Fatalf("CopyToContainer called %d times, want 1", len(fake.copies))
+	}
+	cp := fake.copies[0]
+	if cp.containerID != ctr || cp.dir != dir {
+		t.Errorf("copied to %s:%s, want %s:%s", cp.containerID, cp.dir, ctr, dir)
+	}
+	if len(cp.entries) != len(files) {
+		t.Fatalf("archive has %d entries, want %d", len(cp.entries), len(files))
+	}
+	for i, f := range files {
+		hdr := cp.entries[i]
+		if hdr.Name != f.name {
+			t.Errorf("entry %d name = %q, want %q", i, hdr.Name, f.name)
+		}
+		if hdr.Mode != 0o644 {
+			t.Errorf("%s mode = %o, want 644", f.name, hdr.Mode)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			t.Errorf("%s type = %c, want a regular file", f.name, hdr.Typeflag)
+		}
+		if got := cp.contents[f.name]; got != f.content {
+			t.Errorf("%s content = %q, want %q", f.name, got, f.content)
+		}
+	}
+	// 写入不在容器内启动进程
+	for _, e := range fake.execLog {
+		if e.cmd[0] != "mkdir" {
+			t.Errorf("unexpected exec %q", e.cmd)
+		}
+	}
+}
+
+func TestWriteFilesToContainerMissingDir(t *testing.T) {
+	fake := newFakeDocker()
+	r := &DockerRunner{imageName: "test", cli: fake}
+	ctr := fake.addContainer()
+	if err := r.writeFileToContainer(context.Background(), ctr, containerWorkDir+"/missing", "main.py", ""); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+}
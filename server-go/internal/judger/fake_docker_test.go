@@ -0,0 +1,278 @@
+package judger
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// fakeDocker 内存中的 Docker 假实现，只模拟评测流程用到的命令：
+// mkdir、rm -rf、cat、带 time 统计的运行命令（程序把 input.txt 原样输出）以及容器重置脚本
+type fakeDocker struct {
+	mu         sync.Mutex
+	nextID     int
+	containers map[string]*fakeContainer
+	execs      map[string]*fakeExec
+
+	resetExitCode int // 重置脚本的退出码
+	updateErr     error
+
+	copies  []fakeCopy
+	updates []fakeUpdate
+	removed []string
+	execLog []fakeExec
+}
+
+// fakeContainer 假容器，files 和 dirs 均以绝对路径为键
+type fakeContainer struct {
+	files map[string]string
+	dirs  map[string]bool
+}
+
+type fakeExec struct {
+	containerID string
+	dir         string
+	user        string
+	cmd         []string
+	exitCode    int
+}
+
+type fakeCopy struct {
+	containerID string
+	dir         string
+	entries     []*tar.Header
+	contents    map[string]string // 文件名 -> 内容
+}
+
+type fakeUpdate struct {
+	containerID string
+	config      container.UpdateConfig
+}
+
+func newFakeDocker() *fakeDocker {
+	return &fakeDocker{
+		containers: make(map[string]*fakeContainer),
+		execs:      make(map[string]*fakeExec),
+	}
+}
+
+// addContainer 直接创建一个已启动的容器，返回其 ID
+func (f *fakeDocker) addContainer() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	id := "ctr-" + strconv.Itoa(f.nextID)
+	f.containers[id] = &fakeContainer{
+		files: make(map[string]string),
+		dirs:  map[string]bool{containerWorkDir: true},
+	}
+	return id
+}
+
+func (f *fakeDocker) container(id string) (*fakeContainer, error) {
+	c, ok := f.containers[id]
+	if !ok {
+		return nil, errors.New("no such container: " + id)
+	}
+	return c, nil
+}
+
+func (f *fakeDocker) Ping(ctx context.Context) (types.Ping, error) {
+	return types.Ping{}, nil
+}
+
+func (f *fakeDocker) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	return types.ImageInspect{ID: imageID}, nil, nil
+}
+
+func (f *fakeDocker) ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (f *fakeDocker) ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error) {
+	return nil, nil
+}
+
+func (f *fakeDocker) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	return container.CreateResponse{ID: f.addContainer()}, nil
+}
+
+func (f *fakeDocker) ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := f.container(containerID)
+	return err
+}
+
+func (f *fakeDocker) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	return nil
+}
+
+func (f *fakeDocker) ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.container(containerID); err != nil {
+		return err
+	}
+	delete(f.containers, containerID)
+	f.removed = append(f.removed, containerID)
+	return nil
+}
+
+func (f *fakeDocker) ContainerUpdate(ctx context.Context, containerID string, updateConfig container.UpdateConfig) (container.ContainerUpdateOKBody, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.container(containerID); err != nil {
+		return container.ContainerUpdateOKBody{}, err
+	}
+	if f.updateErr != nil {
+		return container.ContainerUpdateOKBody{}, f.updateErr
+	}
+	f.updates = append(f.updates, fakeUpdate{containerID: containerID, config: updateConfig})
+	return container.ContainerUpdateOKBody{}, nil
+}
+
+func (f *fakeDocker) CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, options container.CopyToContainerOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.container(containerID)
+	if err != nil {
+		return err
+	}
+	if !c.dirs[dstPath] {
+		return errors.New("no such directory: " + dstPath)
+	}
+	cp := fakeCopy{containerID: containerID, dir: dstPath, contents: make(map[string]string)}
+	tr := tar.NewReader(content)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		c.files[path.Join(dstPath, hdr.Name)] = string(data)
+		cp.entries = append(cp.entries, hdr)
+		cp.contents[hdr.Name] = string(data)
+	}
+	f.copies = append(f.copies, cp)
+	return nil
+}
+
+func (f *fakeDocker) ContainerExecCreate(ctx context.Context, containerID string, options container.ExecOptions) (types.IDResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.container(containerID); err != nil {
+		return types.IDResponse{}, err
+	}
+	f.nextID++
+	id := "exec-" + strconv.Itoa(f.nextID)
+	f.execs[id] = &fakeExec{containerID: containerID, dir: options.WorkingDir, user: options.User, cmd: options.Cmd}
+	return types.IDResponse{ID: id}, nil
+}
+
+func (f *fakeDocker) ContainerExecAttach(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.execs[execID]
+	if !ok {
+		return types.HijackedResponse{}, errors.New("no such exec: " + execID)
+	}
+	c, err := f.container(e.containerID)
+	if err != nil {
+		return types.HijackedResponse{}, err
+	}
+	stdout, stderr, exitCode := f.run(c, e)
+	e.exitCode = exitCode
+	f.execLog = append(f.execLog, *e)
+
+	var buf bytes.Buffer
+	_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write([]byte(stdout))
+	if stderr != "" {
+		_, _ = stdcopy.NewStdWriter(&buf, stdcopy.Stderr).Write([]byte(stderr))
+	}
+	conn, peer := net.Pipe()
+	peer.Close()
+	return types.HijackedResponse{Conn: conn, Reader: bufio.NewReader(&buf)}, nil
+}
+
+func (f *fakeDocker) ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.execs[execID]
+	if !ok {
+		return container.ExecInspect{}, errors.New("no such exec: " + execID)
+	}
+	return container.ExecInspect{ExecID: execID, ExitCode: e.exitCode}, nil
+}
+
+// run 模拟在容器中执行命令，调用方持有 f.mu
+func (f *fakeDocker) run(c *fakeContainer, e *fakeExec) (stdout, stderr string, exitCode int) {
+	cmd := e.cmd
+	switch {
+	case len(cmd) == 2 && cmd[0] == "mkdir":
+		if c.dirs[cmd[1]] {
+			return "", "mkdir: File exists", 1
+		}
+		c.dirs[cmd[1]] = true
+		return "", "", 0
+	case len(cmd) == 4 && cmd[0] == "rm" && cmd[1] == "-rf" && cmd[2] == "--":
+		c.remove(cmd[3])
+		return "", "", 0
+	case len(cmd) == 2 && cmd[0] == "cat":
+		content, ok := c.files[path.Join(e.dir, cmd[1])]
+		if !ok {
+			return "", "cat: No such file or directory", 1
+		}
+		return content, "", 0
+	case len(cmd) == 3 && cmd[0] == "/bin/bash" && cmd[2] == resetScript:
+		if f.resetExitCode == 0 {
+			c.remove(containerWorkDir)
+			c.dirs[containerWorkDir] = true
+		}
+		return "", "", f.resetExitCode
+	case len(cmd) == 3 && cmd[0] == "/bin/bash" && strings.HasSuffix(cmd[2], " < input.txt"):
+		input, ok := c.files[path.Join(e.dir, "input.txt")]
+		if !ok {
+			return "", "input.txt: No such file or directory", 1
+		}
+		c.files[path.Join(e.dir, timeOutputFile)] = "1024 0.01 0.00 0.00\n"
+		return input, "", 0
+	}
+	return "", "unsupported command: " + strings.Join(cmd, " "), 127
+}
+
+// remove 删除目录 dir 及其下的全部内容
+func (c *fakeContainer) remove(dir string) {
+	delete(c.dirs, dir)
+	for p := range c.dirs {
+		if strings.HasPrefix(p, dir+"/") {
+			delete(c.dirs, p)
+		}
+	}
+	for p := range c.files {
+		if strings.HasPrefix(p, dir+"/") {
+			delete(c.files, p)
+		}
+	}
+}
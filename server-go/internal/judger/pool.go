@@ -0,0 +1,232 @@
+package judger
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// poolContainerLifetime 池中容器的存活时间，到期前会被替换
+const poolContainerLifetime = time.Hour
+
+// poolRetireBefore 剩余存活时间少于该值的空闲容器不再分配，由补充循环替换
+const poolRetireBefore = poolContainerLifetime / 2
+
+// poolFillInterval 补充循环的最长间隔，取出容器时也会立即唤醒
+const poolFillInterval = time.Minute
+
+// resetTimeout 重置容器的超时
+const resetTimeout = 10 * time.Second
+
+// resetScript 以 root 身份在容器内执行，用于复用前恢复干净状态：
+// 终止除 PID 1 以外的全部进程，确认没有残留（僵尸进程除外），再清空可写目录
+// 任一步失败都以非零退出码结束，调用方据此丢弃容器
+const resetScript = `kill -KILL -1 2>/dev/null
+sleep 0.2
+for d in /proc/[0-9]*; do
+  pid=${d#/proc/}
+  { [ "$pid" = 1 ] || [ "$pid" = $$ ]; } && continue
+  grep -q '^State:[[:space:]]*Z' "$d/status" 2>/dev/null || [ ! -e "$d" ] || exit 1
+done
+find ` + containerWorkDir + ` /tmp /var/tmp /dev/shm /home/runner -mindepth 1 -delete`
+
+// judgeContainer 一次评测使用的容器
+type judgeContainer struct {
+	id      string
+	expires time.Time // 容器内 sleep 结束的时间
+	pooled  bool      // 是否取自容器池，是则用完后重置并放回
+}
+
+// containerPool 预先启动、可重复使用的评测容器池
+// 省去每次评测创建和启动容器的开销；容器用完后重置状态再放回，重置失败则删除
+type containerPool struct {
+	r    *DockerRunner
+	size int
+
+	mu   sync.Mutex
+	idle []judgeContainer
+
+	wake chan struct{}
+}
+
+// newContainerPool 创建容器池并在后台补充到 size 个空闲容器
+func newContainerPool(r *DockerRunner, size int) *containerPool {
+	p := &containerPool{r: r, size: size, wake: make(chan struct{}, 1)}
+	go p.run()
+	return p
+}
+
+// run 补充循环：替换即将到期的空闲容器，并补足到 size 个
+func (p *containerPool) run() {
+	ticker := time.NewTicker(poolFillInterval)
+	defer ticker.Stop()
+	for {
+		p.fill()
+		select {
+		case <-p.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// signal 唤醒补充循环，不阻塞
+func (p *containerPool) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// fill 删除即将到期的空闲容器，并创建新容器补足空位
+func (p *containerPool) fill() {
+	p.mu.Lock()
+	var stale []judgeContainer
+	kept := p.idle[:0]
+	for _, c := range p.idle {
+		if time.Until(c.expires) < poolRetireBefore {
+			stale = append(stale, c)
+		} else {
+			kept = append(kept, c)
+		}
+	}
+	p.idle = kept
+	missing := p.size - len(p.idle)
+	p.mu.Unlock()
+
+	for _, c := range stale {
+		p.r.cleanupContainer(c.id)
+	}
+	for range missing {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		id, err := p.r.createAndStartContainer(ctx, Options{}, poolContainerLifetime)
+		cancel()
+		if err != nil {
+			log.Printf("[judger] 预启动评测容器失败: %v", err)
+			return
+		}
+		p.put(judgeContainer{id: id, expires: time.Now().Add(poolContainerLifetime), pooled: true})
+	}
+}
+
+// put 将空闲容器放回池中，池已满时删除
+func (p *containerPool) put(c judgeContainer) {
+	p.mu.Lock()
+	if len(p.idle) < p.size {
+		p.idle = append(p.idle, c)
+		c.id = ""
+	}
+	p.mu.Unlock()
+	if c.id != "" {
+		p.r.cleanupContainer(c.id)
+	}
+}
+
+// take 取出一个剩余存活时间不少于 lifetime 的空闲容器，并将其内存限制调整为 memoryBytes
+// 池中没有可用容器或评测所需时间超出池中容器的保证存活时间时返回 false，调用方应改为新建容器
+func (p *containerPool) take(ctx context.Context, memoryBytes int64, lifetime time.Duration) (judgeContainer, bool) {
+	if lifetime > poolRetireBefore {
+		return judgeContainer{}, false
+	}
+	defer p.signal()
+	for {
+		p.mu.Lock()
+		n := len(p.idle)
+		if n == 0 {
+			p.mu.Unlock()
+			return judgeContainer{}, false
+		}
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+
+		if time.Until(c.expires) < lifetime {
+			p.r.cleanupContainer(c.id)
+			continue
+		}
+		// 与创建容器时只设置 Memory 的效果一致：交换空间上限为内存的 2 倍
+		_, err := p.r.cli.ContainerUpdate(ctx, c.id, container.UpdateConfig{
+			Resources: container.Resources{Memory: memoryBytes, MemorySwap: 2 * memoryBytes},
+		})
+		if err != nil {
+			log.Printf("[judger] 调整池中容器内存限制失败: %v", err)
+			p.r.cleanupContainer(c.id)
+			continue
+		}
+		return c, true
+	}
+}
+
+// release 重置用完的池中容器并放回，重置失败时删除
+func (p *containerPool) release(c judgeContainer) {
+	if time.Until(c.expires) < poolRetireBefore {
+		p.r.cleanupContainer(c.id)
+		p.signal()
+		return
+	}
+	if err := p.r.resetContainer(c.id); err != nil {
+		log.Printf("[judger] 重置评测容器失败，已丢弃: %v", err)
+		p.r.cleanupContainer(c.id)
+		p.signal()
+		return
+	}
+	p.put(c)
+}
+
+// resetContainer 以 root 身份执行 resetScript，恢复容器的进程和文件状态
+func (r *DockerRunner) resetContainer(containerID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), resetTimeout)
+	defer cancel()
+	created, err := r.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		User:         "root",
+		Cmd:          []string{"/bin/bash", "-c", resetScript},
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+	attach, err := r.cli.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return err
+	}
+	defer attach.Close()
+	res, err := r.readExecOutput(ctx, ctx, containerID, created.ID, attach)
+	if err != nil {
+		return err
+	}
+	if res.TimedOut {
+		return errors.New("重置容器超时")
+	}
+	if res.ExitCode != 0 {
+		return errors.New("残留进程未能终止或清理文件失败: " + strings.TrimSpace(res.Stderr))
+	}
+	return nil
+}
+
+// acquireContainer 为一次评测取得容器：优先从容器池中取出，否则新建
+func (r *DockerRunner) acquireContainer(ctx context.Context, opts Options, lifetime time.Duration) (judgeContainer, error) {
+	if r.pool != nil {
+		if c, ok := r.pool.take(ctx, memoryLimitBytes(opts), lifetime); ok {
+			return c, nil
+		}
+	}
+	id, err := r.createAndStartContainer(ctx, opts, lifetime)
+	if err != nil {
+		return judgeContainer{}, err
+	}
+	return judgeContainer{id: id, expires: time.Now().Add(lifetime)}, nil
+}
+
+// releaseContainer 评测结束后归还容器：池中容器在后台重置后放回，其余直接删除
+func (r *DockerRunner) releaseContainer(c judgeContainer) {
+	if c.pooled && r.pool != nil {
+		go r.pool.release(c)
+		return
+	}
+	r.cleanupContainer(c.id)
+}
@@ -0,0 +1,144 @@
+package judger
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestPool 创建使用假 Docker 的容器池，不启动后台补充循环
+func newTestPool(size int) (*fakeDocker, *containerPool) {
+	fake := newFakeDocker()
+	r := &DockerRunner{imageName: "test", cli: fake}
+	r.pool = &containerPool{r: r, size: size, wake: make(chan struct{}, 1)}
+	return fake, r.pool
+}
+
+// addIdle 向池中加入一个剩余存活时间为 left 的空闲容器
+func addIdle(fake *fakeDocker, p *containerPool, left time.Duration) judgeContainer {
+	c := judgeContainer{id: fake.addContainer(), expires: time.Now().Add(left), pooled: true}
+	p.idle = append(p.idle, c)
+	return c
+}
+
+func TestPoolTakeResizesIdleContainer(t *testing.T) {
+	fake, p := newTestPool(2)
+	idle := addIdle(fake, p, poolContainerLifetime)
+
+	const memory = 256 << 20
+	c, ok := p.take(context.Background(), memory, time.Minute)
+	if !ok || c.id != idle.id || !c.pooled {
+		t.Fatalf("take = %+v, %v; want idle container %s", c, ok, idle.id)
+	}
+	if len(p.idle) != 0 {
+		t.Errorf("container still idle after take: %v", p.idle)
+	}
+	if len(fake.updates) != 1 {
+		t.Fatalf("ContainerUpdate called %d times, want 1", len(fake.updates))
+	}
+	u := fake.updates[0]
+	if u.containerID != idle.id || u.config.Memory != memory || u.config.MemorySwap != 2*memory {
+		t.Errorf("update = %s %+v, want memory %d and swap %d", u.containerID, u.config.Resources, memory, 2*memory)
+	}
+}
+
+func TestPoolTakeDiscardsShortLivedAndFailedUpdates(t *testing.T) {
+	fake, p := newTestPool(2)
+	short := addIdle(fake, p, 2*time.Minute)
+	_, ok := p.take(context.Background(), 1<<20, 5*time.Minute)
+	if ok {
+		t.Fatal("take returned a container that expires before the judge ends")
+	}
+	if !slices.Contains(fake.removed, short.id) {
+		t.Errorf("short-lived container %s not removed", short.id)
+	}
+
+	broken := addIdle(fake, p, poolContainerLifetime)
+	fake.updateErr = errors.New("update failed")
+	if _, ok := p.take(context.Background(), 1<<20, time.Minute); ok {
+		t.Fatal("take returned a container whose memory limit could not be set")
+	}
+	if !slices.Contains(fake.removed, broken.id) {
+		t.Errorf("container %s with failed update not removed", broken.id)
+	}
+}
+
+func TestAcquireContainerLongJudgeSkipsPool(t *testing.T) {
+	fake, p := newTestPool(1)
+	idle := addIdle(fake, p, poolContainerLifetime)
+
+	c, err := p.r.acquireContainer(context.Background(), Options{}, poolRetireBefore+time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.pooled || c.id == idle.id {
+		t.Errorf("acquireContainer = %+v, want a new container", c)
+	}
+	if len(p.idle) != 1 || len(fake.updates) != 0 {
+		t.Errorf("pool touched for a long judge: idle %v, updates %v", p.idle, fake.updates)
+	}
+}
+
+func TestPoolReleaseResetsOrDiscards(t *testing.T) {
+	fake, p := newTestPool(2)
+	c := judgeContainer{id: fake.addContainer(), expires: time.Now().Add(poolContainerLifetime), pooled: true}
+	fake.containers[c.id].files[containerWorkDir+"/judge-x/main.py"] = "leftover"
+
+	p.release(c)
+	if len(p.idle) != 1 || p.idle[0].id != c.id {
+		t.Fatalf("reset container not returned to the pool: %v", p.idle)
+	}
+	if files := fake.containers[c.id].files; len(files) != 0 {
+		t.Errorf("files left after reset: %v", files)
+	}
+	if last := fake.execLog[len(fake.execLog)-1]; last.user != "root" {
+		t.Errorf("reset ran as %q, want root", last.user)
+	}
+
+	p.idle = nil
+	fake.resetExitCode = 1
+	p.release(c)
+	if len(p.idle) != 0 {
+		t.Errorf("container with failed reset returned to the pool: %v", p.idle)
+	}
+	if !slices.Contains(fake.removed, c.id) {
+		t.Errorf("container %s with failed reset not removed", c.id)
+	}
+}
+
+func TestPoolFillRetiresNearExpiry(t *testing.T) {
+	fake, p := newTestPool(2)
+	stale := addIdle(fake, p, poolRetireBefore-time.Minute)
+	fresh := addIdle(fake, p, poolContainerLifetime)
+
+	p.fill()
+	if !slices.Contains(fake.removed, stale.id) {
+		t.Errorf("near-expiry container %s not removed", stale.id)
+	}
+	if len(p.idle) != 2 {
+		t.Fatalf("pool has %d idle containers, want 2", len(p.idle))
+	}
+	if p.idle[0].id != fresh.id {
+		t.Errorf("fresh container %s not kept: %v", fresh.id, p.idle)
+	}
+	added := p.idle[1]
+	if added.id == stale.id || !added.pooled || time.Until(added.expires) < poolRetireBefore {
+		t.Errorf("replacement container = %+v", added)
+	}
+}
+
+func TestPoolPutDeletesWhenFull(t *testing.T) {
+	fake, p := newTestPool(1)
+	addIdle(fake, p, poolContainerLifetime)
+	extra := judgeContainer{id: fake.addContainer(), expires: time.Now().Add(poolContainerLifetime), pooled: true}
+
+	p.put(extra)
+	if len(p.idle) != 1 || p.idle[0].id == extra.id {
+		t.Errorf("full pool accepted container: %v", p.idle)
+	}
+	if !slices.Contains(fake.removed, extra.id) {
+		t.Errorf("container %s not removed from full pool", extra.id)
+	}
+}
@@ -0,0 +1,166 @@
+// Package migrate applies Prisma migrations. Applied migrations are recorded
+// in Prisma's own _prisma_migrations table, and the same advisory lock as
+// `prisma migrate deploy` is held, so the two can be used interchangeably.
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+)
+
+// prismaLockKey is the advisory lock key used by Prisma's migration engine.
+const prismaLockKey = 72707369
+
+type migration struct {
+	name     string
+	sql      string
+	checksum string
+}
+
+type record struct {
+	checksum string
+	finished bool
+}
+
+// Up applies, in name order, every migration in fsys that is not yet
+// recorded as applied and returns the names of those it applied. fsys holds
+// one directory per migration containing a migration.sql. Each migration
+// runs in its own transaction, so a failed one leaves no trace and is
+// retried on the next start.
+func Up(ctx context.Context, db *sql.DB, fsys fs.FS) ([]string, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, prismaLockKey); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, prismaLockKey)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS "_prisma_migrations" (
+			"id" VARCHAR(36) PRIMARY KEY NOT NULL,
+			"checksum" VARCHAR(64) NOT NULL,
+			"finished_at" TIMESTAMPTZ,
+			"migration_name" VARCHAR(255) NOT NULL,
+			"logs" TEXT,
+			"rolled_back_at" TIMESTAMPTZ,
+			"started_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+			"applied_steps_count" INTEGER NOT NULL DEFAULT 0
+		)
+	`); err != nil {
+		return nil, err
+	}
+	done, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []string
+	for _, m := range migrations {
+		if rec, ok := done[m.name]; ok {
+			if !rec.finished {
+				return applied, fmt.Errorf("migration %s did not finish; resolve it with `prisma migrate resolve` first", m.name)
+			}
+			if rec.checksum != m.checksum {
+				log.Printf("[migrate] %s was modified after it was applied", m.name)
+			}
+			continue
+		}
+		if err := apply(ctx, conn, m); err != nil {
+			return applied, fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		applied = append(applied, m.name)
+	}
+	return applied, nil
+}
+
+func load(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var out []migration
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		body, err := fs.ReadFile(fsys, path.Join(e.Name(), "migration.sql"))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(body)
+		out = append(out, migration{name: e.Name(), sql: string(body), checksum: hex.EncodeToString(sum[:])})
+	}
+	return out, nil
+}
+
+// appliedMigrations ignores rolled-back rows, as Prisma does.
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[string]record, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT "migration_name", "checksum", "finished_at" IS NOT NULL
+		FROM "_prisma_migrations"
+		WHERE "rolled_back_at" IS NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]record{}
+	for rows.Next() {
+		var name string
+		var rec record
+		if err := rows.Scan(&name, &rec.checksum, &rec.finished); err != nil {
+			return nil, err
+		}
+		out[name] = rec
+	}
+	return out, rows.Err()
+}
+
+func apply(ctx context.Context, conn *sql.Conn, m migration) error {
+	id, err := newUUID()
+	if err != nil {
+		return err
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Without arguments the statement goes over the simple protocol, which
+	// allows the several statements a migration file holds.
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO "_prisma_migrations" ("id","checksum","migration_name","started_at","finished_at","applied_steps_count")
+		VALUES ($1,$2,$3,now(),now(),1)
+	`, id, m.checksum, m.name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,46 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"20240102000000_second/migration.sql": {Data: []byte("ALTER TABLE a ADD b INT;")},
+		"20240101000000_first/migration.sql":  {Data: []byte("CREATE TABLE a ();")},
+		"20240101000000_first/README.md":      {Data: []byte("notes")},
+		"migration_lock.toml":                 {Data: []byte(`provider = "postgresql"`)},
+	}
+	got, err := load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ name, sql string }{
+		{"20240101000000_first", "CREATE TABLE a ();"},
+		{"20240102000000_second", "ALTER TABLE a ADD b INT;"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("load returned %d migrations, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		sum := sha256.Sum256([]byte(w.sql))
+		if got[i].name != w.name || got[i].sql != w.sql {
+			t.Errorf("migration %d = %s %q, want %s %q", i, got[i].name, got[i].sql, w.name, w.sql)
+		}
+		if got[i].checksum != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s checksum = %s, want the SHA-256 of migration.sql", w.name, got[i].checksum)
+		}
+	}
+}
+
+func TestLoadMissingMigrationFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"20240101000000_empty/notes.txt": {Data: []byte("")},
+	}
+	if _, err := load(fsys); err == nil {
+		t.Fatal("expected an error for a directory without migration.sql")
+	}
+}
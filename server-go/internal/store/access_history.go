@@ -27,6 +27,8 @@ type AccessHistory struct {
 	IsSensitive bool      `json:"isSensitive"`
 	CreatedAt   time.Time `json:"createdAt"`
 	WebRTCIP    *string   `json:"webrtcIP,omitempty"`
+	// RawIP is the address as observed when it differed from the normalized IP.
+	RawIP *string `json:"rawIp,omitempty"`
 }
 
 type ErrorStats struct {
@@ -74,12 +76,18 @@ type CreateAccessHistoryParams struct {
 	IsSensitive bool
 }
 
-// CreateAccessHistory creates a new access history record
+// CreateAccessHistory creates a new access history record. p.IP is stored
+// normalized; the observed form is kept in "rawIp" when it differs.
 func (s *Store) CreateAccessHistory(ctx context.Context, p CreateAccessHistoryParams) error {
+	ip := NormalizeIP(p.IP)
+	var rawIP *string
+	if ip != p.IP {
+		rawIP = &p.IP
+	}
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO "AccessHistory" ("userId", "ip", "country", "province", "city", "isp", "browser", "os", "device", "userAgent", "accessType", "webrtcIP", "statusCode", "requestPath", "isSensitive")
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-	`, p.UserID, p.IP, p.Country, p.Province, p.City, p.ISP, p.Browser, p.OS, p.Device, p.UserAgent, p.AccessType, p.WebRTCIP, p.StatusCode, p.RequestPath, p.IsSensitive)
+		INSERT INTO "AccessHistory" ("userId", "ip", "country", "province", "city", "isp", "browser", "os", "device", "userAgent", "accessType", "webrtcIP", "statusCode", "requestPath", "isSensitive", "rawIp")
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`, p.UserID, ip, p.Country, p.Province, p.City, p.ISP, p.Browser, p.OS, p.Device, p.UserAgent, p.AccessType, p.WebRTCIP, p.StatusCode, p.RequestPath, p.IsSensitive, rawIP)
 	if err != nil {
 		return err
 	}
@@ -91,7 +99,7 @@ func (s *Store) CreateAccessHistory(ctx context.Context, p CreateAccessHistoryPa
 		ON CONFLICT ("userId", "ip") DO UPDATE SET
 			"lastSeen" = CURRENT_TIMESTAMP,
 			"accessCount" = "UserIPAssociation"."accessCount" + 1
-	`, p.UserID, p.IP)
+	`, p.UserID, ip)
 
 	return err
 }
@@ -100,7 +108,7 @@ func (s *Store) CreateAccessHistory(ctx context.Context, p CreateAccessHistoryPa
 func (s *Store) ListAccessHistory(ctx context.Context, userID *int, limit int) ([]AccessHistory, error) {
 	query := `
 		SELECT h."id", h."userId", u."username", h."ip", h."country", h."province", h."city", 
-		       h."isp", h."browser", h."os", h."device", h."userAgent", h."accessType", h."statusCode", h."requestPath", h."isSensitive", h."createdAt", h."webrtcIP"
+		       h."isp", h."browser", h."os", h."device", h."userAgent", h."accessType", h."statusCode", h."requestPath", h."isSensitive", h."createdAt", h."webrtcIP", h."rawIp"
 		FROM "AccessHistory" h
 		LEFT JOIN "User" u ON h."userId" = u."id"
 	`
@@ -129,10 +137,10 @@ func (s *Store) ListAccessHistory(ctx context.Context, userID *int, limit int) (
 	var records []AccessHistory
 	for rows.Next() {
 		var h AccessHistory
-		var country, province, city, isp, browser, os, device, userAgent, requestPath, webrtcIP sql.NullString
+		var country, province, city, isp, browser, os, device, userAgent, requestPath, webrtcIP, rawIP sql.NullString
 		var statusCode sql.NullInt32
 		if err := rows.Scan(&h.ID, &h.UserID, &h.Username, &h.IP, &country, &province, &city,
-			&isp, &browser, &os, &device, &userAgent, &h.AccessType, &statusCode, &requestPath, &h.IsSensitive, &h.CreatedAt, &webrtcIP); err != nil {
+			&isp, &browser, &os, &device, &userAgent, &h.AccessType, &statusCode, &requestPath, &h.IsSensitive, &h.CreatedAt, &webrtcIP, &rawIP); err != nil {
 			return nil, err
 		}
 		if country.Valid {
@@ -169,6 +177,9 @@ func (s *Store) ListAccessHistory(ctx context.Context, userID *int, limit int) (
 		if webrtcIP.Valid {
 			h.WebRTCIP = &webrtcIP.String
 		}
+		if rawIP.Valid {
+			h.RawIP = &rawIP.String
+		}
 		records = append(records, h)
 	}
 	return records, nil
@@ -177,14 +188,14 @@ func (s *Store) ListAccessHistory(ctx context.Context, userID *int, limit int) (
 func (s *Store) ListAccessHistoryByIP(ctx context.Context, ip string, limit int) ([]AccessHistory, error) {
 	query := `
 		SELECT h."id", h."userId", u."username", h."ip", h."country", h."province", h."city", 
-		       h."isp", h."browser", h."os", h."device", h."userAgent", h."accessType", h."statusCode", h."requestPath", h."isSensitive", h."createdAt", h."webrtcIP"
+		       h."isp", h."browser", h."os", h."device", h."userAgent", h."accessType", h."statusCode", h."requestPath", h."isSensitive", h."createdAt", h."webrtcIP", h."rawIp"
 		FROM "AccessHistory" h
 		LEFT JOIN "User" u ON h."userId" = u."id"
 		WHERE h."ip" = $1
 		ORDER BY h."createdAt" DESC
 		LIMIT $2
 	`
-	rows, err := s.db.QueryContext(ctx, query, ip, limit)
+	rows, err := s.db.QueryContext(ctx, query, NormalizeIP(ip), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -193,10 +204,10 @@ func (s *Store) ListAccessHistoryByIP(ctx context.Context, ip string, limit int)
 	var records []AccessHistory
 	for rows.Next() {
 		var h AccessHistory
-		var country, province, city, isp, browser, os, device, userAgent, requestPath, webrtcIP sql.NullString
+		var country, province, city, isp, browser, os, device, userAgent, requestPath, webrtcIP, rawIP sql.NullString
 		var statusCode sql.NullInt32
 		if err := rows.Scan(&h.ID, &h.UserID, &h.Username, &h.IP, &country, &province, &city,
-			&isp, &browser, &os, &device, &userAgent, &h.AccessType, &statusCode, &requestPath, &h.IsSensitive, &h.CreatedAt, &webrtcIP); err != nil {
+			&isp, &browser, &os, &device, &userAgent, &h.AccessType, &statusCode, &requestPath, &h.IsSensitive, &h.CreatedAt, &webrtcIP, &rawIP); err != nil {
 			return nil, err
 		}
 		if country.Valid {
@@ -233,6 +244,9 @@ func (s *Store) ListAccessHistoryByIP(ctx context.Context, ip string, limit int)
 		if webrtcIP.Valid {
 			h.WebRTCIP = &webrtcIP.String
 		}
+		if rawIP.Valid {
+			h.RawIP = &rawIP.String
+		}
 		records = append(records, h)
 	}
 	return records, nil
@@ -287,62 +301,38 @@ func (s *Store) GetAllIPsForUser(ctx context.Context, userID int) ([]string, err
 	return ips, nil
 }
 
-// BanUserWithAllIPs bans a user and all their associated IPs
+// BanUserWithAllIPs bans a user and all their associated IPs. The IPs are
+// banned by a single INSERT ... SELECT, so users with thousands of IPs cost
+// no more round-trips than users with one.
 func (s *Store) BanUserWithAllIPs(ctx context.Context, userID int, reason string) (int, error) {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return 0, err
-	}
-	defer tx.Rollback()
-
-	// Ban the user
-	now := time.Now()
-	_, err = tx.ExecContext(ctx, `
-		UPDATE "User" SET "isBanned" = true, "bannedAt" = $1, "bannedReason" = $2
-		WHERE "id" = $3
-	`, now, reason, userID)
-	if err != nil {
-		return 0, err
-	}
-
-	// Get all IPs associated with this user
-	rows, err := tx.QueryContext(ctx, `
-		SELECT DISTINCT "ip" FROM "UserIPAssociation" WHERE "userId" = $1
-	`, userID)
-	if err != nil {
-		return 0, err
-	}
-
-	var ips []string
-	for rows.Next() {
-		var ip string
-		if err := rows.Scan(&ip); err != nil {
-			rows.Close()
-			return 0, err
+	bannedCount := 0
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		// Ban the user
+		now := time.Now()
+		_, err := tx.ExecContext(ctx, `
+			UPDATE "User" SET "isBanned" = true, "bannedAt" = $1, "bannedReason" = $2
+			WHERE "id" = $3
+		`, now, reason, userID)
+		if err != nil {
+			return err
 		}
-		ips = append(ips, ip)
-	}
-	rows.Close()
 
-	// Ban all associated IPs
-	bannedCount := 0
-	for _, ip := range ips {
+		// Ban all associated IPs
 		result, err := tx.ExecContext(ctx, `
 			INSERT INTO "BannedIP" ("ip", "userId", "reason")
-			VALUES ($1, $2, $3)
-			ON CONFLICT ("ip") DO UPDATE SET "userId" = $2, "reason" = $3, "createdAt" = CURRENT_TIMESTAMP
-		`, ip, userID, reason)
+			SELECT DISTINCT "ip", $1::int, $2::text FROM "UserIPAssociation" WHERE "userId" = $1
+			ON CONFLICT ("ip") DO UPDATE SET "userId" = EXCLUDED."userId", "reason" = EXCLUDED."reason", "createdAt" = CURRENT_TIMESTAMP
+		`, userID, reason)
 		if err != nil {
-			return 0, err
+			return err
 		}
 		affected, _ := result.RowsAffected()
-		bannedCount += int(affected)
-	}
-
-	if err := tx.Commit(); err != nil {
+		bannedCount = int(affected)
+		return nil
+	})
+	if err != nil {
 		return 0, err
 	}
-
 	return bannedCount, nil
 }
 
@@ -350,7 +340,7 @@ func (s *Store) BanUserWithAllIPs(ctx context.Context, userID int, reason string
 func (s *Store) GetAccessHistoryForUser(ctx context.Context, userID int, limit int) ([]AccessHistory, error) {
 	query := `
 		SELECT h."id", h."userId", u."username", h."ip", h."country", h."province", h."city", 
-		       h."isp", h."browser", h."os", h."device", h."userAgent", h."accessType", h."statusCode", h."requestPath", h."isSensitive", h."createdAt", h."webrtcIP"
+		       h."isp", h."browser", h."os", h."device", h."userAgent", h."accessType", h."statusCode", h."requestPath", h."isSensitive", h."createdAt", h."webrtcIP", h."rawIp"
 		FROM "AccessHistory" h
 		LEFT JOIN "User" u ON h."userId" = u."id"
 		WHERE h."userId" = $1
@@ -367,10 +357,10 @@ func (s *Store) GetAccessHistoryForUser(ctx context.Context, userID int, limit i
 	var records []AccessHistory
 	for rows.Next() {
 		var h AccessHistory
-		var country, province, city, isp, browser, os, device, userAgent, requestPath, webrtcIP sql.NullString
+		var country, province, city, isp, browser, os, device, userAgent, requestPath, webrtcIP, rawIP sql.NullString
 		var statusCode sql.NullInt32
 		if err := rows.Scan(&h.ID, &h.UserID, &h.Username, &h.IP, &country, &province, &city,
-			&isp, &browser, &os, &device, &userAgent, &h.AccessType, &statusCode, &requestPath, &h.IsSensitive, &h.CreatedAt, &webrtcIP); err != nil {
+			&isp, &browser, &os, &device, &userAgent, &h.AccessType, &statusCode, &requestPath, &h.IsSensitive, &h.CreatedAt, &webrtcIP, &rawIP); err != nil {
 			return nil, err
 		}
 		if country.Valid {
@@ -407,6 +397,9 @@ func (s *Store) GetAccessHistoryForUser(ctx context.Context, userID int, limit i
 		if webrtcIP.Valid {
 			h.WebRTCIP = &webrtcIP.String
 		}
+		if rawIP.Valid {
+			h.RawIP = &rawIP.String
+		}
 		records = append(records, h)
 	}
 	return records, nil
@@ -492,11 +485,53 @@ func (s *Store) GetSensitiveAccessReport(ctx context.Context, from, to time.Time
 	return out, nil
 }
 
+type SensitiveIPCount struct {
+	IP       string    `json:"ip"`
+	Count    int       `json:"count"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// ListSensitiveIPCounts returns IPs with at least minCount sensitive-path hits
+// since the given time. Hits by admins are ignored, as are IPs that already
+// carry an unexpired mark, so an admin's decision is never overwritten.
+func (s *Store) ListSensitiveIPCounts(ctx context.Context, since time.Time, minCount int) ([]SensitiveIPCount, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT h."ip", COUNT(*) AS cnt, MAX(h."createdAt")
+		FROM "AccessHistory" h
+		JOIN "User" u ON u."id" = h."userId"
+		WHERE h."isSensitive" = true
+		  AND h."createdAt" >= $1
+		  AND u."role" <> 'ADMIN'
+		  AND NOT EXISTS (
+			SELECT 1 FROM "IPMark" m
+			WHERE m."ipAddress" = h."ip"
+			  AND (m."expireAt" IS NULL OR m."expireAt" > CURRENT_TIMESTAMP)
+		  )
+		GROUP BY h."ip"
+		HAVING COUNT(*) >= $2
+		ORDER BY cnt DESC
+	`, since, minCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SensitiveIPCount
+	for rows.Next() {
+		var c SensitiveIPCount
+		if err := rows.Scan(&c.IP, &c.Count, &c.LastSeen); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
 // GetUserByIP returns user IDs that have used a specific IP
 func (s *Store) GetUsersByIP(ctx context.Context, ip string) ([]int, error) {
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT DISTINCT "userId" FROM "UserIPAssociation" WHERE "ip" = $1
-	`, ip)
+	`, NormalizeIP(ip))
 	if err != nil {
 		return nil, err
 	}
@@ -513,6 +548,31 @@ func (s *Store) GetUsersByIP(ctx context.Context, ip string) ([]int, error) {
 	return userIDs, nil
 }
 
+// GetUnbannedUsersByIP returns the IDs of users that have used ip and are not
+// banned.
+func (s *Store) GetUnbannedUsersByIP(ctx context.Context, ip string) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT a."userId"
+		FROM "UserIPAssociation" a
+		JOIN "User" u ON u."id" = a."userId"
+		WHERE a."ip" = $1 AND u."isBanned" = false
+	`, NormalizeIP(ip))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
 // UnbanIPByID removes a specific IP from the banned list
 func (s *Store) UnbanIPByID(ctx context.Context, id int) error {
 	res, err := s.db.ExecContext(ctx, `DELETE FROM "BannedIP" WHERE "id" = $1`, id)
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+
+	"onlinejudge-server-go/internal/testdb"
+)
+
+// seedUser creates a student who has used the given IPs.
+func seedUser(t *testing.T, db *sql.DB, username string, ips ...string) int {
+	t.Helper()
+	ctx := context.Background()
+	var id int
+	if err := db.QueryRowContext(ctx, `INSERT INTO "User" ("username","password") VALUES ($1,'x') RETURNING "id"`, username).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	for _, ip := range ips {
+		if _, err := db.ExecContext(ctx, `INSERT INTO "UserIPAssociation" ("userId","ip") VALUES ($1,$2)`, id, ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return id
+}
+
+func countBannedIPs(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM "BannedIP"`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestBanUserWithAllIPs(t *testing.T) {
+	db := testdb.Open(t)
+	s := New(db)
+	ctx := context.Background()
+
+	ips := make([]string, 0, 300)
+	for i := 0; i < 300; i++ {
+		ips = append(ips, "10."+strconv.Itoa(i/256)+"."+strconv.Itoa(i%256)+".1")
+	}
+	alice := seedUser(t, db, "alice", ips...)
+	// bob shares one of alice's IPs and has another of his own.
+	bob := seedUser(t, db, "bob", ips[0], "192.0.2.1")
+
+	n, err := s.BanUserWithAllIPs(ctx, alice, "cheating")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(ips) {
+		t.Errorf("banned %d IPs, want %d", n, len(ips))
+	}
+	if got := countBannedIPs(t, db); got != len(ips) {
+		t.Errorf("BannedIP has %d rows, want %d", got, len(ips))
+	}
+	var owners int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM "BannedIP" WHERE "userId" = $1 AND "reason" = 'cheating'`, alice).Scan(&owners); err != nil {
+		t.Fatal(err)
+	}
+	if owners != len(ips) {
+		t.Errorf("%d bans attributed to alice, want %d", owners, len(ips))
+	}
+
+	// Re-running updates the existing bans instead of adding rows.
+	if _, err := s.BanUserWithAllIPs(ctx, alice, "cheating again"); err != nil {
+		t.Fatal(err)
+	}
+	if got := countBannedIPs(t, db); got != len(ips) {
+		t.Errorf("after re-run BannedIP has %d rows, want %d", got, len(ips))
+	}
+
+	// Other users of alice's IPs are neither banned nor lose their own IPs.
+	u, err := s.GetUserByID(ctx, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.IsBanned {
+		t.Error("bob was banned through a shared IP")
+	}
+	if banned, err := s.IsIPBanned(ctx, "192.0.2.1"); err != nil || banned {
+		t.Errorf("bob's own IP banned = %v (err %v), want false", banned, err)
+	}
+	if u, err := s.GetUserByID(ctx, alice); err != nil || !u.IsBanned {
+		t.Errorf("alice banned = %v (err %v), want true", u.IsBanned, err)
+	}
+}
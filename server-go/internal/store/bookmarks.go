@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type BookmarkedProblem struct {
+	ID           int       `json:"id"`
+	Title        string    `json:"title"`
+	Difficulty   string    `json:"difficulty"`
+	Tags         []string  `json:"tags"`
+	Slug         *string   `json:"slug"`
+	BookmarkedAt time.Time `json:"bookmarkedAt"`
+}
+
+// AddProblemBookmark bookmarks a problem for the user. Bookmarking twice is
+// a no-op.
+func (s *Store) AddProblemBookmark(ctx context.Context, userID, problemID int) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO "ProblemBookmark" ("userId","problemId","createdAt")
+		VALUES ($1,$2,NOW())
+		ON CONFLICT ("userId","problemId") DO NOTHING
+	`, userID, problemID)
+	return err
+}
+
+// RemoveProblemBookmark removes the bookmark if present.
+func (s *Store) RemoveProblemBookmark(ctx context.Context, userID, problemID int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM "ProblemBookmark" WHERE "userId"=$1 AND "problemId"=$2`, userID, problemID)
+	return err
+}
+
+// ListProblemBookmarks returns the user's bookmarked problems, newest first.
+// Problems hidden after being bookmarked are skipped when onlyVisible is set.
+func (s *Store) ListProblemBookmarks(ctx context.Context, userID int, onlyVisible bool) ([]BookmarkedProblem, error) {
+	visibleCond := ""
+	if onlyVisible {
+		visibleCond = `AND ` + publicProblemCond("p.")
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p."id", p."title", p."difficulty", p."tags", p."slug", b."createdAt"
+		FROM "ProblemBookmark" b
+		JOIN "Problem" p ON p."id"=b."problemId"
+		WHERE b."userId"=$1
+		  `+visibleCond+`
+		ORDER BY b."createdAt" DESC, p."id" ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []BookmarkedProblem{}
+	for rows.Next() {
+		var bp BookmarkedProblem
+		var tags PGTextArray
+		if err := rows.Scan(&bp.ID, &bp.Title, &bp.Difficulty, &tags, &bp.Slug, &bp.BookmarkedAt); err != nil {
+			return nil, err
+		}
+		bp.Tags = []string(tags)
+		out = append(out, bp)
+	}
+	return out, rows.Err()
+}
+
+// GetBookmarkedProblemIDs returns the set of problem ids the user bookmarked.
+func (s *Store) GetBookmarkedProblemIDs(ctx context.Context, userID int) (map[int]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT "problemId" FROM "ProblemBookmark" WHERE "userId"=$1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int]bool{}
+	for rows.Next() {
+		var pid int
+		if err := rows.Scan(&pid); err != nil {
+			return nil, err
+		}
+		out[pid] = true
+	}
+	return out, rows.Err()
+}
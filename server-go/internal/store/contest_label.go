@@ -0,0 +1,34 @@
+package store
+
+import "strings"
+
+// ContestProblemLabel returns the letter label of a contest problem order:
+// 0 is "A", 25 is "Z", 26 is "AA" and so on.
+func ContestProblemLabel(order int) string {
+	if order < 0 {
+		return ""
+	}
+	var b []byte
+	for n := order + 1; n > 0; n = (n - 1) / 26 {
+		b = append([]byte{byte('A' + (n-1)%26)}, b...)
+	}
+	return string(b)
+}
+
+// ParseContestProblemLabel is the inverse of ContestProblemLabel. Lowercase
+// letters are accepted.
+func ParseContestProblemLabel(label string) (int, bool) {
+	label = strings.ToUpper(strings.TrimSpace(label))
+	if label == "" || len(label) > 3 {
+		return 0, false
+	}
+	n := 0
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		if c < 'A' || c > 'Z' {
+			return 0, false
+		}
+		n = n*26 + int(c-'A') + 1
+	}
+	return n - 1, true
+}
@@ -0,0 +1,50 @@
+package store
+
+import "testing"
+
+func TestParseContestProblemLabel(t *testing.T) {
+	tests := []struct {
+		label string
+		want  int
+		ok    bool
+	}{
+		{"A", 0, true},
+		{"Z", 25, true},
+		{"AA", 26, true},
+		{"AZ", 51, true},
+		{"BA", 52, true},
+		{"ZZ", 701, true},
+		{"AAA", 702, true},
+		{"ZZZ", 18277, true},
+		{"c", 2, true},
+		{" aa ", 26, true},
+
+		{"", 0, false},
+		{"   ", 0, false},
+		{"AAAA", 0, false},
+		{"A1", 0, false},
+		{"1", 0, false},
+		{"@", 0, false},
+		{"[", 0, false},
+		{"A-B", 0, false},
+		{"É", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseContestProblemLabel(tt.label)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseContestProblemLabel(%q) = %d, %v; want %d, %v", tt.label, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestContestProblemLabelRoundTrip(t *testing.T) {
+	for order := 0; order <= 18277; order++ {
+		label := ContestProblemLabel(order)
+		if got, ok := ParseContestProblemLabel(label); !ok || got != order {
+			t.Fatalf("ParseContestProblemLabel(ContestProblemLabel(%d) = %q) = %d, %v", order, label, got, ok)
+		}
+	}
+	if got := ContestProblemLabel(-1); got != "" {
+		t.Errorf("ContestProblemLabel(-1) = %q, want empty", got)
+	}
+}
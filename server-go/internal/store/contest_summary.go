@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+type ContestSummary struct {
+	ContestID        int                     `json:"contestId"`
+	TotalSubmissions int                     `json:"totalSubmissions"`
+	DistinctUsers    int                     `json:"distinctUsers"`
+	StatusCounts     map[string]int          `json:"statusCounts"`
+	LanguageCounts   map[string]int          `json:"languageCounts"`
+	AvgSolveSeconds  *float64                `json:"avgSolveSeconds"`
+	Problems         []ContestProblemSummary `json:"problems"`
+}
+
+type ContestProblemSummary struct {
+	ProblemID       int      `json:"problemId"`
+	Label           string   `json:"label"`
+	Title           string   `json:"title"`
+	Submissions     int      `json:"submissions"`
+	Accepted        int      `json:"accepted"`
+	Solvers         int      `json:"solvers"`
+	AvgSolveSeconds *float64 `json:"avgSolveSeconds"`
+}
+
+// GetContestSummary aggregates every submission made in the contest. Solve
+// time is measured from the contest start to a user's first full-score
+// Accepted submission on a problem. Verdicts are not masked, so this is for
+// admins only.
+func (s *Store) GetContestSummary(ctx context.Context, contestID int) (ContestSummary, error) {
+	sum := ContestSummary{
+		ContestID:      contestID,
+		StatusCounts:   map[string]int{},
+		LanguageCounts: map[string]int{},
+		Problems:       []ContestProblemSummary{},
+	}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(DISTINCT "userId")
+		FROM "Submission"
+		WHERE "contestId"=$1
+	`, contestID).Scan(&sum.TotalSubmissions, &sum.DistinctUsers)
+	if err != nil {
+		return ContestSummary{}, err
+	}
+
+	for _, g := range []struct {
+		col string
+		dst map[string]int
+	}{
+		{`"status"`, sum.StatusCounts},
+		{`"language"`, sum.LanguageCounts},
+	} {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT `+g.col+`, COUNT(*)
+			FROM "Submission"
+			WHERE "contestId"=$1
+			GROUP BY `+g.col, contestID)
+		if err != nil {
+			return ContestSummary{}, err
+		}
+		for rows.Next() {
+			var key string
+			var n int
+			if err := rows.Scan(&key, &n); err != nil {
+				rows.Close()
+				return ContestSummary{}, err
+			}
+			g.dst[key] = n
+		}
+		if err := rows.Close(); err != nil {
+			return ContestSummary{}, err
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		WITH first_ac AS (
+			SELECT s."problemId", EXTRACT(EPOCH FROM MIN(s."createdAt") - c."startTime") AS "secs"
+			FROM "Submission" s
+			JOIN "Contest" c ON c."id"=s."contestId"
+			WHERE s."contestId"=$1 AND s."status"='Accepted' AND COALESCE(s."score",0)>=100
+			GROUP BY s."userId", s."problemId", c."startTime"
+		)
+		SELECT cp."problemId", cp."order", p."title",
+		       COALESCE(sub."total",0), COALESCE(sub."accepted",0),
+		       COALESCE(fa."solvers",0), fa."avgSecs"
+		FROM "ContestProblem" cp
+		JOIN "Problem" p ON p."id"=cp."problemId"
+		LEFT JOIN (
+			SELECT "problemId", COUNT(*) AS "total", COUNT(*) FILTER (WHERE "status"='Accepted') AS "accepted"
+			FROM "Submission"
+			WHERE "contestId"=$1
+			GROUP BY "problemId"
+		) sub ON sub."problemId"=cp."problemId"
+		LEFT JOIN (
+			SELECT "problemId", COUNT(*) AS "solvers", AVG("secs") AS "avgSecs"
+			FROM first_ac
+			GROUP BY "problemId"
+		) fa ON fa."problemId"=cp."problemId"
+		WHERE cp."contestId"=$1
+		ORDER BY cp."order" ASC
+	`, contestID)
+	if err != nil {
+		return ContestSummary{}, err
+	}
+	defer rows.Close()
+
+	var totalSecs float64
+	var totalSolves int
+	for rows.Next() {
+		var ps ContestProblemSummary
+		var order int
+		var avg sql.NullFloat64
+		if err := rows.Scan(&ps.ProblemID, &order, &ps.Title, &ps.Submissions, &ps.Accepted, &ps.Solvers, &avg); err != nil {
+			return ContestSummary{}, err
+		}
+		ps.Label = ContestProblemLabel(order)
+		if avg.Valid {
+			v := avg.Float64
+			ps.AvgSolveSeconds = &v
+			totalSecs += v * float64(ps.Solvers)
+			totalSolves += ps.Solvers
+		}
+		sum.Problems = append(sum.Problems, ps)
+	}
+	if err := rows.Err(); err != nil {
+		return ContestSummary{}, err
+	}
+	if totalSolves > 0 {
+		v := totalSecs / float64(totalSolves)
+		sum.AvgSolveSeconds = &v
+	}
+	return sum, nil
+}
@@ -18,15 +18,24 @@ type Contest struct {
 	PasswordHash *string   `json:"passwordHash"`
 	IsPublished  bool      `json:"isPublished"`
 	Languages    []string  `json:"languages"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	// SubmissionRateLimit overrides the global per-minute submission limit
+	// for submissions to this contest; nil uses the global limit.
+	SubmissionRateLimit *int `json:"submissionRateLimit"`
+	// ScoringMode is one of ContestScoringModes.
+	ScoringMode string `json:"scoringMode"`
+	// WrongSubmissionPenalty is deducted from an OI problem score for each
+	// rejected submission before the first Accepted one; 0 disables it.
+	WrongSubmissionPenalty int       `json:"wrongSubmissionPenalty"`
+	CreatedAt              time.Time `json:"createdAt"`
+	UpdatedAt              time.Time `json:"updatedAt"`
 }
 
 type ContestProblem struct {
-	ID        int `json:"id"`
-	Order     int `json:"order"`
-	ContestID int `json:"contestId"`
-	ProblemID int `json:"problemId"`
+	ID        int    `json:"id"`
+	Order     int    `json:"order"`
+	Label     string `json:"label"`
+	ContestID int    `json:"contestId"`
+	ProblemID int    `json:"problemId"`
 	Problem   struct {
 		ID         int    `json:"id"`
 		Title      string `json:"title"`
@@ -80,6 +89,8 @@ type ContestPublicDetail struct {
 	HasPassword      bool      `json:"hasPassword"`
 	Problems         []struct {
 		ID         int    `json:"id"`
+		Order      int    `json:"order"`
+		Label      string `json:"label"`
 		Title      string `json:"title"`
 		Difficulty string `json:"difficulty"`
 	} `json:"problems"`
@@ -116,50 +127,51 @@ type CreateContestParams struct {
 	IsPublished  bool
 	Languages    []string
 	ProblemIDs   []int
+	CreatedBy    *int
+	// SubmissionRateLimit is nil to use the global limit.
+	SubmissionRateLimit    *int
+	ScoringMode            string
+	WrongSubmissionPenalty int
 }
 
 func (s *Store) CreateContest(ctx context.Context, p CreateContestParams) (int, error) {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return 0, err
-	}
-	defer tx.Rollback()
-
 	var created Contest
-	var desc sql.NullString
-	if strings.TrimSpace(p.Description) != "" {
-		desc = sql.NullString{String: p.Description, Valid: true}
-	}
-	var password sql.NullString
-	if p.PasswordHash != nil && strings.TrimSpace(*p.PasswordHash) != "" {
-		password = sql.NullString{String: *p.PasswordHash, Valid: true}
-	}
-	var languages PGTextArray
-
-	err = tx.QueryRowContext(ctx, `
-		INSERT INTO "Contest" ("name","description","startTime","endTime","rule","passwordHash","isPublished","languages")
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
-		RETURNING "id","name","description","startTime","endTime","rule","passwordHash","isPublished","languages","createdAt","updatedAt"
-	`, p.Name, desc, p.StartTime, p.EndTime, p.Rule, password, p.IsPublished, p.Languages).
-		Scan(&created.ID, &created.Name, &created.Description, &created.StartTime, &created.EndTime, &created.Rule, &created.PasswordHash, &created.IsPublished, &languages, &created.CreatedAt, &created.UpdatedAt)
-	if err != nil {
-		return 0, err
-	}
-	created.Languages = []string(languages)
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var desc sql.NullString
+		if strings.TrimSpace(p.Description) != "" {
+			desc = sql.NullString{String: p.Description, Valid: true}
+		}
+		var password sql.NullString
+		if p.PasswordHash != nil && strings.TrimSpace(*p.PasswordHash) != "" {
+			password = sql.NullString{String: *p.PasswordHash, Valid: true}
+		}
+		var languages PGTextArray
 
-	if len(p.ProblemIDs) > 0 {
-		existing, err := fetchExistingProblemIDs(ctx, tx, p.ProblemIDs)
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO "Contest" ("name","description","startTime","endTime","rule","passwordHash","isPublished","languages","createdBy","updatedBy","submissionRateLimit","scoringMode","wrongSubmissionPenalty")
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$9,$10,$11,$12)
+			RETURNING "id","name","description","startTime","endTime","rule","passwordHash","isPublished","languages","submissionRateLimit","scoringMode","wrongSubmissionPenalty","createdAt","updatedAt"
+		`, p.Name, desc, p.StartTime, p.EndTime, p.Rule, password, p.IsPublished, p.Languages, p.CreatedBy, p.SubmissionRateLimit, p.ScoringMode, p.WrongSubmissionPenalty).
+			Scan(&created.ID, &created.Name, &created.Description, &created.StartTime, &created.EndTime, &created.Rule, &created.PasswordHash, &created.IsPublished, &languages, &created.SubmissionRateLimit, &created.ScoringMode, &created.WrongSubmissionPenalty, &created.CreatedAt, &created.UpdatedAt)
 		if err != nil {
-			return 0, err
+			return err
 		}
-		if len(existing) > 0 {
-			if err := replaceContestProblems(ctx, tx, created.ID, p.ProblemIDs, existing); err != nil {
-				return 0, err
+		created.Languages = []string(languages)
+
+		if len(p.ProblemIDs) > 0 {
+			existing, err := fetchExistingProblemIDs(ctx, tx, p.ProblemIDs)
+			if err != nil {
+				return err
+			}
+			if len(existing) > 0 {
+				if err := replaceContestProblems(ctx, tx, created.ID, p.ProblemIDs, existing); err != nil {
+					return err
+				}
 			}
 		}
-	}
-
-	if err := tx.Commit(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return 0, err
 	}
 	return created.ID, nil
@@ -178,15 +190,18 @@ type UpdateContestParams struct {
 	PasswordHash   *string
 	UpdateProblems bool
 	ProblemIDs     []int
+	UpdatedBy      *int
+	// UpdateRateLimit sets SubmissionRateLimit, where nil restores the
+	// global limit.
+	UpdateRateLimit     bool
+	SubmissionRateLimit *int
+	// ScoringMode is left unchanged when empty and WrongSubmissionPenalty
+	// when nil.
+	ScoringMode            string
+	WrongSubmissionPenalty *int
 }
 
 func (s *Store) UpdateContest(ctx context.Context, p UpdateContestParams) error {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
 	setParts := []string{`"name"=$1`, `"description"=$2`, `"startTime"=$3`, `"endTime"=$4`, `"rule"=$5`, `"languages"=$6`}
 	args := []any{}
 
@@ -211,47 +226,67 @@ func (s *Store) UpdateContest(ctx context.Context, p UpdateContestParams) error
 		args = append(args, password)
 		arg++
 	}
+	if p.UpdateRateLimit {
+		setParts = append(setParts, `"submissionRateLimit"=$`+itoa(arg))
+		args = append(args, p.SubmissionRateLimit)
+		arg++
+	}
+	if p.ScoringMode != "" {
+		setParts = append(setParts, `"scoringMode"=$`+itoa(arg))
+		args = append(args, p.ScoringMode)
+		arg++
+	}
+	if p.WrongSubmissionPenalty != nil {
+		setParts = append(setParts, `"wrongSubmissionPenalty"=$`+itoa(arg))
+		args = append(args, *p.WrongSubmissionPenalty)
+		arg++
+	}
+
+	setParts = append(setParts, `"updatedBy"=$`+itoa(arg))
+	args = append(args, p.UpdatedBy)
+	arg++
 
 	args = append(args, p.ID)
 
 	setParts = append(setParts, `"updatedAt"=NOW()`)
-	res, err := tx.ExecContext(ctx, `UPDATE "Contest" SET `+strings.Join(setParts, ",")+` WHERE "id"=$`+itoa(len(args)), args...)
-	if err != nil {
-		return err
-	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		return ErrNotFound
-	}
-
-	if p.UpdateProblems {
-		if _, err := tx.ExecContext(ctx, `DELETE FROM "ContestProblem" WHERE "contestId"=$1`, p.ID); err != nil {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE "Contest" SET `+strings.Join(setParts, ",")+` WHERE "id"=$`+itoa(len(args)), args...)
+		if err != nil {
 			return err
 		}
-		if len(p.ProblemIDs) > 0 {
-			existing, err := fetchExistingProblemIDs(ctx, tx, p.ProblemIDs)
-			if err != nil {
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			return ErrNotFound
+		}
+
+		if p.UpdateProblems {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM "ContestProblem" WHERE "contestId"=$1`, p.ID); err != nil {
 				return err
 			}
-			if len(existing) > 0 {
-				if err := insertContestProblems(ctx, tx, p.ID, p.ProblemIDs, existing); err != nil {
+			if len(p.ProblemIDs) > 0 {
+				existing, err := fetchExistingProblemIDs(ctx, tx, p.ProblemIDs)
+				if err != nil {
 					return err
 				}
+				if len(existing) > 0 {
+					if err := insertContestProblems(ctx, tx, p.ID, p.ProblemIDs, existing); err != nil {
+						return err
+					}
+				}
 			}
 		}
-	}
-
-	return tx.Commit()
+		return nil
+	})
 }
 
 func (s *Store) GetContestByID(ctx context.Context, id int) (Contest, error) {
 	var c Contest
 	var languages PGTextArray
 	err := s.db.QueryRowContext(ctx, `
-		SELECT "id","name","description","startTime","endTime","rule","passwordHash","isPublished","languages","createdAt","updatedAt"
+		SELECT "id","name","description","startTime","endTime","rule","passwordHash","isPublished","languages","submissionRateLimit","scoringMode","wrongSubmissionPenalty","createdAt","updatedAt"
 		FROM "Contest"
 		WHERE "id"=$1
-	`, id).Scan(&c.ID, &c.Name, &c.Description, &c.StartTime, &c.EndTime, &c.Rule, &c.PasswordHash, &c.IsPublished, &languages, &c.CreatedAt, &c.UpdatedAt)
+	`, id).Scan(&c.ID, &c.Name, &c.Description, &c.StartTime, &c.EndTime, &c.Rule, &c.PasswordHash, &c.IsPublished, &languages, &c.SubmissionRateLimit, &c.ScoringMode, &c.WrongSubmissionPenalty, &c.CreatedAt, &c.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Contest{}, ErrNotFound
@@ -286,6 +321,7 @@ func (s *Store) GetContestAdmin(ctx context.Context, id int) (ContestAdminDetail
 		if err := rows.Scan(&cp.ID, &cp.Order, &cp.ContestID, &cp.ProblemID, &cp.Problem.ID, &cp.Problem.Title, &cp.Problem.Difficulty); err != nil {
 			return ContestAdminDetail{}, err
 		}
+		cp.Label = ContestProblemLabel(cp.Order)
 		problems = append(problems, cp)
 	}
 	if err := rows.Err(); err != nil {
@@ -295,6 +331,10 @@ func (s *Store) GetContestAdmin(ctx context.Context, id int) (ContestAdminDetail
 	return ContestAdminDetail{Contest: c, Problems: problems}, nil
 }
 
+func (s *Store) GetContestEditors(ctx context.Context, id int) (Editors, error) {
+	return s.getEditors(ctx, "Contest", id)
+}
+
 func (s *Store) ListContestsAdmin(ctx context.Context) ([]ContestAdminListItem, error) {
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT c."id",c."name",c."description",c."startTime",c."endTime",c."rule",c."isPublished",c."languages",
@@ -486,7 +526,7 @@ func (s *Store) GetContestWithProblemsPublic(ctx context.Context, id int) (Conte
 	contest.HasPassword = hasPassword
 
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT p."id",p."title",p."difficulty"
+		SELECT p."id",cp."order",p."title",p."difficulty"
 		FROM "ContestProblem" cp
 		JOIN "Problem" p ON p."id"=cp."problemId"
 		WHERE cp."contestId"=$1 AND p."visible"=true
@@ -500,12 +540,15 @@ func (s *Store) GetContestWithProblemsPublic(ctx context.Context, id int) (Conte
 	for rows.Next() {
 		var item struct {
 			ID         int    `json:"id"`
+			Order      int    `json:"order"`
+			Label      string `json:"label"`
 			Title      string `json:"title"`
 			Difficulty string `json:"difficulty"`
 		}
-		if err := rows.Scan(&item.ID, &item.Title, &item.Difficulty); err != nil {
+		if err := rows.Scan(&item.ID, &item.Order, &item.Title, &item.Difficulty); err != nil {
 			return ContestPublicDetail{}, err
 		}
+		item.Label = ContestProblemLabel(item.Order)
 		contest.Problems = append(contest.Problems, item)
 	}
 	if err := rows.Err(); err != nil {
@@ -529,6 +572,54 @@ func (s *Store) UpsertContestParticipant(ctx context.Context, contestID int, use
 	return err
 }
 
+type ContestParticipant struct {
+	ID              int    `json:"id"`
+	ContestID       int    `json:"contestId"`
+	UserID          int    `json:"userId"`
+	Username        string `json:"username"`
+	Disqualified    bool   `json:"disqualified"`
+	SubmissionCount int    `json:"submissionCount"`
+}
+
+func (s *Store) ListContestParticipants(ctx context.Context, contestID int) ([]ContestParticipant, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT cp."id",cp."contestId",cp."userId",u."username",cp."disqualified",
+		       (SELECT COUNT(*) FROM "Submission" s WHERE s."contestId"=cp."contestId" AND s."userId"=cp."userId") as "submissionCount"
+		FROM "ContestParticipant" cp
+		JOIN "User" u ON u."id"=cp."userId"
+		WHERE cp."contestId"=$1
+		ORDER BY u."username" ASC
+	`, contestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ContestParticipant
+	for rows.Next() {
+		var p ContestParticipant
+		if err := rows.Scan(&p.ID, &p.ContestID, &p.UserID, &p.Username, &p.Disqualified, &p.SubmissionCount); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) SetContestParticipantDisqualified(ctx context.Context, contestID int, userID int, disqualified bool) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE "ContestParticipant" SET "disqualified"=$1
+		WHERE "contestId"=$2 AND "userId"=$3
+	`, disqualified, contestID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 type ContestPasswordAttempt struct {
 	FailedCount  int        `json:"failedCount"`
 	LastFailedAt *time.Time `json:"lastFailedAt"`
@@ -769,7 +860,10 @@ func listContestProblemsSimple(ctx context.Context, db *sql.DB, contestIDs []int
 	return out, rows.Err()
 }
 
-func (s *Store) ListContestLeaderboard(ctx context.Context, contestID int) ([]ContestLeaderboardItem, error) {
+func (s *Store) ListContestLeaderboard(ctx context.Context, contestID int) (_ []ContestLeaderboardItem, err error) {
+	ctx, cancel := withAggregateTimeout(ctx)
+	defer cancel()
+	defer func() { err = aggregateTimeoutError(ctx, err) }()
 	rows, err := s.db.QueryContext(ctx, `
 		WITH user_problem_max AS (
 			SELECT s."userId" AS "userId", s."problemId" AS "problemId", MAX(COALESCE(s."score",0)) AS "maxScore"
@@ -786,6 +880,10 @@ func (s *Store) ListContestLeaderboard(ctx context.Context, contestID int) ([]Co
 			SELECT s."userId" AS "userId", COUNT(*) AS "submissionCount"
 			FROM "Submission" s
 			WHERE s."contestId"=$1
+			  AND NOT EXISTS (
+			    SELECT 1 FROM "ContestParticipant" cp
+			    WHERE cp."contestId"=s."contestId" AND cp."userId"=s."userId" AND cp."disqualified"=true
+			  )
 			GROUP BY s."userId"
 		)
 		SELECT u."id",u."username",COALESCE(uc."submissionCount",0),COALESCE(ut."totalScore",0)
@@ -810,7 +908,41 @@ func (s *Store) ListContestLeaderboard(ctx context.Context, contestID int) ([]Co
 	return out, rows.Err()
 }
 
-func (s *Store) ListContestLeaderboardPaged(ctx context.Context, contestID int, contestRule string, page int, pageSize int, sortBy string, asc bool) ([]ContestLeaderboardItem, int, error) {
+// Contest scoring modes. The default scores each problem by the rule: the
+// last submission for OI, the best one otherwise. FIRST_AC applies the same
+// rule but ignores every submission after a user's first Accepted one on
+// the problem, so resubmitting after AC cannot change the score.
+const (
+	ContestScoringDefault = "DEFAULT"
+	ContestScoringFirstAC = "FIRST_AC"
+)
+
+// ContestScoringModes lists the accepted Contest.ScoringMode values.
+var ContestScoringModes = []string{ContestScoringDefault, ContestScoringFirstAC}
+
+// scoreBeforeFirstACCond matches submissions s made no later than the
+// user's first Accepted submission on the same problem.
+const scoreBeforeFirstACCond = `NOT EXISTS (
+	SELECT 1 FROM "Submission" e
+	WHERE e."contestId"=s."contestId" AND e."userId"=s."userId" AND e."problemId"=s."problemId"
+	  AND e."status"='Accepted' AND (e."createdAt", e."id") < (s."createdAt", s."id")
+)`
+
+// rejectedBeforeFirstACCond matches rejected submissions s made before the
+// user's first Accepted submission on the same problem. Compile and system
+// errors are not counted as rejections.
+const rejectedBeforeFirstACCond = `s."status" NOT IN ('Accepted','Pending','Judging','Compilation Error','System Error')
+	AND ` + scoreBeforeFirstACCond + ` AND EXISTS (
+	SELECT 1 FROM "Submission" e
+	WHERE e."contestId"=s."contestId" AND e."userId"=s."userId" AND e."problemId"=s."problemId"
+	  AND e."status"='Accepted' AND (e."createdAt", e."id") > (s."createdAt", s."id")
+)`
+
+func (s *Store) ListContestLeaderboardPaged(ctx context.Context, contest Contest, page int, pageSize int, sortBy string, asc bool) (_ []ContestLeaderboardItem, _ int, err error) {
+	ctx, cancel := withAggregateTimeout(ctx)
+	defer cancel()
+	defer func() { err = aggregateTimeoutError(ctx, err) }()
+	contestID := contest.ID
 	if page <= 0 {
 		page = 1
 	}
@@ -829,63 +961,51 @@ func (s *Store) ListContestLeaderboardPaged(ctx context.Context, contestID int,
 		orderKey = `COALESCE(uc."submissionCount",0)`
 	}
 
-	query := ""
-	useLast := strings.EqualFold(contestRule, "OI")
-	if useLast {
-		query = `
-			WITH user_problem_last AS (
-				SELECT s."userId" AS "userId", s."problemId" AS "problemId",
-				       (ARRAY_AGG(COALESCE(s."score",0) ORDER BY s."createdAt" DESC, s."id" DESC))[1] AS "lastScore"
-				FROM "Submission" s
-				WHERE s."contestId"=$1
-				GROUP BY s."userId", s."problemId"
-			),
-			user_totals AS (
-				SELECT "userId", SUM("lastScore") AS "totalScore"
-				FROM user_problem_last
-				GROUP BY "userId"
-			),
-			user_counts AS (
-				SELECT s."userId" AS "userId", COUNT(*) AS "submissionCount"
-				FROM "Submission" s
-				WHERE s."contestId"=$1
-				GROUP BY s."userId"
-			)
-			SELECT u."id",u."username",COALESCE(uc."submissionCount",0),COALESCE(ut."totalScore",0)
-			FROM "User" u
-			JOIN user_counts uc ON uc."userId"=u."id"
-			LEFT JOIN user_totals ut ON ut."userId"=u."id"
-			ORDER BY ` + orderKey + ` ` + orderDir + `, u."username" ASC
-			LIMIT $2 OFFSET $3
-		`
-	} else {
-		query = `
-			WITH user_problem_max AS (
-				SELECT s."userId" AS "userId", s."problemId" AS "problemId", MAX(COALESCE(s."score",0)) AS "maxScore"
-				FROM "Submission" s
-				WHERE s."contestId"=$1
-				GROUP BY s."userId", s."problemId"
-			),
-			user_totals AS (
-				SELECT "userId", SUM("maxScore") AS "totalScore"
-				FROM user_problem_max
-				GROUP BY "userId"
-			),
-			user_counts AS (
-				SELECT s."userId" AS "userId", COUNT(*) AS "submissionCount"
-				FROM "Submission" s
-				WHERE s."contestId"=$1
-				GROUP BY s."userId"
-			)
-			SELECT u."id",u."username",COALESCE(uc."submissionCount",0),COALESCE(ut."totalScore",0)
-			FROM "User" u
-			JOIN user_counts uc ON uc."userId"=u."id"
-			LEFT JOIN user_totals ut ON ut."userId"=u."id"
-			ORDER BY ` + orderKey + ` ` + orderDir + `, u."username" ASC
-			LIMIT $2 OFFSET $3
-		`
+	// scoreAgg picks the score of one user on one problem from their
+	// submissions s.
+	filter := ""
+	if contest.ScoringMode == ContestScoringFirstAC {
+		filter = ` FILTER (WHERE ` + scoreBeforeFirstACCond + `)`
+	}
+	scoreAgg := `MAX(COALESCE(s."score",0))` + filter
+	if strings.EqualFold(contest.Rule, "OI") {
+		scoreAgg = `(ARRAY_AGG(COALESCE(s."score",0) ORDER BY s."createdAt" DESC, s."id" DESC)` + filter + `)[1]`
+		if contest.WrongSubmissionPenalty > 0 {
+			scoreAgg = `GREATEST(` + scoreAgg + ` - ` + itoa(contest.WrongSubmissionPenalty) +
+				` * COUNT(*) FILTER (WHERE ` + rejectedBeforeFirstACCond + `), 0)`
+		}
 	}
 
+	query := `
+		WITH user_problem_score AS (
+			SELECT s."userId" AS "userId", s."problemId" AS "problemId", ` + scoreAgg + ` AS "score"
+			FROM "Submission" s
+			WHERE s."contestId"=$1
+			GROUP BY s."userId", s."problemId"
+		),
+		user_totals AS (
+			SELECT "userId", SUM("score") AS "totalScore"
+			FROM user_problem_score
+			GROUP BY "userId"
+		),
+		user_counts AS (
+			SELECT s."userId" AS "userId", COUNT(*) AS "submissionCount"
+			FROM "Submission" s
+			WHERE s."contestId"=$1
+			  AND NOT EXISTS (
+			    SELECT 1 FROM "ContestParticipant" cp
+			    WHERE cp."contestId"=s."contestId" AND cp."userId"=s."userId" AND cp."disqualified"=true
+			  )
+			GROUP BY s."userId"
+		)
+		SELECT u."id",u."username",COALESCE(uc."submissionCount",0),COALESCE(ut."totalScore",0)
+		FROM "User" u
+		JOIN user_counts uc ON uc."userId"=u."id"
+		LEFT JOIN user_totals ut ON ut."userId"=u."id"
+		ORDER BY ` + orderKey + ` ` + orderDir + `, u."username" ASC
+		LIMIT $2 OFFSET $3
+	`
+
 	rows, err := s.db.QueryContext(ctx, query, contestID, pageSize, (page-1)*pageSize)
 	if err != nil {
 		return nil, 0, err
@@ -911,6 +1031,10 @@ func (s *Store) ListContestLeaderboardPaged(ctx context.Context, contestID int,
 			SELECT s."userId" AS "userId"
 			FROM "Submission" s
 			WHERE s."contestId"=$1
+			  AND NOT EXISTS (
+			    SELECT 1 FROM "ContestParticipant" cp
+			    WHERE cp."contestId"=s."contestId" AND cp."userId"=s."userId" AND cp."disqualified"=true
+			  )
 			GROUP BY s."userId"
 		) t
 	`, contestID).Scan(&total); err != nil {
@@ -920,24 +1044,12 @@ func (s *Store) ListContestLeaderboardPaged(ctx context.Context, contestID int,
 		return out, total, nil
 	}
 
-	statsQuery := ""
-	if useLast {
-		statsQuery = `
-			SELECT s."userId", s."problemId",
-			       (ARRAY_AGG(COALESCE(s."score",0) ORDER BY s."createdAt" DESC, s."id" DESC))[1] AS "lastScore",
-			       COUNT(*) AS "submissionCount"
-			FROM "Submission" s
-			WHERE s."contestId"=$1 AND s."userId"=ANY($2)
-			GROUP BY s."userId", s."problemId"
-		`
-	} else {
-		statsQuery = `
-			SELECT s."userId", s."problemId", MAX(COALESCE(s."score",0)) AS "maxScore", COUNT(*) AS "submissionCount"
-			FROM "Submission" s
-			WHERE s."contestId"=$1 AND s."userId"=ANY($2)
-			GROUP BY s."userId", s."problemId"
-		`
-	}
+	statsQuery := `
+		SELECT s."userId", s."problemId", ` + scoreAgg + ` AS "score", COUNT(*) AS "submissionCount"
+		FROM "Submission" s
+		WHERE s."contestId"=$1 AND s."userId"=ANY($2)
+		GROUP BY s."userId", s."problemId"
+	`
 
 	statsRows, err := s.db.QueryContext(ctx, statsQuery, contestID, userIDs)
 	if err != nil {
@@ -961,7 +1073,10 @@ func (s *Store) ListContestLeaderboardPaged(ctx context.Context, contestID int,
 	}
 	return out, total, statsRows.Err()
 }
-func (s *Store) ListContestUserProblemStats(ctx context.Context, contestID int) ([]ContestUserProblemStat, error) {
+func (s *Store) ListContestUserProblemStats(ctx context.Context, contestID int) (_ []ContestUserProblemStat, err error) {
+	ctx, cancel := withAggregateTimeout(ctx)
+	defer cancel()
+	defer func() { err = aggregateTimeoutError(ctx, err) }()
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT u."id",u."username",s."problemId",
 		       MAX(COALESCE(s."score",0)) as "maxScore",
@@ -1023,6 +1138,15 @@ func (s *Store) ListContestProblemsSimple(ctx context.Context, contestID int) ([
 	return out, rows.Err()
 }
 
+// ContestHasProblem reports whether problemID is one of the contest's problems.
+func (s *Store) ContestHasProblem(ctx context.Context, contestID, problemID int) (bool, error) {
+	var ok bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM "ContestProblem" WHERE "contestId"=$1 AND "problemId"=$2)
+	`, contestID, problemID).Scan(&ok)
+	return ok, err
+}
+
 func (s *Store) GetContestProblemIDByOrder(ctx context.Context, contestID int, order int) (int, error) {
 	var pid int
 	err := s.db.QueryRowContext(ctx, `
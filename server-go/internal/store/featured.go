@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type FeaturedSolution struct {
+	ProblemID    int       `json:"problemId"`
+	ProblemTitle string    `json:"problemTitle"`
+	SubmissionID int       `json:"submissionId"`
+	Language     string    `json:"language"`
+	Code         string    `json:"code,omitempty"`
+	TimeUsed     *int      `json:"timeUsed"`
+	MemoryUsed   *int      `json:"memoryUsed"`
+	FeaturedAt   time.Time `json:"featuredAt"`
+}
+
+// SetFeaturedSolution features submissionID as userID's solution to its
+// problem, replacing any earlier choice for that problem. Only submissions
+// of the user that count as solved qualify; anything else, including a
+// missing submission, yields ErrNotAccepted.
+func (s *Store) SetFeaturedSolution(ctx context.Context, userID, submissionID int) error {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO "FeaturedSolution" ("userId","problemId","submissionId","createdAt")
+		SELECT s."userId", s."problemId", s."id", NOW()
+		FROM "Submission" s
+		LEFT JOIN "Contest" c ON c."id"=s."contestId"
+		WHERE s."id"=$1 AND s."userId"=$2
+		  AND `+solvedSubmissionCond+`
+		ON CONFLICT ("userId","problemId") DO UPDATE SET "submissionId"=EXCLUDED."submissionId","createdAt"=NOW()
+	`, submissionID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotAccepted
+	}
+	return nil
+}
+
+// UnsetFeaturedSolution stops featuring submissionID if userID featured it.
+func (s *Store) UnsetFeaturedSolution(ctx context.Context, userID, submissionID int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM "FeaturedSolution" WHERE "userId"=$1 AND "submissionId"=$2`, userID, submissionID)
+	return err
+}
+
+// ListFeaturedSolutions returns the user's featured solutions ordered by
+// problem, including code.
+func (s *Store) ListFeaturedSolutions(ctx context.Context, userID int, onlyVisible bool) ([]FeaturedSolution, error) {
+	visibleCond := ""
+	if onlyVisible {
+		visibleCond = `AND ` + publicProblemCond("p.")
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p."id", p."title", s."id", s."language", s."code", s."timeUsed", s."memoryUsed", f."createdAt"
+		FROM "FeaturedSolution" f
+		JOIN "Submission" s ON s."id"=f."submissionId"
+		JOIN "Problem" p ON p."id"=f."problemId"
+		WHERE f."userId"=$1
+		  `+visibleCond+`
+		ORDER BY p."id" ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []FeaturedSolution{}
+	for rows.Next() {
+		var fs FeaturedSolution
+		if err := rows.Scan(&fs.ProblemID, &fs.ProblemTitle, &fs.SubmissionID, &fs.Language, &fs.Code, &fs.TimeUsed, &fs.MemoryUsed, &fs.FeaturedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, fs)
+	}
+	return out, rows.Err()
+}
@@ -2,8 +2,11 @@ package store
 
 import (
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 func itoa(n int) string {
@@ -28,6 +31,46 @@ func itoa(n int) string {
 	return string(buf[i:])
 }
 
+// Postgres error codes (SQLSTATE) that ClassifyError recognises.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+	pgDataExceptionClass  = "22" // value too long, out of range, bad encoding...
+)
+
+// uniqueViolation maps a Postgres unique constraint failure to
+// ErrUniqueViolation and returns any other error unchanged.
+func uniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return ErrUniqueViolation
+	}
+	return err
+}
+
+// ClassifyError maps Postgres failures caused by the values written, rather
+// than by the database itself, to ErrUniqueViolation,
+// ErrForeignKeyViolation, ErrCheckViolation or ErrInvalidValue. Any other
+// error is returned unchanged.
+func ClassifyError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch {
+	case pgErr.Code == pgUniqueViolation:
+		return ErrUniqueViolation
+	case pgErr.Code == pgForeignKeyViolation:
+		return ErrForeignKeyViolation
+	case pgErr.Code == pgCheckViolation:
+		return ErrCheckViolation
+	case strings.HasPrefix(pgErr.Code, pgDataExceptionClass):
+		return ErrInvalidValue
+	}
+	return err
+}
+
 func tryAtoi(s string) (int, bool) {
 	s = strings.TrimSpace(s)
 	if s == "" {
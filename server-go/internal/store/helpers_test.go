@@ -0,0 +1,42 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestClassifyError(t *testing.T) {
+	pg := func(code string) error { return &pgconn.PgError{Code: code, Message: "test"} }
+	other := errors.New("connection reset")
+	notNull := pg("23502")
+	serialization := pg("40001")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"unique", pg("23505"), ErrUniqueViolation},
+		{"foreign key", pg("23503"), ErrForeignKeyViolation},
+		{"check", pg("23514"), ErrCheckViolation},
+		{"invalid text representation", pg("22P02"), ErrInvalidValue},
+		{"numeric out of range", pg("22003"), ErrInvalidValue},
+		{"wrapped", fmt.Errorf("insert: %w", pg("23505")), ErrUniqueViolation},
+		{"other integrity error", notNull, notNull},
+		{"other postgres error", serialization, serialization},
+		{"not a postgres error", other, other},
+		{"no rows", sql.ErrNoRows, sql.ErrNoRows},
+		{"nil", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package store
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// NormalizeIP returns the canonical text form of an address so that
+// equivalent spellings (IPv4-mapped, expanded or bracketed IPv6) key the same
+// ban, mark and association rows. Values that are not addresses are returned
+// trimmed but otherwise unchanged.
+func NormalizeIP(ip string) string {
+	ip = strings.TrimSpace(ip)
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(ip, "["), "]"))
+	if err != nil {
+		return ip
+	}
+	return addr.Unmap().WithZone("").String()
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 )
 
@@ -13,8 +14,12 @@ type IPMark struct {
 	CreatedAt time.Time `json:"createdAt"`
 	ExpireAt  *time.Time `json:"expireAt,omitempty"`
 	Operator  *string   `json:"operator,omitempty"`
+	// SuspiciousCount is how many times the IP has been marked SUSPICIOUS
+	// within the retained hit history.
+	SuspiciousCount int `json:"suspiciousCount"`
 }
 
+// UpsertIPMark sets the mark for an IP.
 func (s *Store) UpsertIPMark(ctx context.Context, ip string, markType string, reason *string, expireAt *time.Time, operator *string) error {
 	_, err := s.db.ExecContext(ctx, `
 		INSERT INTO "IPMark" ("ipAddress", "markType", "reason", "expireAt", "operator")
@@ -24,20 +29,59 @@ func (s *Store) UpsertIPMark(ctx context.Context, ip string, markType string, re
 			"reason" = EXCLUDED."reason",
 			"expireAt" = EXCLUDED."expireAt",
 			"operator" = EXCLUDED."operator"
-	`, ip, markType, reason, expireAt, operator)
+	`, NormalizeIP(ip), markType, reason, expireAt, operator)
 	return err
 }
 
-func (s *Store) GetIPMark(ctx context.Context, ip string) (IPMark, error) {
+// AddIPSuspiciousHit records that ip was marked SUSPICIOUS and returns how
+// many times it has been since the given time, this one included. Hits are
+// kept apart from the mark itself, which expires and is cleaned up.
+func (s *Store) AddIPSuspiciousHit(ctx context.Context, ip string, since time.Time) (int, error) {
+	ip = NormalizeIP(ip)
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO "IPSuspiciousHit" ("ipAddress") VALUES ($1)`, ip); err != nil {
+		return 0, err
+	}
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM "IPSuspiciousHit"
+		WHERE "ipAddress" = $1 AND "createdAt" >= $2
+	`, ip, since).Scan(&count)
+	return count, err
+}
+
+// CleanupIPSuspiciousHits deletes hits recorded before the given time.
+func (s *Store) CleanupIPSuspiciousHits(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM "IPSuspiciousHit" WHERE "createdAt" < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ipMarkColumns selects an IPMark row; the SUSPICIOUS count covers every
+// hit not yet cleaned up.
+const ipMarkColumns = `"ipAddress", "markType", "reason", "createdAt", "expireAt", "operator",
+		(SELECT COUNT(*) FROM "IPSuspiciousHit" h WHERE h."ipAddress" = "IPMark"."ipAddress")`
+
+// activeIPMarkCond filters out marks whose expireAt has passed.
+const activeIPMarkCond = `("expireAt" IS NULL OR "expireAt" > CURRENT_TIMESTAMP)`
+
+// GetIPMark returns the mark for an IP. With activeOnly set, an expired mark
+// is reported as ErrNotFound.
+func (s *Store) GetIPMark(ctx context.Context, ip string, activeOnly bool) (IPMark, error) {
 	var m IPMark
 	var reason sql.NullString
 	var expireAt sql.NullTime
 	var operator sql.NullString
-	err := s.db.QueryRowContext(ctx, `
-		SELECT "ipAddress", "markType", "reason", "createdAt", "expireAt", "operator"
+	query := `
+		SELECT `+ipMarkColumns+`
 		FROM "IPMark"
 		WHERE "ipAddress" = $1
-	`, ip).Scan(&m.IPAddress, &m.MarkType, &reason, &m.CreatedAt, &expireAt, &operator)
+	`
+	if activeOnly {
+		query += ` AND ` + activeIPMarkCond
+	}
+	err := s.db.QueryRowContext(ctx, query, NormalizeIP(ip)).Scan(&m.IPAddress, &m.MarkType, &reason, &m.CreatedAt, &expireAt, &operator, &m.SuspiciousCount)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return IPMark{}, ErrNotFound
@@ -58,7 +102,7 @@ func (s *Store) GetIPMark(ctx context.Context, ip string) (IPMark, error) {
 }
 
 func (s *Store) DeleteIPMark(ctx context.Context, ip string) error {
-	res, err := s.db.ExecContext(ctx, `DELETE FROM "IPMark" WHERE "ipAddress" = $1`, ip)
+	res, err := s.db.ExecContext(ctx, `DELETE FROM "IPMark" WHERE "ipAddress" = $1`, NormalizeIP(ip))
 	if err != nil {
 		return err
 	}
@@ -68,18 +112,27 @@ func (s *Store) DeleteIPMark(ctx context.Context, ip string) error {
 	return nil
 }
 
-func (s *Store) ListIPMarks(ctx context.Context, markType *string, limit, offset int) ([]IPMark, error) {
+// ListIPMarks lists marks, newest first. With activeOnly set, expired marks
+// are skipped.
+func (s *Store) ListIPMarks(ctx context.Context, markType *string, activeOnly bool, limit, offset int) ([]IPMark, error) {
 	query := `
-		SELECT "ipAddress", "markType", "reason", "createdAt", "expireAt", "operator"
+		SELECT `+ipMarkColumns+`
 		FROM "IPMark"
 	`
 	var args []any
+	var conds []string
 	idx := 1
 	if markType != nil {
-		query += ` WHERE "markType" = $1`
+		conds = append(conds, `"markType" = $1`)
 		args = append(args, *markType)
 		idx++
 	}
+	if activeOnly {
+		conds = append(conds, activeIPMarkCond)
+	}
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
 	query += ` ORDER BY "createdAt" DESC`
 	if limit > 0 {
 		query += ` LIMIT $` + string(rune('0'+idx))
@@ -101,7 +154,7 @@ func (s *Store) ListIPMarks(ctx context.Context, markType *string, limit, offset
 		var reason sql.NullString
 		var expireAt sql.NullTime
 		var operator sql.NullString
-		if err := rows.Scan(&m.IPAddress, &m.MarkType, &reason, &m.CreatedAt, &expireAt, &operator); err != nil {
+		if err := rows.Scan(&m.IPAddress, &m.MarkType, &reason, &m.CreatedAt, &expireAt, &operator, &m.SuspiciousCount); err != nil {
 			return nil, err
 		}
 		if reason.Valid {
@@ -130,3 +183,14 @@ func (s *Store) CleanupExpiredIPMarks(ctx context.Context, now time.Time) (int64
 	return res.RowsAffected()
 }
 
+// IsIPWhitelisted reports whether ip has an unexpired WHITELIST mark.
+func (s *Store) IsIPWhitelisted(ctx context.Context, ip string) (bool, error) {
+	var ok bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM "IPMark"
+			WHERE "ipAddress" = $1 AND "markType" = 'WHITELIST' AND `+activeIPMarkCond+`
+		)
+	`, NormalizeIP(ip)).Scan(&ok)
+	return ok, err
+}
@@ -0,0 +1,24 @@
+package store
+
+import "testing"
+
+func TestNormalizeIP(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"203.0.113.5", "203.0.113.5"},
+		{" 203.0.113.5 ", "203.0.113.5"},
+		{"::ffff:203.0.113.5", "203.0.113.5"},
+		{"[::ffff:203.0.113.5]", "203.0.113.5"},
+		{"2001:DB8:0:0::1", "2001:db8::1"},
+		{"[2001:db8::1]", "2001:db8::1"},
+		{"fe80::1%eth0", "fe80::1"},
+		{"[fe80::1%25eth0]", "fe80::1"},
+		// Values that are not addresses are kept so they still match themselves.
+		{"unknown", "unknown"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeIP(tt.in); got != tt.want {
+			t.Errorf("NormalizeIP(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
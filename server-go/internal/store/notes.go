@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type ProblemNote struct {
+	ProblemID int       `json:"problemId"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// GetProblemNote returns the user's note on a problem, or ErrNotFound.
+func (s *Store) GetProblemNote(ctx context.Context, userID, problemID int) (ProblemNote, error) {
+	var n ProblemNote
+	err := s.db.QueryRowContext(ctx, `
+		SELECT "problemId","content","createdAt","updatedAt"
+		FROM "ProblemNote"
+		WHERE "userId"=$1 AND "problemId"=$2
+	`, userID, problemID).Scan(&n.ProblemID, &n.Content, &n.CreatedAt, &n.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ProblemNote{}, ErrNotFound
+		}
+		return ProblemNote{}, err
+	}
+	return n, nil
+}
+
+// UpsertProblemNote creates or replaces the user's note on a problem.
+func (s *Store) UpsertProblemNote(ctx context.Context, userID, problemID int, content string) (ProblemNote, error) {
+	var n ProblemNote
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO "ProblemNote" ("userId","problemId","content","createdAt","updatedAt")
+		VALUES ($1,$2,$3,NOW(),NOW())
+		ON CONFLICT ("userId","problemId") DO UPDATE SET "content"=EXCLUDED."content","updatedAt"=NOW()
+		RETURNING "problemId","content","createdAt","updatedAt"
+	`, userID, problemID, content).Scan(&n.ProblemID, &n.Content, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
+
+// DeleteProblemNote removes the user's note on a problem if present.
+func (s *Store) DeleteProblemNote(ctx context.Context, userID, problemID int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM "ProblemNote" WHERE "userId"=$1 AND "problemId"=$2`, userID, problemID)
+	return err
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	NotificationBan             = "BAN"
+	NotificationContestStarting = "CONTEST_STARTING"
+)
+
+type Notification struct {
+	ID        int        `json:"id"`
+	Type      string     `json:"type"`
+	Message   string     `json:"message"`
+	Link      *string    `json:"link"`
+	ReadAt    *time.Time `json:"readAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type CreateNotificationParams struct {
+	UserID  int
+	Type    string
+	Message string
+	Link    *string
+	// DedupeKey, when set, makes a second notification with the same key
+	// for the same user a no-op.
+	DedupeKey *string
+}
+
+func (s *Store) CreateNotification(ctx context.Context, p CreateNotificationParams) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO "Notification" ("userId","type","message","link","dedupeKey","createdAt")
+		VALUES ($1,$2,$3,$4,$5,NOW())
+		ON CONFLICT ("userId","dedupeKey") DO NOTHING
+	`, p.UserID, p.Type, p.Message, p.Link, p.DedupeKey)
+	return err
+}
+
+// ListNotifications returns the user's newest notifications, at most limit.
+func (s *Store) ListNotifications(ctx context.Context, userID int, unreadOnly bool, limit int) ([]Notification, error) {
+	unreadCond := ""
+	if unreadOnly {
+		unreadCond = `AND "readAt" IS NULL`
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT "id","type","message","link","readAt","createdAt"
+		FROM "Notification"
+		WHERE "userId"=$1
+		  `+unreadCond+`
+		ORDER BY "createdAt" DESC, "id" DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Notification{}
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.Type, &n.Message, &n.Link, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID int) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "Notification" WHERE "userId"=$1 AND "readAt" IS NULL`, userID).Scan(&n)
+	return n, err
+}
+
+// MarkNotificationRead marks one of the user's notifications as read. It
+// returns ErrNotFound if the notification is not the user's.
+func (s *Store) MarkNotificationRead(ctx context.Context, userID, id int) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE "Notification" SET "readAt"=COALESCE("readAt",NOW())
+		WHERE "id"=$1 AND "userId"=$2
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user as
+// read and returns how many there were.
+func (s *Store) MarkAllNotificationsRead(ctx context.Context, userID int) (int, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE "Notification" SET "readAt"=NOW() WHERE "userId"=$1 AND "readAt" IS NULL`, userID)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// NotifyContestStarting notifies the participants of published contests
+// starting within window. Each participant is notified once per contest.
+func (s *Store) NotifyContestStarting(ctx context.Context, window time.Duration) (int, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO "Notification" ("userId","type","message","link","dedupeKey","createdAt")
+		SELECT cp."userId", $1::text, 'Contest "' || c."name" || '" starts soon',
+		       '/contest/' || c."id", 'contest-start:' || c."id", NOW()
+		FROM "Contest" c
+		JOIN "ContestParticipant" cp ON cp."contestId"=c."id"
+		WHERE c."isPublished"=true
+		  AND c."startTime" > NOW()
+		  AND c."startTime" <= NOW() + make_interval(secs => $2::float8)
+		ON CONFLICT ("userId","dedupeKey") DO NOTHING
+	`, NotificationContestStarting, window.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
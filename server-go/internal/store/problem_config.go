@@ -0,0 +1,167 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ProblemConfig is the typed form of Problem.Config. The stored JSON keeps
+// per-language overrides as top-level keys, e.g. {"cpp":{"timeLimit":2000}},
+// next to the reserved keys below.
+type ProblemConfig struct {
+	Languages   map[string]LanguageLimits
+	CompareMode string
+	Checker     *CheckerConfig
+	// Templates is starter code per language shown in the editor.
+	Templates map[string]string
+	// Scoring is one of the Scoring* policies; empty means proportional.
+	Scoring  string
+	Subtasks []Subtask
+}
+
+// Scoring policies for turning per-case verdicts into a score.
+const (
+	ScoringProportional = "proportional" // passed/total*100 (default)
+	ScoringBinary       = "binary"       // 100 if every case passes, else 0
+	ScoringSubtask      = "subtask"      // sum of the subtasks whose cases all pass
+)
+
+// ScoringPolicies lists every accepted Scoring value.
+var ScoringPolicies = []string{ScoringProportional, ScoringBinary, ScoringSubtask}
+
+// Subtask groups the next Cases test cases, in test case order, worth Score
+// points when all of them pass.
+type Subtask struct {
+	Cases int `json:"cases"`
+	Score int `json:"score"`
+}
+
+// CheckerConfig is a special-judge program that decides whether an output
+// is correct instead of comparing it with the expected output.
+type CheckerConfig struct {
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
+
+// LanguageLimits overrides the problem limits for one language. Zero keeps
+// the problem default.
+type LanguageLimits struct {
+	TimeLimit   int `json:"timeLimit,omitempty"`
+	MemoryLimit int `json:"memoryLimit,omitempty"`
+}
+
+const (
+	configKeyCompareMode = "compareMode"
+	configKeyChecker     = "checker"
+	configKeyTemplates   = "templates"
+	configKeyScoring     = "scoring"
+	configKeySubtasks    = "subtasks"
+)
+
+// ParseProblemConfig decodes a stored or submitted config. An empty or null
+// config is valid and yields the zero value.
+func ParseProblemConfig(raw json.RawMessage) (ProblemConfig, error) {
+	var cfg ProblemConfig
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return cfg, nil
+	}
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &top); err != nil {
+		return cfg, errors.New("config must be a JSON object")
+	}
+
+	keys := make([]string, 0, len(top))
+	for k := range top {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := top[k]
+		if k == configKeyCompareMode {
+			if err := json.Unmarshal(v, &cfg.CompareMode); err != nil {
+				return cfg, fmt.Errorf("config.%s must be a string", k)
+			}
+			continue
+		}
+		if k == configKeyChecker {
+			if string(bytes.TrimSpace(v)) == "null" {
+				continue
+			}
+			var checker CheckerConfig
+			dec := json.NewDecoder(bytes.NewReader(v))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&checker); err != nil {
+				return cfg, fmt.Errorf("config.%s must be an object with string language/code", k)
+			}
+			cfg.Checker = &checker
+			continue
+		}
+		if k == configKeyTemplates {
+			if err := json.Unmarshal(v, &cfg.Templates); err != nil {
+				return cfg, fmt.Errorf("config.%s must be an object of language to code", k)
+			}
+			continue
+		}
+		if k == configKeyScoring {
+			if err := json.Unmarshal(v, &cfg.Scoring); err != nil {
+				return cfg, fmt.Errorf("config.%s must be a string", k)
+			}
+			continue
+		}
+		if k == configKeySubtasks {
+			dec := json.NewDecoder(bytes.NewReader(v))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&cfg.Subtasks); err != nil {
+				return cfg, fmt.Errorf("config.%s must be an array of objects with integer cases/score", k)
+			}
+			continue
+		}
+		var limits LanguageLimits
+		dec := json.NewDecoder(bytes.NewReader(v))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&limits); err != nil {
+			return cfg, fmt.Errorf("config.%s must be an object with integer timeLimit/memoryLimit", k)
+		}
+		if cfg.Languages == nil {
+			cfg.Languages = map[string]LanguageLimits{}
+		}
+		cfg.Languages[k] = limits
+	}
+	return cfg, nil
+}
+
+// PublicProblemConfig returns raw with the checker removed, for showing the
+// config to users who may not see the checker source. Unparseable configs
+// are returned unchanged.
+func PublicProblemConfig(raw json.RawMessage) json.RawMessage {
+	var top map[string]json.RawMessage
+	if json.Unmarshal(raw, &top) != nil {
+		return raw
+	}
+	if _, ok := top[configKeyChecker]; !ok {
+		return raw
+	}
+	delete(top, configKeyChecker)
+	b, err := json.Marshal(top)
+	if err != nil {
+		return raw
+	}
+	return b
+}
+
+// LimitsFor returns the time and memory limits for language, falling back to
+// the given problem defaults where no override is set.
+func (c ProblemConfig) LimitsFor(language string, timeLimit, memoryLimit int) (int, int) {
+	if l, ok := c.Languages[language]; ok {
+		if l.TimeLimit > 0 {
+			timeLimit = l.TimeLimit
+		}
+		if l.MemoryLimit > 0 {
+			memoryLimit = l.MemoryLimit
+		}
+	}
+	return timeLimit, memoryLimit
+}
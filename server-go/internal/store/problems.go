@@ -10,19 +10,28 @@ import (
 )
 
 type ProblemListItem struct {
-	ID         int       `json:"id"`
-	Title      string    `json:"title"`
-	Difficulty string    `json:"difficulty"`
-	Tags       []string  `json:"tags"`
-	CreatedAt  time.Time `json:"createdAt"`
-	Visible    bool      `json:"visible"`
-	Score      *int      `json:"score,omitempty"`
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Difficulty  string     `json:"difficulty"`
+	Tags        []string   `json:"tags"`
+	Slug        *string    `json:"slug"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	Visible     bool       `json:"visible"`
+	VisibleFrom *time.Time `json:"visibleFrom"`
+	Score       *int       `json:"score,omitempty"`
+	Bookmarked  *bool      `json:"bookmarked,omitempty"`
+	// TestCaseCount is only filled in for admins. NoTestCases flags the
+	// problems that would reject every submission.
+	TestCaseCount *int `json:"testCaseCount,omitempty"`
+	NoTestCases   bool `json:"noTestCases,omitempty"`
 }
 
 type ListProblemsParams struct {
 	Difficulty string
 	Search     string
 	Tags       []string
+	// TagsMatchAll requires every tag in Tags instead of any of them.
+	TagsMatchAll bool
 }
 
 func (s *Store) ListProblemsPublic(ctx context.Context, p ListProblemsParams) ([]ProblemListItem, error) {
@@ -57,13 +66,17 @@ func (s *Store) listProblems(ctx context.Context, p ListProblemsParams, public b
 	}
 
 	if len(p.Tags) > 0 {
-		conds = append(conds, `"tags" && $`+itoa(arg)+`::text[]`)
+		op := "&&"
+		if p.TagsMatchAll {
+			op = "@>"
+		}
+		conds = append(conds, `"tags" `+op+` $`+itoa(arg)+`::text[]`)
 		args = append(args, p.Tags)
 		arg++
 	}
 
 	if public {
-		conds = append(conds, `"visible"=true`)
+		conds = append(conds, publicProblemCond(""))
 	}
 
 	where := ""
@@ -72,7 +85,7 @@ func (s *Store) listProblems(ctx context.Context, p ListProblemsParams, public b
 	}
 
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT "id","title","difficulty","tags","createdAt","visible"
+		SELECT "id","title","difficulty","tags","slug","createdAt","visible","visibleFrom"
 		FROM "Problem"
 		`+where+`
 		ORDER BY "id" ASC
@@ -86,7 +99,7 @@ func (s *Store) listProblems(ctx context.Context, p ListProblemsParams, public b
 	for rows.Next() {
 		var item ProblemListItem
 		var tags PGTextArray
-		if err := rows.Scan(&item.ID, &item.Title, &item.Difficulty, &tags, &item.CreatedAt, &item.Visible); err != nil {
+		if err := rows.Scan(&item.ID, &item.Title, &item.Difficulty, &tags, &item.Slug, &item.CreatedAt, &item.Visible, &item.VisibleFrom); err != nil {
 			return nil, err
 		}
 		item.Tags = []string(tags)
@@ -95,6 +108,31 @@ func (s *Store) listProblems(ctx context.Context, p ListProblemsParams, public b
 	return out, rows.Err()
 }
 
+// GetTestCaseCountsByProblem returns the number of test cases of every
+// problem, including those without any.
+func (s *Store) GetTestCaseCountsByProblem(ctx context.Context) (map[int]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p."id", COUNT(tc."id")
+		FROM "Problem" p
+		LEFT JOIN "TestCase" tc ON tc."problemId"=p."id"
+		GROUP BY p."id"
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int]int{}
+	for rows.Next() {
+		var pid, n int
+		if err := rows.Scan(&pid, &n); err != nil {
+			return nil, err
+		}
+		out[pid] = n
+	}
+	return out, rows.Err()
+}
+
 func (s *Store) GetUserMaxScoresByProblem(ctx context.Context, userID int) (map[int]int, error) {
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT "problemId", MAX("score") as "maxScore"
@@ -121,6 +159,55 @@ func (s *Store) GetUserMaxScoresByProblem(ctx context.Context, userID int) (map[
 	return out, rows.Err()
 }
 
+type SolvedProblem struct {
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	Difficulty string    `json:"difficulty"`
+	SolvedAt   time.Time `json:"solvedAt"`
+}
+
+// solvedSubmissionCond matches full-score Accepted submissions s whose
+// verdict is public, i.e. not in an OI contest c that is still running.
+const solvedSubmissionCond = `s."status"='Accepted'
+		  AND COALESCE(s."score",0)>=100
+		  AND (c."id" IS NULL OR c."rule"<>'OI' OR c."endTime"<=NOW())`
+
+// ListSolvedProblems returns problems the user has a full-score Accepted
+// submission for, with the time of the first such submission. Submissions in
+// OI contests that have not ended yet are ignored since their verdicts are
+// still masked.
+func (s *Store) ListSolvedProblems(ctx context.Context, userID int, onlyVisible bool) ([]SolvedProblem, error) {
+	visibleCond := ""
+	if onlyVisible {
+		visibleCond = `AND ` + publicProblemCond("p.")
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p."id", p."title", p."difficulty", MIN(s."createdAt")
+		FROM "Submission" s
+		JOIN "Problem" p ON p."id"=s."problemId"
+		LEFT JOIN "Contest" c ON c."id"=s."contestId"
+		WHERE s."userId"=$1
+		  AND `+solvedSubmissionCond+`
+		  `+visibleCond+`
+		GROUP BY p."id", p."title", p."difficulty"
+		ORDER BY p."id" ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SolvedProblem{}
+	for rows.Next() {
+		var sp SolvedProblem
+		if err := rows.Scan(&sp.ID, &sp.Title, &sp.Difficulty, &sp.SolvedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, sp)
+	}
+	return out, rows.Err()
+}
+
 type Problem struct {
 	ID                    int             `json:"id"`
 	Title                 string          `json:"title"`
@@ -131,20 +218,33 @@ type Problem struct {
 	DefaultCompileOptions string          `json:"defaultCompileOptions"`
 	Difficulty            string          `json:"difficulty"`
 	Tags                  []string        `json:"tags"`
+	Slug                  *string         `json:"slug"`
 	Visible               bool            `json:"visible"`
+	VisibleFrom           *time.Time      `json:"visibleFrom"`
 	CreatedAt             time.Time       `json:"createdAt"`
 	UpdatedAt             time.Time       `json:"updatedAt"`
 }
 
 func (s *Store) GetProblemByID(ctx context.Context, id int) (Problem, error) {
+	return s.getProblem(ctx, `"id"=$1`, id)
+}
+
+// GetProblemBySlug looks a problem up by its optional slug.
+func (s *Store) GetProblemBySlug(ctx context.Context, slug string) (Problem, error) {
+	return s.getProblem(ctx, `"slug"=$1`, slug)
+}
+
+// getProblem loads the single problem matching cond, a constant condition
+// on one placeholder.
+func (s *Store) getProblem(ctx context.Context, cond string, arg any) (Problem, error) {
 	var p Problem
 	var cfg []byte
 	var tags PGTextArray
 	err := s.db.QueryRowContext(ctx, `
-		SELECT "id","title","description","timeLimit","memoryLimit","config","defaultCompileOptions","difficulty","tags","visible","createdAt","updatedAt"
+		SELECT "id","title","description","timeLimit","memoryLimit","config","defaultCompileOptions","difficulty","tags","slug","visible","visibleFrom","createdAt","updatedAt"
 		FROM "Problem"
-		WHERE "id"=$1
-	`, id).Scan(&p.ID, &p.Title, &p.Description, &p.TimeLimit, &p.MemoryLimit, &cfg, &p.DefaultCompileOptions, &p.Difficulty, &tags, &p.Visible, &p.CreatedAt, &p.UpdatedAt)
+		WHERE `+cond+`
+	`, arg).Scan(&p.ID, &p.Title, &p.Description, &p.TimeLimit, &p.MemoryLimit, &cfg, &p.DefaultCompileOptions, &p.Difficulty, &tags, &p.Slug, &p.Visible, &p.VisibleFrom, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Problem{}, ErrNotFound
@@ -158,6 +258,53 @@ func (s *Store) GetProblemByID(ctx context.Context, id int) (Problem, error) {
 	return p, nil
 }
 
+// BatchSetProblemVisibility sets the visible flag of the given problems in
+// one statement, clearing any visibleFrom schedule like
+// UpdateProblemVisibility does when none is given.
+func (s *Store) BatchSetProblemVisibility(ctx context.Context, ids []int, visible bool, updatedBy *int) (int, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE "Problem" SET "visible"=$1,"visibleFrom"=NULL,"updatedAt"=NOW(),"updatedBy"=$3
+		WHERE "id" = ANY($2)
+	`, visible, ids, updatedBy)
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := res.RowsAffected()
+	return int(affected), nil
+}
+
+// AssignProblemTags adds and removes tags on the given problems without
+// touching their other fields. Existing tags keep their order, added ones go
+// last and duplicates are dropped. It returns how many problems were updated.
+func (s *Store) AssignProblemTags(ctx context.Context, ids []int, add, remove []string, updatedBy *int) (int, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE "Problem" SET "tags"=ARRAY(
+			SELECT t FROM unnest("tags" || $2::text[]) WITH ORDINALITY AS x(t, n)
+			WHERE t <> ALL($3::text[])
+			GROUP BY t
+			ORDER BY MIN(n)
+		),"updatedAt"=NOW(),"updatedBy"=$4
+		WHERE "id" = ANY($1)
+	`, ids, add, remove, updatedBy)
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := res.RowsAffected()
+	return int(affected), nil
+}
+
+// IsPublic reports whether non-admins may see the problem: it is visible
+// and its visibleFrom, if scheduled, has passed.
+func (p Problem) IsPublic() bool {
+	return p.Visible && (p.VisibleFrom == nil || !p.VisibleFrom.After(time.Now()))
+}
+
+// publicProblemCond is the SQL form of Problem.IsPublic. alias qualifies
+// the Problem columns, e.g. "p.", or is empty.
+func publicProblemCond(alias string) string {
+	return `(` + alias + `"visible"=true AND (` + alias + `"visibleFrom" IS NULL OR ` + alias + `"visibleFrom"<=NOW()))`
+}
+
 type TestCase struct {
 	ID             int    `json:"id"`
 	Input          string `json:"input"`
@@ -213,64 +360,62 @@ type CreateProblemParams struct {
 	DefaultCompileOptions string
 	Difficulty            string
 	Tags                  []string
+	Slug                  *string
 	Config                json.RawMessage
 	TestCases             []TestCaseInput
 	ContestID             int
+	CreatedBy             *int
 }
 
 func (s *Store) CreateProblem(ctx context.Context, p CreateProblemParams) (Problem, error) {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return Problem{}, err
-	}
-	defer tx.Rollback()
-
 	var created Problem
-	var cfg []byte
-	var tags PGTextArray
-	err = tx.QueryRowContext(ctx, `
-		INSERT INTO "Problem" ("title","description","timeLimit","memoryLimit","defaultCompileOptions","difficulty","tags","config","createdAt","updatedAt")
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,NOW(),NOW())
-		RETURNING "id","title","description","timeLimit","memoryLimit","config","defaultCompileOptions","difficulty","tags","visible","createdAt","updatedAt"
-	`, p.Title, p.Description, p.TimeLimit, p.MemoryLimit, p.DefaultCompileOptions, p.Difficulty, p.Tags, p.Config).
-		Scan(&created.ID, &created.Title, &created.Description, &created.TimeLimit, &created.MemoryLimit, &cfg, &created.DefaultCompileOptions, &created.Difficulty, &tags, &created.Visible, &created.CreatedAt, &created.UpdatedAt)
-	if err != nil {
-		return Problem{}, err
-	}
-	if cfg != nil {
-		created.Config = cfg
-	}
-	created.Tags = []string(tags)
-
-	for _, tc := range p.TestCases {
-		_, err := tx.ExecContext(ctx, `INSERT INTO "TestCase" ("input","expectedOutput","problemId") VALUES ($1,$2,$3)`, tc.Input, tc.ExpectedOutput, created.ID)
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var cfg []byte
+		var tags PGTextArray
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO "Problem" ("title","description","timeLimit","memoryLimit","defaultCompileOptions","difficulty","tags","config","createdAt","updatedAt","createdBy","updatedBy","slug")
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,NOW(),NOW(),$9,$9,$10)
+			RETURNING "id","title","description","timeLimit","memoryLimit","config","defaultCompileOptions","difficulty","tags","slug","visible","visibleFrom","createdAt","updatedAt"
+		`, p.Title, p.Description, p.TimeLimit, p.MemoryLimit, p.DefaultCompileOptions, p.Difficulty, p.Tags, p.Config, p.CreatedBy, p.Slug).
+			Scan(&created.ID, &created.Title, &created.Description, &created.TimeLimit, &created.MemoryLimit, &cfg, &created.DefaultCompileOptions, &created.Difficulty, &tags, &created.Slug, &created.Visible, &created.VisibleFrom, &created.CreatedAt, &created.UpdatedAt)
 		if err != nil {
-			return Problem{}, err
+			return uniqueViolation(err)
 		}
-	}
-
-	if p.ContestID > 0 {
-		var exists bool
-		if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM "Contest" WHERE "id"=$1)`, p.ContestID).Scan(&exists); err != nil {
-			return Problem{}, err
+		if cfg != nil {
+			created.Config = cfg
 		}
-		if exists {
-			var maxOrder sql.NullInt64
-			if err := tx.QueryRowContext(ctx, `SELECT MAX("order") FROM "ContestProblem" WHERE "contestId"=$1`, p.ContestID).Scan(&maxOrder); err != nil {
-				return Problem{}, err
-			}
-			nextOrder := 0
-			if maxOrder.Valid {
-				nextOrder = int(maxOrder.Int64) + 1
-			}
-			_, err := tx.ExecContext(ctx, `INSERT INTO "ContestProblem" ("contestId","problemId","order") VALUES ($1,$2,$3)`, p.ContestID, created.ID, nextOrder)
+		created.Tags = []string(tags)
+
+		for _, tc := range p.TestCases {
+			_, err := tx.ExecContext(ctx, `INSERT INTO "TestCase" ("input","expectedOutput","problemId") VALUES ($1,$2,$3)`, tc.Input, tc.ExpectedOutput, created.ID)
 			if err != nil {
-				return Problem{}, err
+				return err
 			}
 		}
-	}
 
-	if err := tx.Commit(); err != nil {
+		if p.ContestID > 0 {
+			var exists bool
+			if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM "Contest" WHERE "id"=$1)`, p.ContestID).Scan(&exists); err != nil {
+				return err
+			}
+			if exists {
+				var maxOrder sql.NullInt64
+				if err := tx.QueryRowContext(ctx, `SELECT MAX("order") FROM "ContestProblem" WHERE "contestId"=$1`, p.ContestID).Scan(&maxOrder); err != nil {
+					return err
+				}
+				nextOrder := 0
+				if maxOrder.Valid {
+					nextOrder = int(maxOrder.Int64) + 1
+				}
+				_, err := tx.ExecContext(ctx, `INSERT INTO "ContestProblem" ("contestId","problemId","order") VALUES ($1,$2,$3)`, p.ContestID, created.ID, nextOrder)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return Problem{}, err
 	}
 	return created, nil
@@ -286,54 +431,67 @@ type UpdateProblemParams struct {
 	Difficulty            string
 	Tags                  []string
 	Config                json.RawMessage
-	TestCases             []TestCaseInput
+	UpdatedBy             *int
+	// UpdateTestCases replaces all test cases with TestCases; otherwise
+	// the existing ones are kept.
+	UpdateTestCases bool
+	TestCases       []TestCaseInput
+	// UpdateSlug sets Slug, where nil removes it.
+	UpdateSlug bool
+	Slug       *string
 }
 
 func (s *Store) UpdateProblem(ctx context.Context, p UpdateProblemParams) (ProblemWithTestCases, error) {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return ProblemWithTestCases{}, err
-	}
-	defer tx.Rollback()
-
-	res, err := tx.ExecContext(ctx, `
-		UPDATE "Problem"
-		SET "title"=$1,"description"=$2,"timeLimit"=$3,"memoryLimit"=$4,"defaultCompileOptions"=$5,"difficulty"=$6,"tags"=$7,"config"=$8,"updatedAt"=NOW()
-		WHERE "id"=$9
-	`, p.Title, p.Description, p.TimeLimit, p.MemoryLimit, p.DefaultCompileOptions, p.Difficulty, p.Tags, p.Config, p.ID)
-	if err != nil {
-		return ProblemWithTestCases{}, err
-	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		return ProblemWithTestCases{}, ErrNotFound
-	}
-
-	if _, err := tx.ExecContext(ctx, `DELETE FROM "TestCase" WHERE "problemId"=$1`, p.ID); err != nil {
-		return ProblemWithTestCases{}, err
-	}
-
-	for _, tc := range p.TestCases {
-		_, err := tx.ExecContext(ctx, `INSERT INTO "TestCase" ("input","expectedOutput","problemId") VALUES ($1,$2,$3)`, tc.Input, tc.ExpectedOutput, p.ID)
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE "Problem"
+			SET "title"=$1,"description"=$2,"timeLimit"=$3,"memoryLimit"=$4,"defaultCompileOptions"=$5,"difficulty"=$6,"tags"=$7,"config"=$8,"updatedAt"=NOW(),"updatedBy"=$10
+			WHERE "id"=$9
+		`, p.Title, p.Description, p.TimeLimit, p.MemoryLimit, p.DefaultCompileOptions, p.Difficulty, p.Tags, p.Config, p.ID, p.UpdatedBy)
 		if err != nil {
-			return ProblemWithTestCases{}, err
+			return err
+		}
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			return ErrNotFound
+		}
+		if p.UpdateSlug {
+			if _, err := tx.ExecContext(ctx, `UPDATE "Problem" SET "slug"=$1 WHERE "id"=$2`, p.Slug, p.ID); err != nil {
+				return uniqueViolation(err)
+			}
+		}
+
+		if !p.UpdateTestCases {
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM "TestCase" WHERE "problemId"=$1`, p.ID); err != nil {
+			return err
 		}
-	}
 
-	if err := tx.Commit(); err != nil {
+		for _, tc := range p.TestCases {
+			_, err := tx.ExecContext(ctx, `INSERT INTO "TestCase" ("input","expectedOutput","problemId") VALUES ($1,$2,$3)`, tc.Input, tc.ExpectedOutput, p.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return ProblemWithTestCases{}, err
 	}
 	return s.GetProblemWithTestCases(ctx, p.ID)
 }
 
-func (s *Store) UpdateProblemVisibility(ctx context.Context, id int, visible bool) (Problem, error) {
+// UpdateProblemVisibility sets the visible flag and the visibleFrom
+// schedule; a nil visibleFrom clears any schedule.
+func (s *Store) UpdateProblemVisibility(ctx context.Context, id int, visible bool, visibleFrom *time.Time, updatedBy *int) (Problem, error) {
 	var p Problem
 	var cfg []byte
 	var tags PGTextArray
 	err := s.db.QueryRowContext(ctx, `
-		UPDATE "Problem" SET "visible"=$1,"updatedAt"=NOW() WHERE "id"=$2
-		RETURNING "id","title","description","timeLimit","memoryLimit","config","defaultCompileOptions","difficulty","tags","visible","createdAt","updatedAt"
-	`, visible, id).Scan(&p.ID, &p.Title, &p.Description, &p.TimeLimit, &p.MemoryLimit, &cfg, &p.DefaultCompileOptions, &p.Difficulty, &tags, &p.Visible, &p.CreatedAt, &p.UpdatedAt)
+		UPDATE "Problem" SET "visible"=$1,"visibleFrom"=$4,"updatedAt"=NOW(),"updatedBy"=$3 WHERE "id"=$2
+		RETURNING "id","title","description","timeLimit","memoryLimit","config","defaultCompileOptions","difficulty","tags","slug","visible","visibleFrom","createdAt","updatedAt"
+	`, visible, id, updatedBy, visibleFrom).Scan(&p.ID, &p.Title, &p.Description, &p.TimeLimit, &p.MemoryLimit, &cfg, &p.DefaultCompileOptions, &p.Difficulty, &tags, &p.Slug, &p.Visible, &p.VisibleFrom, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Problem{}, ErrNotFound
@@ -348,29 +506,30 @@ func (s *Store) UpdateProblemVisibility(ctx context.Context, id int, visible boo
 }
 
 func (s *Store) DeleteProblemCascade(ctx context.Context, problemID int) error {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	if _, err := tx.ExecContext(ctx, `DELETE FROM "Submission" WHERE "problemId"=$1`, problemID); err != nil {
-		return err
-	}
-	if _, err := tx.ExecContext(ctx, `DELETE FROM "TestCase" WHERE "problemId"=$1`, problemID); err != nil {
-		return err
-	}
-	if _, err := tx.ExecContext(ctx, `DELETE FROM "Problem" WHERE "id"=$1`, problemID); err != nil {
-		return err
-	}
-	return tx.Commit()
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM "Submission" WHERE "problemId"=$1`, problemID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM "TestCase" WHERE "problemId"=$1`, problemID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM "Problem" WHERE "id"=$1`, problemID); err != nil {
+			return err
+		}
+		return nil
+	})
 }
 
-func (s *Store) CloneProblem(ctx context.Context, problemID int, newTitle string) (ProblemWithTestCases, error) {
+// CloneProblem copies a problem with its test cases. Unless allowEmpty is
+// set it returns ErrNoTestCases for a problem without test cases.
+func (s *Store) CloneProblem(ctx context.Context, problemID int, newTitle string, allowEmpty bool, createdBy *int) (ProblemWithTestCases, error) {
 	original, err := s.GetProblemWithTestCases(ctx, problemID)
 	if err != nil {
 		return ProblemWithTestCases{}, err
 	}
+	if len(original.TestCases) == 0 && !allowEmpty {
+		return ProblemWithTestCases{}, ErrNoTestCases
+	}
 
 	title := strings.TrimSpace(newTitle)
 	if title == "" {
@@ -392,9 +551,55 @@ func (s *Store) CloneProblem(ctx context.Context, problemID int, newTitle string
 		Tags:                  original.Tags,
 		Config:                original.Config,
 		TestCases:             testInputs,
+		CreatedBy:             createdBy,
 	})
 	if err != nil {
 		return ProblemWithTestCases{}, err
 	}
 	return s.GetProblemWithTestCases(ctx, created.ID)
 }
+
+// EditorRef identifies the admin who created or last updated an entity.
+type EditorRef struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+// Editors holds the createdBy/updatedBy users of a problem or contest.
+// Either is nil for rows written before tracking existed or whose user was deleted.
+type Editors struct {
+	CreatedBy *EditorRef `json:"createdBy"`
+	UpdatedBy *EditorRef `json:"updatedBy"`
+}
+
+// getEditors resolves the createdBy/updatedBy columns of table to usernames.
+// table is always a constant from this package.
+func (s *Store) getEditors(ctx context.Context, table string, id int) (Editors, error) {
+	var createdID, updatedID sql.NullInt64
+	var createdName, updatedName sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT t."createdBy", cu."username", t."updatedBy", uu."username"
+		FROM "`+table+`" t
+		LEFT JOIN "User" cu ON cu."id"=t."createdBy"
+		LEFT JOIN "User" uu ON uu."id"=t."updatedBy"
+		WHERE t."id"=$1
+	`, id).Scan(&createdID, &createdName, &updatedID, &updatedName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Editors{}, ErrNotFound
+		}
+		return Editors{}, err
+	}
+	var e Editors
+	if createdID.Valid && createdName.Valid {
+		e.CreatedBy = &EditorRef{ID: int(createdID.Int64), Username: createdName.String}
+	}
+	if updatedID.Valid && updatedName.Valid {
+		e.UpdatedBy = &EditorRef{ID: int(updatedID.Int64), Username: updatedName.String}
+	}
+	return e, nil
+}
+
+func (s *Store) GetProblemEditors(ctx context.Context, id int) (Editors, error) {
+	return s.getEditors(ctx, "Problem", id)
+}
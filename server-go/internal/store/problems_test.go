@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"onlinejudge-server-go/internal/testdb"
+)
+
+func TestListProblemsTagMode(t *testing.T) {
+	s := New(testdb.Open(t))
+	ctx := context.Background()
+	for _, p := range []struct {
+		title string
+		tags  []string
+	}{
+		{"dp only", []string{"dp"}},
+		{"graph only", []string{"graph"}},
+		{"dp and graph", []string{"dp", "graph", "math"}},
+		{"untagged", []string{}},
+	} {
+		if _, err := s.CreateProblem(ctx, CreateProblemParams{
+			Title: p.title, Description: "d", TimeLimit: 1000, MemoryLimit: 128,
+			Difficulty: "LEVEL2", Tags: p.tags,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	titles := func(matchAll bool) []string {
+		items, err := s.ListProblemsAdmin(ctx, ListProblemsParams{Tags: []string{"dp", "graph"}, TagsMatchAll: matchAll})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, it := range items {
+			out = append(out, it.Title)
+		}
+		return out
+	}
+	if got, want := titles(false), []string{"dp only", "graph only", "dp and graph"}; !slices.Equal(got, want) {
+		t.Errorf("any: got %q, want %q", got, want)
+	}
+	if got, want := titles(true), []string{"dp and graph"}; !slices.Equal(got, want) {
+		t.Errorf("all: got %q, want %q", got, want)
+	}
+}
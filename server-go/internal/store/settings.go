@@ -3,8 +3,10 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"strconv"
+	"time"
 )
 
 func (s *Store) IsRegistrationEnabled(ctx context.Context) (bool, error) {
@@ -116,6 +118,23 @@ func (s *Store) GetSubmissionRateLimit(ctx context.Context) (int, error) {
 	return limit, nil
 }
 
+// GetEffectiveSubmissionRateLimit returns the per-minute submission limit for
+// a submission to contestID: the contest's own limit when it sets one, the
+// global limit otherwise (including for practice submissions, contestID nil).
+func (s *Store) GetEffectiveSubmissionRateLimit(ctx context.Context, contestID *int) (int, error) {
+	if contestID != nil {
+		var limit sql.NullInt64
+		err := s.db.QueryRowContext(ctx, `SELECT "submissionRateLimit" FROM "Contest" WHERE "id"=$1`, *contestID).Scan(&limit)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+		if limit.Valid {
+			return int(limit.Int64), nil
+		}
+	}
+	return s.GetSubmissionRateLimit(ctx)
+}
+
 func (s *Store) UpsertSubmissionRateLimit(ctx context.Context, limit int) (int, error) {
 	var stored string
 	err := s.db.QueryRowContext(ctx, `
@@ -164,6 +183,41 @@ func (s *Store) UpsertCodeRunRateLimit(ctx context.Context, limit int) (int, err
 	return result, nil
 }
 
+// Code run rate limit per client IP (runs per minute), shared by every
+// account using that address.
+func (s *Store) GetCodeRunIPRateLimit(ctx context.Context) (int, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"='code_run_ip_rate_limit'`).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 20, nil
+		}
+		return 20, err
+	}
+	if !value.Valid {
+		return 20, nil
+	}
+	limit, err := strconv.Atoi(value.String)
+	if err != nil {
+		return 20, nil
+	}
+	return limit, nil
+}
+
+func (s *Store) UpsertCodeRunIPRateLimit(ctx context.Context, limit int) (int, error) {
+	var stored string
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ('code_run_ip_rate_limit',$1)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+		RETURNING "value"
+		`, strconv.Itoa(limit)).Scan(&stored)
+	if err != nil {
+		return 0, err
+	}
+	result, _ := strconv.Atoi(stored)
+	return result, nil
+}
+
 // Turnstile settings
 func (s *Store) GetTurnstileEnabled(ctx context.Context) (bool, error) {
 	var value sql.NullString
@@ -197,9 +251,15 @@ func (s *Store) UpsertTurnstileEnabled(ctx context.Context, enabled bool) (bool,
 	return stored == "true", nil
 }
 
-func (s *Store) GetTurnstileSiteKey(ctx context.Context) (string, error) {
+// Captcha keys are stored per provider as <provider>_site_key and
+// <provider>_secret_key, so switching providers never sends one vendor's
+// secret to another. Turnstile's keys keep their original names.
+func captchaSiteKeyKey(provider string) string   { return provider + "_site_key" }
+func captchaSecretKeyKey(provider string) string { return provider + "_secret_key" }
+
+func (s *Store) GetCaptchaSiteKey(ctx context.Context, provider string) (string, error) {
 	var value sql.NullString
-	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"='turnstile_site_key'`).Scan(&value)
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"=$1`, captchaSiteKeyKey(provider)).Scan(&value)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", nil
@@ -212,15 +272,482 @@ func (s *Store) GetTurnstileSiteKey(ctx context.Context) (string, error) {
 	return value.String, nil
 }
 
-func (s *Store) UpsertTurnstileSiteKey(ctx context.Context, siteKey string) (string, error) {
+func (s *Store) UpsertCaptchaSiteKey(ctx context.Context, provider, siteKey string) (string, error) {
 	var stored string
 	err := s.db.QueryRowContext(ctx, `
-		INSERT INTO "Setting" ("key","value") VALUES ('turnstile_site_key',$1)
+		INSERT INTO "Setting" ("key","value") VALUES ($1,$2)
 		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
 		RETURNING "value"
-	`, siteKey).Scan(&stored)
+	`, captchaSiteKeyKey(provider), siteKey).Scan(&stored)
 	if err != nil {
 		return "", err
 	}
 	return stored, nil
 }
+
+func (s *Store) GetCaptchaSecretKey(ctx context.Context, provider string) (string, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"=$1`, captchaSecretKeyKey(provider)).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !value.Valid {
+		return "", nil
+	}
+	return value.String, nil
+}
+
+func (s *Store) UpsertCaptchaSecretKey(ctx context.Context, provider, secret string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ($1,$2)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+	`, captchaSecretKeyKey(provider), secret)
+	return err
+}
+
+func (s *Store) DeleteCaptchaSecretKey(ctx context.Context, provider string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM "Setting" WHERE "key"=$1`, captchaSecretKeyKey(provider))
+	return err
+}
+
+// Captcha provider (turnstile, hcaptcha or recaptcha)
+func (s *Store) GetCaptchaProvider(ctx context.Context) (string, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"='captcha_provider'`).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !value.Valid {
+		return "", nil
+	}
+	return value.String, nil
+}
+
+func (s *Store) UpsertCaptchaProvider(ctx context.Context, provider string) (string, error) {
+	var stored string
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ('captcha_provider',$1)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+		RETURNING "value"
+	`, provider).Scan(&stored)
+	if err != nil {
+		return "", err
+	}
+	return stored, nil
+}
+
+// Per-action captcha toggles, stored as captcha_on_<action>
+func (s *Store) GetCaptchaActionEnabled(ctx context.Context, action string) (bool, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"=$1`, "captcha_on_"+action).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !value.Valid {
+		return false, nil
+	}
+	return value.String == "true", nil
+}
+
+func (s *Store) UpsertCaptchaActionEnabled(ctx context.Context, action string, enabled bool) (bool, error) {
+	val := "false"
+	if enabled {
+		val = "true"
+	}
+	var stored string
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ($1,$2)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+		RETURNING "value"
+	`, "captcha_on_"+action, val).Scan(&stored)
+	if err != nil {
+		return false, err
+	}
+	return stored == "true", nil
+}
+
+// Submission retention in days; 0 keeps submissions forever
+func (s *Store) GetSubmissionRetentionDays(ctx context.Context) (int, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"='submission_retention_days'`).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if !value.Valid {
+		return 0, nil
+	}
+	days, err := strconv.Atoi(value.String)
+	if err != nil || days < 0 {
+		return 0, nil
+	}
+	return days, nil
+}
+
+func (s *Store) UpsertSubmissionRetentionDays(ctx context.Context, days int) (int, error) {
+	var stored string
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ('submission_retention_days',$1)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+		RETURNING "value"
+	`, strconv.Itoa(days)).Scan(&stored)
+	if err != nil {
+		return 0, err
+	}
+	result, _ := strconv.Atoi(stored)
+	return result, nil
+}
+
+// SensitivePathPatterns decides which request paths are logged as sensitive.
+// Prefixes and substrings match the lowercased path; regexes match it as is.
+type SensitivePathPatterns struct {
+	Prefixes []string `json:"prefixes"`
+	Contains []string `json:"contains"`
+	Regexes  []string `json:"regexes"`
+}
+
+// DefaultSensitivePathPatterns is used until an admin saves patterns.
+func DefaultSensitivePathPatterns() SensitivePathPatterns {
+	return SensitivePathPatterns{
+		Prefixes: []string{"/api/admin", "/admin", "/.git", "/.env"},
+		Contains: []string{"config"},
+		Regexes:  []string{},
+	}
+}
+
+func (s *Store) GetSensitivePathPatterns(ctx context.Context) (SensitivePathPatterns, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"='sensitive_path_patterns'`).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultSensitivePathPatterns(), nil
+		}
+		return SensitivePathPatterns{}, err
+	}
+	var p SensitivePathPatterns
+	if !value.Valid || json.Unmarshal([]byte(value.String), &p) != nil {
+		return DefaultSensitivePathPatterns(), nil
+	}
+	return p, nil
+}
+
+func (s *Store) UpsertSensitivePathPatterns(ctx context.Context, p SensitivePathPatterns) (SensitivePathPatterns, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return SensitivePathPatterns{}, err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ('sensitive_path_patterns',$1)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+	`, string(b))
+	if err != nil {
+		return SensitivePathPatterns{}, err
+	}
+	return p, nil
+}
+
+// SensitiveAlertSettings controls automatic SUSPICIOUS marks for IPs whose
+// sensitive-path hits within WindowMinutes reach Threshold.
+type SensitiveAlertSettings struct {
+	Enabled       bool `json:"enabled"`
+	Threshold     int  `json:"threshold"`
+	WindowMinutes int  `json:"windowMinutes"`
+	MarkHours     int  `json:"markHours"`
+	Notify        bool `json:"notify"`
+}
+
+func DefaultSensitiveAlertSettings() SensitiveAlertSettings {
+	return SensitiveAlertSettings{Enabled: true, Threshold: 30, WindowMinutes: 5, MarkHours: 24, Notify: true}
+}
+
+func (s *Store) GetSensitiveAlertSettings(ctx context.Context) (SensitiveAlertSettings, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"='sensitive_alert_settings'`).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultSensitiveAlertSettings(), nil
+		}
+		return SensitiveAlertSettings{}, err
+	}
+	settings := DefaultSensitiveAlertSettings()
+	if !value.Valid || json.Unmarshal([]byte(value.String), &settings) != nil {
+		return DefaultSensitiveAlertSettings(), nil
+	}
+	return settings, nil
+}
+
+func (s *Store) UpsertSensitiveAlertSettings(ctx context.Context, settings SensitiveAlertSettings) (SensitiveAlertSettings, error) {
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return SensitiveAlertSettings{}, err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ('sensitive_alert_settings',$1)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+	`, string(b))
+	if err != nil {
+		return SensitiveAlertSettings{}, err
+	}
+	return settings, nil
+}
+
+// IPAutoBanSettings turns IP marks into temporary bans: a MALICIOUS mark, or
+// the SuspiciousThreshold-th SUSPICIOUS mark (0 disables) within the last
+// SuspiciousWindowHours, bans the IP for BanHours. WHITELIST marks are never
+// banned.
+type IPAutoBanSettings struct {
+	Enabled               bool `json:"enabled"`
+	BanOnMalicious        bool `json:"banOnMalicious"`
+	SuspiciousThreshold   int  `json:"suspiciousThreshold"`
+	SuspiciousWindowHours int  `json:"suspiciousWindowHours"`
+	BanHours              int  `json:"banHours"`
+}
+
+func DefaultIPAutoBanSettings() IPAutoBanSettings {
+	return IPAutoBanSettings{Enabled: false, BanOnMalicious: true, SuspiciousThreshold: 3, SuspiciousWindowHours: 30 * 24, BanHours: 72}
+}
+
+func (s *Store) GetIPAutoBanSettings(ctx context.Context) (IPAutoBanSettings, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"='ip_auto_ban_settings'`).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultIPAutoBanSettings(), nil
+		}
+		return IPAutoBanSettings{}, err
+	}
+	settings := DefaultIPAutoBanSettings()
+	if !value.Valid || json.Unmarshal([]byte(value.String), &settings) != nil {
+		return DefaultIPAutoBanSettings(), nil
+	}
+	return settings, nil
+}
+
+func (s *Store) UpsertIPAutoBanSettings(ctx context.Context, settings IPAutoBanSettings) (IPAutoBanSettings, error) {
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return IPAutoBanSettings{}, err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ('ip_auto_ban_settings',$1)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+	`, string(b))
+	if err != nil {
+		return IPAutoBanSettings{}, err
+	}
+	return settings, nil
+}
+
+// DefaultCompileOptions returns the compile options used per language for
+// problems that set none of their own.
+func DefaultCompileOptions() map[string]string {
+	return map[string]string{"cpp": "-O2"}
+}
+
+// GetDefaultCompileOptions returns the global per-language compile options.
+// A stored value that cannot be parsed falls back to DefaultCompileOptions.
+func (s *Store) GetDefaultCompileOptions(ctx context.Context) (map[string]string, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"='default_compile_options'`).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultCompileOptions(), nil
+		}
+		return nil, err
+	}
+	var opts map[string]string
+	if !value.Valid || json.Unmarshal([]byte(value.String), &opts) != nil || opts == nil {
+		return DefaultCompileOptions(), nil
+	}
+	return opts, nil
+}
+
+func (s *Store) UpsertDefaultCompileOptions(ctx context.Context, opts map[string]string) (map[string]string, error) {
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ('default_compile_options',$1)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+	`, string(b))
+	if err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// DefaultCodeTemplates returns the starter code offered per language when a
+// problem sets no template of its own.
+func DefaultCodeTemplates() map[string]string {
+	return map[string]string{
+		"cpp":    "#include <bits/stdc++.h>\nusing namespace std;\n\nint main() {\n    ios::sync_with_stdio(false);\n    cin.tie(nullptr);\n\n    return 0;\n}\n",
+		"python": "import sys\n\ninput = sys.stdin.readline\n\n",
+	}
+}
+
+// GetCodeTemplates returns the global per-language starter code. A stored
+// value replaces the defaults as a whole, so a language can be left empty.
+func (s *Store) GetCodeTemplates(ctx context.Context) (map[string]string, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"='code_templates'`).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultCodeTemplates(), nil
+		}
+		return nil, err
+	}
+	var templates map[string]string
+	if !value.Valid || json.Unmarshal([]byte(value.String), &templates) != nil || templates == nil {
+		return DefaultCodeTemplates(), nil
+	}
+	return templates, nil
+}
+
+func (s *Store) UpsertCodeTemplates(ctx context.Context, templates map[string]string) (map[string]string, error) {
+	b, err := json.Marshal(templates)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ('code_templates',$1)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+	`, string(b))
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// GuestRunSettings controls unauthenticated test runs. Guests may only run
+// code against the listed problems and at most RateLimit times per minute
+// per IP.
+type GuestRunSettings struct {
+	Enabled    bool  `json:"enabled"`
+	RateLimit  int   `json:"rateLimit"`
+	ProblemIDs []int `json:"problemIds"`
+}
+
+func DefaultGuestRunSettings() GuestRunSettings {
+	return GuestRunSettings{Enabled: false, RateLimit: 2, ProblemIDs: []int{}}
+}
+
+func (s *Store) GetGuestRunSettings(ctx context.Context) (GuestRunSettings, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"='guest_run_settings'`).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultGuestRunSettings(), nil
+		}
+		return GuestRunSettings{}, err
+	}
+	settings := DefaultGuestRunSettings()
+	if !value.Valid || json.Unmarshal([]byte(value.String), &settings) != nil {
+		return DefaultGuestRunSettings(), nil
+	}
+	if settings.ProblemIDs == nil {
+		settings.ProblemIDs = []int{}
+	}
+	return settings, nil
+}
+
+func (s *Store) UpsertGuestRunSettings(ctx context.Context, settings GuestRunSettings) (GuestRunSettings, error) {
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return GuestRunSettings{}, err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ('guest_run_settings',$1)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+	`, string(b))
+	if err != nil {
+		return GuestRunSettings{}, err
+	}
+	return settings, nil
+}
+
+// BannerSettings is the site-wide announcement shown above every page while
+// Active is set. UpdatedAt changes on every save so clients can show a
+// banner again after the user dismissed an earlier version of it.
+type BannerSettings struct {
+	Message   string     `json:"message"`
+	Severity  string     `json:"severity"`
+	Active    bool       `json:"active"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+func DefaultBannerSettings() BannerSettings {
+	return BannerSettings{Message: "", Severity: "info", Active: false}
+}
+
+func (s *Store) GetBannerSettings(ctx context.Context) (BannerSettings, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"='banner'`).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultBannerSettings(), nil
+		}
+		return BannerSettings{}, err
+	}
+	banner := DefaultBannerSettings()
+	if !value.Valid || json.Unmarshal([]byte(value.String), &banner) != nil {
+		return DefaultBannerSettings(), nil
+	}
+	return banner, nil
+}
+
+func (s *Store) UpsertBannerSettings(ctx context.Context, banner BannerSettings) (BannerSettings, error) {
+	b, err := json.Marshal(banner)
+	if err != nil {
+		return BannerSettings{}, err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ('banner',$1)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+	`, string(b))
+	if err != nil {
+		return BannerSettings{}, err
+	}
+	return banner, nil
+}
+
+// IsMaintenanceMode reports whether non-admin writes are blocked.
+func (s *Store) IsMaintenanceMode(ctx context.Context) (bool, error) {
+	var value sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT "value" FROM "Setting" WHERE "key"='maintenance_mode'`).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return value.Valid && value.String == "true", nil
+}
+
+func (s *Store) UpsertMaintenanceMode(ctx context.Context, enabled bool) (bool, error) {
+	value := "false"
+	if enabled {
+		value = "true"
+	}
+	var stored string
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO "Setting" ("key","value") VALUES ('maintenance_mode',$1)
+		ON CONFLICT ("key") DO UPDATE SET "value"=EXCLUDED."value"
+		RETURNING "value"
+	`, value).Scan(&stored)
+	if err != nil {
+		return false, err
+	}
+	return stored == "true", nil
+}
@@ -0,0 +1,299 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+)
+
+type ProblemStats struct {
+	ProblemID         int            `json:"problemId"`
+	TotalSubmissions  int            `json:"totalSubmissions"`
+	AcceptedCount     int            `json:"acceptedCount"`
+	DistinctUsers     int            `json:"distinctUsers"`
+	AverageScore      float64        `json:"averageScore"`
+	StatusCounts      map[string]int `json:"statusCounts"`
+	AvgAcceptedTimeMs *float64       `json:"avgAcceptedTimeMs"`
+	AvgAcceptedMemory *float64       `json:"avgAcceptedMemoryKb"`
+}
+
+// GetProblemStats aggregates all submissions of a problem, contest ones included.
+func (s *Store) GetProblemStats(ctx context.Context, problemID int) (_ ProblemStats, err error) {
+	ctx, cancel := withAggregateTimeout(ctx)
+	defer cancel()
+	defer func() { err = aggregateTimeoutError(ctx, err) }()
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM "Problem" WHERE "id"=$1)`, problemID).Scan(&exists); err != nil {
+		return ProblemStats{}, err
+	}
+	if !exists {
+		return ProblemStats{}, ErrNotFound
+	}
+
+	st := ProblemStats{ProblemID: problemID, StatusCounts: map[string]int{}}
+	var avgScore, avgTime, avgMem sql.NullFloat64
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE "status"='Accepted'),
+		       COUNT(DISTINCT "userId"),
+		       AVG(COALESCE("score",0)),
+		       AVG("timeUsed") FILTER (WHERE "status"='Accepted'),
+		       AVG("memoryUsed") FILTER (WHERE "status"='Accepted')
+		FROM "Submission"
+		WHERE "problemId"=$1
+	`, problemID).Scan(&st.TotalSubmissions, &st.AcceptedCount, &st.DistinctUsers, &avgScore, &avgTime, &avgMem)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return ProblemStats{}, err
+	}
+	st.AverageScore = avgScore.Float64
+	if avgTime.Valid {
+		v := avgTime.Float64
+		st.AvgAcceptedTimeMs = &v
+	}
+	if avgMem.Valid {
+		v := avgMem.Float64
+		st.AvgAcceptedMemory = &v
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT "status", COUNT(*)
+		FROM "Submission"
+		WHERE "problemId"=$1
+		GROUP BY "status"
+	`, problemID)
+	if err != nil {
+		return ProblemStats{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return ProblemStats{}, err
+		}
+		st.StatusCounts[status] = n
+	}
+	return st, rows.Err()
+}
+
+type SiteOverview struct {
+	TotalUsers       int `json:"totalUsers"`
+	TotalProblems    int `json:"totalProblems"`
+	TotalSubmissions int `json:"totalSubmissions"`
+	SubmissionsToday int `json:"submissionsToday"`
+	AcceptedToday    int `json:"acceptedToday"`
+}
+
+// GetSiteOverview counts site-wide activity; "today" is the database's current day.
+// Only visible problems are counted.
+func (s *Store) GetSiteOverview(ctx context.Context) (_ SiteOverview, err error) {
+	ctx, cancel := withAggregateTimeout(ctx)
+	defer cancel()
+	defer func() { err = aggregateTimeoutError(ctx, err) }()
+	var o SiteOverview
+	err = s.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM "User"),
+			(SELECT COUNT(*) FROM "Problem" WHERE `+publicProblemCond("")+`),
+			(SELECT COUNT(*) FROM "Submission"),
+			(SELECT COUNT(*) FROM "Submission" WHERE "createdAt">=date_trunc('day', NOW())),
+			(SELECT COUNT(*) FROM "Submission" WHERE "createdAt">=date_trunc('day', NOW()) AND "status"='Accepted')
+	`).Scan(&o.TotalUsers, &o.TotalProblems, &o.TotalSubmissions, &o.SubmissionsToday, &o.AcceptedToday)
+	return o, err
+}
+
+type RecentAccepted struct {
+	SubmissionID int       `json:"submissionId"`
+	Username     string    `json:"username"`
+	ProblemID    int       `json:"problemId"`
+	ProblemTitle string    `json:"problemTitle"`
+	Language     string    `json:"language"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// ListRecentAccepted returns the newest Accepted submissions outside contests,
+// skipping hidden problems and banned users.
+func (s *Store) ListRecentAccepted(ctx context.Context, limit int) ([]RecentAccepted, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT s."id", u."username", p."id", p."title", s."language", s."createdAt"
+		FROM "Submission" s
+		JOIN "User" u ON u."id"=s."userId"
+		JOIN "Problem" p ON p."id"=s."problemId"
+		WHERE s."status"='Accepted'
+		  AND s."contestId" IS NULL
+		  AND `+publicProblemCond("p.")+`
+		  AND u."isBanned"=false
+		ORDER BY s."createdAt" DESC, s."id" DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []RecentAccepted{}
+	for rows.Next() {
+		var ra RecentAccepted
+		if err := rows.Scan(&ra.SubmissionID, &ra.Username, &ra.ProblemID, &ra.ProblemTitle, &ra.Language, &ra.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, ra)
+	}
+	return out, rows.Err()
+}
+
+type FirstAccepted struct {
+	ProblemID  int       `json:"problemId"`
+	Difficulty string    `json:"difficulty"`
+	AcceptedAt time.Time `json:"acceptedAt"`
+}
+
+// ListFirstAccepted returns, per problem, the user's earliest Accepted
+// submission, oldest first. Verdicts still masked by a running OI contest
+// are skipped.
+func (s *Store) ListFirstAccepted(ctx context.Context, userID int) ([]FirstAccepted, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT "problemId", "difficulty", "createdAt"
+		FROM (
+			SELECT s."problemId", p."difficulty", s."createdAt",
+			       ROW_NUMBER() OVER (PARTITION BY s."problemId" ORDER BY s."createdAt" ASC, s."id" ASC) AS rn
+			FROM "Submission" s
+			JOIN "Problem" p ON p."id"=s."problemId"
+			LEFT JOIN "Contest" c ON c."id"=s."contestId"
+			WHERE s."userId"=$1
+			  AND s."status"='Accepted'
+			  AND (c."id" IS NULL OR c."rule"<>'OI' OR c."endTime"<=NOW())
+		) firsts
+		WHERE rn=1
+		ORDER BY "createdAt" ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []FirstAccepted{}
+	for rows.Next() {
+		var fa FirstAccepted
+		if err := rows.Scan(&fa.ProblemID, &fa.Difficulty, &fa.AcceptedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, fa)
+	}
+	return out, rows.Err()
+}
+
+type LanguageStats struct {
+	Language         string         `json:"language"`
+	TotalSubmissions int            `json:"totalSubmissions"`
+	AcceptedCount    int            `json:"acceptedCount"`
+	StatusCounts     map[string]int `json:"statusCounts"`
+	AcceptedRate     float64        `json:"acceptedRate"`
+	TLERate          float64        `json:"tleRate"`
+	CompileErrorRate float64        `json:"compileErrorRate"`
+	AvgTimeMs        *float64       `json:"avgTimeMs"`
+	AvgMemoryKb      *float64       `json:"avgMemoryKb"`
+	MaxMemoryKb      *int           `json:"maxMemoryKb"`
+}
+
+// GetLanguageStats groups submissions created in [from, to] by language.
+// Nil bounds are open. Averages only cover submissions that actually ran.
+func (s *Store) GetLanguageStats(ctx context.Context, from, to *time.Time) (_ []LanguageStats, err error) {
+	ctx, cancel := withAggregateTimeout(ctx)
+	defer cancel()
+	defer func() { err = aggregateTimeoutError(ctx, err) }()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT "language", "status", COUNT(*)
+		FROM "Submission"
+		WHERE ($1::timestamp IS NULL OR "createdAt">=$1)
+		  AND ($2::timestamp IS NULL OR "createdAt"<=$2)
+		GROUP BY "language", "status"
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byLang := map[string]*LanguageStats{}
+	var order []string
+	for rows.Next() {
+		var lang, status string
+		var n int
+		if err := rows.Scan(&lang, &status, &n); err != nil {
+			return nil, err
+		}
+		ls, ok := byLang[lang]
+		if !ok {
+			ls = &LanguageStats{Language: lang, StatusCounts: map[string]int{}}
+			byLang[lang] = ls
+			order = append(order, lang)
+		}
+		ls.StatusCounts[status] = n
+		ls.TotalSubmissions += n
+		if status == "Accepted" {
+			ls.AcceptedCount += n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	perfRows, err := s.db.QueryContext(ctx, `
+		SELECT "language", AVG("timeUsed"), AVG("memoryUsed"), MAX("memoryUsed")
+		FROM "Submission"
+		WHERE ($1::timestamp IS NULL OR "createdAt">=$1)
+		  AND ($2::timestamp IS NULL OR "createdAt"<=$2)
+		  AND "status" NOT IN ('Pending','Judging','Compilation Error','System Error')
+		GROUP BY "language"
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer perfRows.Close()
+	for perfRows.Next() {
+		var lang string
+		var avgTime, avgMem sql.NullFloat64
+		var maxMem sql.NullInt64
+		if err := perfRows.Scan(&lang, &avgTime, &avgMem, &maxMem); err != nil {
+			return nil, err
+		}
+		ls, ok := byLang[lang]
+		if !ok {
+			continue
+		}
+		if avgTime.Valid {
+			v := avgTime.Float64
+			ls.AvgTimeMs = &v
+		}
+		if avgMem.Valid {
+			v := avgMem.Float64
+			ls.AvgMemoryKb = &v
+		}
+		if maxMem.Valid {
+			v := int(maxMem.Int64)
+			ls.MaxMemoryKb = &v
+		}
+	}
+	if err := perfRows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]LanguageStats, 0, len(order))
+	for _, lang := range order {
+		ls := byLang[lang]
+		if ls.TotalSubmissions > 0 {
+			total := float64(ls.TotalSubmissions)
+			ls.AcceptedRate = float64(ls.AcceptedCount) / total
+			ls.TLERate = float64(ls.StatusCounts["Time Limit Exceeded"]) / total
+			ls.CompileErrorRate = float64(ls.StatusCounts["Compilation Error"]) / total
+		}
+		out = append(out, *ls)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TotalSubmissions != out[j].TotalSubmissions {
+			return out[i].TotalSubmissions > out[j].TotalSubmissions
+		}
+		return out[i].Language < out[j].Language
+	})
+	return out, nil
+}
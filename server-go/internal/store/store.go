@@ -1,13 +1,25 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 )
 
 var (
 	ErrNotFound        = errors.New("not found")
 	ErrUniqueViolation = errors.New("unique violation")
+	// ErrForeignKeyViolation, ErrCheckViolation and ErrInvalidValue are
+	// returned by ClassifyError for input the database rejected.
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrCheckViolation      = errors.New("check violation")
+	ErrInvalidValue        = errors.New("invalid value")
+	ErrNotAccepted         = errors.New("submission not accepted")
+	ErrNoTestCases         = errors.New("problem has no test cases")
+	// ErrQueryTimeout is returned when an aggregation outlives
+	// aggregateQueryTimeout.
+	ErrQueryTimeout = errors.New("query timed out")
 )
 
 type Store struct {
@@ -17,3 +29,42 @@ type Store struct {
 func New(db *sql.DB) *Store {
 	return &Store{db: db}
 }
+
+// Ping checks that the database is reachable.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// aggregateQueryTimeout bounds the leaderboard and statistics aggregations,
+// which scan every submission of a contest, problem or the whole site, so a
+// runaway plan cannot hold a pooled connection for the whole request.
+const aggregateQueryTimeout = 15 * time.Second
+
+// withAggregateTimeout derives the context for an aggregation. Cancelling
+// it makes pgx cancel the running statement on the server.
+func withAggregateTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, aggregateQueryTimeout)
+}
+
+// aggregateTimeoutError maps an error caused by ctx's deadline to
+// ErrQueryTimeout and returns any other error unchanged.
+func aggregateTimeoutError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrQueryTimeout
+	}
+	return err
+}
+
+// withTx runs fn in a transaction, committing when fn returns nil and
+// rolling back otherwise.
+func (s *Store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
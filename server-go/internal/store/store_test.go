@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"onlinejudge-server-go/internal/testdb"
+)
+
+// txRecorder is a database/sql driver that records transaction outcomes
+// and the statements executed, failing the statement numbered failAt
+// (1-based; 0 never fails).
+type txRecorder struct {
+	mu        sync.Mutex
+	failAt    int
+	execs     []string
+	commits   int
+	rollbacks int
+}
+
+var errInjected = errors.New("injected failure")
+
+func (r *txRecorder) Connect(context.Context) (driver.Conn, error) { return r, nil }
+func (r *txRecorder) Driver() driver.Driver                        { return nil }
+
+func (r *txRecorder) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (r *txRecorder) Close() error              { return nil }
+func (r *txRecorder) Begin() (driver.Tx, error) { return r, nil }
+
+func (r *txRecorder) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.execs = append(r.execs, strings.TrimSpace(query))
+	if len(r.execs) == r.failAt {
+		return nil, errInjected
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (r *txRecorder) Commit() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commits++
+	return nil
+}
+
+func (r *txRecorder) Rollback() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rollbacks++
+	return nil
+}
+
+func newRecorderStore(failAt int) (*Store, *txRecorder) {
+	r := &txRecorder{failAt: failAt}
+	db := sql.OpenDB(r)
+	db.SetMaxOpenConns(1)
+	return New(db), r
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+
+	s, r := newRecorderStore(0)
+	if err := s.withTx(ctx, func(tx *sql.Tx) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if r.commits != 1 || r.rollbacks != 0 {
+		t.Errorf("success: %d commits, %d rollbacks; want 1, 0", r.commits, r.rollbacks)
+	}
+
+	s, r = newRecorderStore(0)
+	fnErr := errors.New("fn failed")
+	if err := s.withTx(ctx, func(tx *sql.Tx) error { return fnErr }); !errors.Is(err, fnErr) {
+		t.Fatalf("withTx = %v, want %v", err, fnErr)
+	}
+	if r.commits != 0 || r.rollbacks != 1 {
+		t.Errorf("failure: %d commits, %d rollbacks; want 0, 1", r.commits, r.rollbacks)
+	}
+}
+
+func TestDeleteUserRollsBackMidFailure(t *testing.T) {
+	// The third statement deletes the submissions; the user row is never reached.
+	s, r := newRecorderStore(3)
+	if err := s.DeleteUser(context.Background(), 1); !errors.Is(err, errInjected) {
+		t.Fatalf("DeleteUser = %v, want %v", err, errInjected)
+	}
+	if r.commits != 0 || r.rollbacks != 1 {
+		t.Errorf("%d commits, %d rollbacks; want 0, 1", r.commits, r.rollbacks)
+	}
+	for _, q := range r.execs {
+		if strings.HasPrefix(q, `DELETE FROM "User"`) {
+			t.Errorf("statement after the failure executed: %s", q)
+		}
+	}
+}
+
+func TestCreateProblemRollsBackMidFailure(t *testing.T) {
+	db := testdb.Open(t)
+	s := New(db)
+	ctx := context.Background()
+
+	// PostgreSQL rejects NUL bytes in text, so the second test case fails
+	// after the problem and the first test case have been inserted.
+	_, err := s.CreateProblem(ctx, CreateProblemParams{
+		Title:       "rollback",
+		Description: "d",
+		TimeLimit:   1000,
+		MemoryLimit: 128,
+		Difficulty:  "LEVEL2",
+		Tags:        []string{},
+		TestCases: []TestCaseInput{
+			{Input: "1", ExpectedOutput: "1"},
+			{Input: "bad\x00input", ExpectedOutput: "2"},
+		},
+	})
+	// 22021 is character_not_in_repertoire, raised by the second test case
+	// rather than by the problem row.
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "22021" {
+		t.Fatalf("CreateProblem = %v, want a 22021 error from the test case insert", err)
+	}
+	for _, table := range []string{`"Problem"`, `"TestCase"`} {
+		var n int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("%s has %d rows after the rollback, want 0", table, n)
+		}
+	}
+}
@@ -18,6 +18,7 @@ type SubmissionListItem struct {
 	TimeUsed   *int      `json:"timeUsed"`
 	MemoryUsed *int      `json:"memoryUsed"`
 	Score      *int      `json:"score"`
+	Label      *string   `json:"label"`
 	CreatedAt  time.Time `json:"createdAt"`
 	ProblemID  int       `json:"problemId"`
 	Problem    struct {
@@ -67,7 +68,7 @@ func (s *Store) ListSubmissions(ctx context.Context, p ListSubmissionsParams) ([
 
 	args = append(args, limit) 
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT s."id",s."code",s."language",s."status",s."output",s."timeUsed",s."memoryUsed",s."score",s."createdAt",s."problemId",
+		SELECT s."id",s."code",s."language",s."status",s."output",s."timeUsed",s."memoryUsed",s."score",s."label",s."createdAt",s."problemId",
 		       p."title", u."username",
 		       c."rule", c."endTime"
 		FROM "Submission" s
@@ -91,7 +92,7 @@ func (s *Store) ListSubmissions(ctx context.Context, p ListSubmissionsParams) ([
 		var rule sql.NullString
 		var endTime sql.NullTime
 
-		if err := rows.Scan(&item.ID, &item.Code, &item.Language, &item.Status, &item.Output, &item.TimeUsed, &item.MemoryUsed, &item.Score, &item.CreatedAt, &item.ProblemID, &item.Problem.Title, &item.User.Username, &rule, &endTime); err != nil {
+		if err := rows.Scan(&item.ID, &item.Code, &item.Language, &item.Status, &item.Output, &item.TimeUsed, &item.MemoryUsed, &item.Score, &item.Label, &item.CreatedAt, &item.ProblemID, &item.Problem.Title, &item.User.Username, &rule, &endTime); err != nil {
 			return nil, err
 		}
 
@@ -118,7 +119,9 @@ type Submission struct {
 	TimeUsed        *int            `json:"timeUsed"`
 	MemoryUsed      *int            `json:"memoryUsed"`
 	Score           *int            `json:"score"`
+	Label           *string         `json:"label"`
 	TestCaseResults json.RawMessage `json:"testCaseResults"`
+	VerdictSummary  json.RawMessage `json:"verdictSummary"`
 	CreatedAt       time.Time       `json:"createdAt"`
 	ProblemID       int             `json:"problemId"`
 	UserID          *int            `json:"userId"`
@@ -200,6 +203,7 @@ func (s *Store) GetSubmissionWithProblemAndUser(ctx context.Context, submissionI
 	var memUsed sql.NullInt64
 	var score sql.NullInt64
 	var tcJSON []byte
+	var summaryJSON []byte
 	var userID sql.NullInt64
 	var contestID sql.NullInt64
 	var tags PGTextArray
@@ -207,8 +211,8 @@ func (s *Store) GetSubmissionWithProblemAndUser(ctx context.Context, submissionI
 	var endTime sql.NullTime
 
 	err := s.db.QueryRowContext(ctx, `
-		SELECT s."id",s."code",s."language",s."status",s."output",s."timeUsed",s."memoryUsed",s."score",s."testCaseResults",s."createdAt",s."problemId",s."userId",s."contestId",
-		       p."id",p."title",p."description",p."timeLimit",p."memoryLimit",p."config",p."defaultCompileOptions",p."difficulty",p."tags",p."visible",p."createdAt",p."updatedAt",
+		SELECT s."id",s."code",s."language",s."status",s."output",s."timeUsed",s."memoryUsed",s."score",s."label",s."testCaseResults",s."verdictSummary",s."createdAt",s."problemId",s."userId",s."contestId",
+		       p."id",p."title",p."description",p."timeLimit",p."memoryLimit",p."config",p."defaultCompileOptions",p."difficulty",p."tags",p."visible",p."visibleFrom",p."createdAt",p."updatedAt",
 		       u."id",u."username",u."role",
 		       c."rule", c."endTime"
 		FROM "Submission" s
@@ -217,8 +221,8 @@ func (s *Store) GetSubmissionWithProblemAndUser(ctx context.Context, submissionI
 		LEFT JOIN "Contest" c ON c."id"=s."contestId"
 		WHERE s."id"=$1
 	`, submissionID).Scan(
-		&sub.ID, &sub.Code, &sub.Language, &sub.Status, &output, &timeUsed, &memUsed, &score, &tcJSON, &sub.CreatedAt, &sub.ProblemID, &userID, &contestID,
-		&sub.Problem.ID, &sub.Problem.Title, &sub.Problem.Description, &sub.Problem.TimeLimit, &sub.Problem.MemoryLimit, &cfg, &sub.Problem.DefaultCompileOptions, &sub.Problem.Difficulty, &tags, &sub.Problem.Visible, &sub.Problem.CreatedAt, &sub.Problem.UpdatedAt,
+		&sub.ID, &sub.Code, &sub.Language, &sub.Status, &output, &timeUsed, &memUsed, &score, &sub.Label, &tcJSON, &summaryJSON, &sub.CreatedAt, &sub.ProblemID, &userID, &contestID,
+		&sub.Problem.ID, &sub.Problem.Title, &sub.Problem.Description, &sub.Problem.TimeLimit, &sub.Problem.MemoryLimit, &cfg, &sub.Problem.DefaultCompileOptions, &sub.Problem.Difficulty, &tags, &sub.Problem.Visible, &sub.Problem.VisibleFrom, &sub.Problem.CreatedAt, &sub.Problem.UpdatedAt,
 		&sub.User.ID, &sub.User.Username, &sub.User.Role,
 		&rule, &endTime,
 	)
@@ -244,6 +248,7 @@ func (s *Store) GetSubmissionWithProblemAndUser(ctx context.Context, submissionI
 		memUsed = sql.NullInt64{}
 		score = sql.NullInt64{}
 		tcJSON = nil // Hide test case results
+		summaryJSON = nil
 	}
 
 	if output.Valid {
@@ -264,6 +269,9 @@ func (s *Store) GetSubmissionWithProblemAndUser(ctx context.Context, submissionI
 	if tcJSON != nil {
 		sub.TestCaseResults = tcJSON
 	}
+	if summaryJSON != nil {
+		sub.VerdictSummary = summaryJSON
+	}
 	if cfg != nil {
 		sub.Problem.Config = cfg
 	}
@@ -306,6 +314,11 @@ type JudgeCaseResult struct {
 	TimeUsed   int    `json:"timeUsed"`
 	MemoryUsed int    `json:"memoryUsed"`
 	Output     string `json:"output"`
+	// Runtime Error details; only admins may see them since stderr can echo
+	// hidden test data.
+	Stderr   string `json:"stderr,omitempty"`
+	ExitCode int    `json:"exitCode,omitempty"`
+	Signal   string `json:"signal,omitempty"`
 }
 
 func (s *Store) UpdateSubmissionStatus(ctx context.Context, submissionID int, status string, output string) error {
@@ -321,13 +334,119 @@ type UpdateSubmissionJudgedParams struct {
 	Score         int
 	TestCaseJSON  json.RawMessage
 	OutputMessage string
+	// VerdictSummary counts the per-case verdicts. Unlike TestCaseJSON it
+	// survives TrimOldSubmissions.
+	VerdictSummary json.RawMessage
 }
 
 func (s *Store) UpdateSubmissionJudged(ctx context.Context, p UpdateSubmissionJudgedParams) error {
 	_, err := s.db.ExecContext(ctx, `
 		UPDATE "Submission"
-		SET "status"=$1,"timeUsed"=$2,"memoryUsed"=$3,"score"=$4,"testCaseResults"=$5,"output"=$6
+		SET "status"=$1,"timeUsed"=$2,"memoryUsed"=$3,"score"=$4,"testCaseResults"=$5,"output"=$6,"verdictSummary"=$8
 		WHERE "id"=$7
-	`, p.Status, p.TimeUsed, p.MemoryUsed, p.Score, p.TestCaseJSON, p.OutputMessage, p.ID)
+	`, p.Status, p.TimeUsed, p.MemoryUsed, p.Score, p.TestCaseJSON, p.OutputMessage, p.ID, p.VerdictSummary)
 	return err
 }
+
+// TrimOldSubmissions clears code, output and per-case results of finished
+// non-contest submissions created before cutoff, keeping status, score and
+// timings for statistics. Featured solutions are left intact. Rows are
+// processed in batches of batchSize to keep locks short; it returns the
+// number of rows trimmed.
+func (s *Store) TrimOldSubmissions(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		res, err := s.db.ExecContext(ctx, `
+			UPDATE "Submission"
+			SET "code"='',"output"=NULL,"testCaseResults"=NULL
+			WHERE "id" IN (
+				SELECT "id" FROM "Submission"
+				WHERE "createdAt"<$1
+				  AND "contestId" IS NULL
+				  AND "status" NOT IN ('Pending','Judging')
+				  AND ("code"<>'' OR "output" IS NOT NULL OR "testCaseResults" IS NOT NULL)
+				  AND NOT EXISTS (SELECT 1 FROM "FeaturedSolution" f WHERE f."submissionId" = "Submission"."id")
+				LIMIT $2
+			)
+		`, cutoff, batchSize)
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+		if n < int64(batchSize) {
+			return total, nil
+		}
+	}
+}
+
+// CountSubmissionsByStatus counts submissions in each of the given statuses.
+// Statuses with no rows are reported as zero.
+func (s *Store) CountSubmissionsByStatus(ctx context.Context, statuses ...string) (map[string]int, error) {
+	counts := make(map[string]int, len(statuses))
+	for _, st := range statuses {
+		counts[st] = 0
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT "status", COUNT(*) FROM "Submission"
+		WHERE "status" = ANY($1)
+		GROUP BY "status"
+	`, statuses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var st string
+		var n int
+		if err := rows.Scan(&st, &n); err != nil {
+			return nil, err
+		}
+		counts[st] = n
+	}
+	return counts, rows.Err()
+}
+
+// SubmissionCode is the owner and source of a submission.
+type SubmissionCode struct {
+	ID        int
+	UserID    *int
+	ProblemID int
+	Language  string
+	Code      string
+}
+
+// GetSubmissionCode loads just the owner and code of a submission.
+func (s *Store) GetSubmissionCode(ctx context.Context, id int) (SubmissionCode, error) {
+	var c SubmissionCode
+	var userID sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT "id","userId","problemId","language","code" FROM "Submission" WHERE "id"=$1
+	`, id).Scan(&c.ID, &userID, &c.ProblemID, &c.Language, &c.Code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SubmissionCode{}, ErrNotFound
+		}
+		return SubmissionCode{}, err
+	}
+	if userID.Valid {
+		v := int(userID.Int64)
+		c.UserID = &v
+	}
+	return c, nil
+}
+
+// SetSubmissionLabel sets or, when label is nil, clears the label of a
+// submission owned by userID. It returns ErrNotFound if no such submission
+// belongs to the user.
+func (s *Store) SetSubmissionLabel(ctx context.Context, id, userID int, label *string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE "Submission" SET "label"=$1 WHERE "id"=$2 AND "userId"=$3`, label, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -6,8 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
-
-	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type User struct {
@@ -75,11 +73,7 @@ type CreateUserParams struct {
 func (s *Store) CreateUser(ctx context.Context, p CreateUserParams) error {
 	_, err := s.db.ExecContext(ctx, `INSERT INTO "User" ("username","password","role") VALUES ($1,$2,$3)`, p.Username, p.Password, p.Role)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			return ErrUniqueViolation
-		}
-		return err
+		return uniqueViolation(err)
 	}
 	return nil
 }
@@ -114,6 +108,34 @@ func (s *Store) UpdateUserPreferences(ctx context.Context, userID int, preferenc
 	return err
 }
 
+// MergeUserPreferences replaces the user's preferences with the result of
+// merge applied to the current ones (nil when unset). The row is locked
+// meanwhile, so concurrent merges do not lose each other's keys. Errors from
+// merge are returned unchanged.
+func (s *Store) MergeUserPreferences(ctx context.Context, userID int, merge func(current json.RawMessage) (json.RawMessage, error)) (json.RawMessage, error) {
+	var merged json.RawMessage
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var current []byte
+		err := tx.QueryRowContext(ctx, `SELECT "preferences" FROM "User" WHERE "id"=$1 FOR UPDATE`, userID).Scan(&current)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		merged, err = merge(current)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE "User" SET "preferences"=$1 WHERE "id"=$2`, merged, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
 func (s *Store) UpdateUserPassword(ctx context.Context, id int, hashed string) error {
 	res, err := s.db.ExecContext(ctx, `UPDATE "User" SET "password"=$1 WHERE "id"=$2`, hashed, id)
 	if err != nil {
@@ -190,30 +212,31 @@ func (s *Store) UnbanUser(ctx context.Context, userID int) error {
 
 // DeleteUser deletes a user and their submissions
 func (s *Store) DeleteUser(ctx context.Context, userID int) error {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	// Delete contest participants
-	_, _ = tx.ExecContext(ctx, `DELETE FROM "ContestParticipant" WHERE "userId" = $1`, userID)
-	// Delete contest password attempts
-	_, _ = tx.ExecContext(ctx, `DELETE FROM "ContestPasswordAttempt" WHERE "userId" = $1`, userID)
-	// Delete submissions
-	_, _ = tx.ExecContext(ctx, `DELETE FROM "Submission" WHERE "userId" = $1`, userID)
-	// Delete banned IPs associated with user
-	_, _ = tx.ExecContext(ctx, `UPDATE "BannedIP" SET "userId" = NULL WHERE "userId" = $1`, userID)
-	// Delete user
-	res, err := tx.ExecContext(ctx, `DELETE FROM "User" WHERE "id" = $1`, userID)
-	if err != nil {
-		return err
-	}
-	if n, _ := res.RowsAffected(); n == 0 {
-		return ErrNotFound
-	}
-
-	return tx.Commit()
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		for _, q := range []string{
+			// Delete contest participants
+			`DELETE FROM "ContestParticipant" WHERE "userId" = $1`,
+			// Delete contest password attempts
+			`DELETE FROM "ContestPasswordAttempt" WHERE "userId" = $1`,
+			// Delete submissions
+			`DELETE FROM "Submission" WHERE "userId" = $1`,
+			// Delete banned IPs associated with user
+			`UPDATE "BannedIP" SET "userId" = NULL WHERE "userId" = $1`,
+		} {
+			if _, err := tx.ExecContext(ctx, q, userID); err != nil {
+				return err
+			}
+		}
+		// Delete user
+		res, err := tx.ExecContext(ctx, `DELETE FROM "User" WHERE "id" = $1`, userID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
 }
 
 // DeleteUserSubmissions deletes all submissions for a user
@@ -243,13 +266,13 @@ func (s *Store) BanIP(ctx context.Context, ip string, userID *int, reason string
 		INSERT INTO "BannedIP" ("ip", "userId", "reason", "expiresAt")
 		VALUES ($1, $2, $3, $4)
 		ON CONFLICT ("ip") DO UPDATE SET "userId" = $2, "reason" = $3, "expiresAt" = $4, "createdAt" = CURRENT_TIMESTAMP
-	`, ip, userID, reason, expiresAt)
+	`, NormalizeIP(ip), userID, reason, expiresAt)
 	return err
 }
 
 // UnbanIP removes an IP from the banned list
 func (s *Store) UnbanIP(ctx context.Context, ip string) error {
-	res, err := s.db.ExecContext(ctx, `DELETE FROM "BannedIP" WHERE "ip" = $1`, ip)
+	res, err := s.db.ExecContext(ctx, `DELETE FROM "BannedIP" WHERE "ip" = $1`, NormalizeIP(ip))
 	if err != nil {
 		return err
 	}
@@ -259,13 +282,19 @@ func (s *Store) UnbanIP(ctx context.Context, ip string) error {
 	return nil
 }
 
-// IsIPBanned checks if an IP is banned
+// IsIPBanned checks if an IP is banned. An active WHITELIST mark overrides
+// any ban on the IP.
 func (s *Store) IsIPBanned(ctx context.Context, ip string) (bool, error) {
 	var id int
 	err := s.db.QueryRowContext(ctx, `
-		SELECT "id" FROM "BannedIP" 
-		WHERE "ip" = $1 AND ("expiresAt" IS NULL OR "expiresAt" > CURRENT_TIMESTAMP)
-	`, ip).Scan(&id)
+		SELECT b."id" FROM "BannedIP" b
+		WHERE b."ip" = $1 AND (b."expiresAt" IS NULL OR b."expiresAt" > CURRENT_TIMESTAMP)
+		  AND NOT EXISTS (
+			SELECT 1 FROM "IPMark" m
+			WHERE m."ipAddress" = b."ip" AND m."markType" = 'WHITELIST'
+			  AND (m."expireAt" IS NULL OR m."expireAt" > CURRENT_TIMESTAMP)
+		  )
+	`, NormalizeIP(ip)).Scan(&id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return false, nil
@@ -316,14 +345,35 @@ func (s *Store) ListBannedIPs(ctx context.Context) ([]BannedIP, error) {
 	return ips, nil
 }
 
-// CountUserSubmissionsInWindow counts submissions by a user in a time window
-func (s *Store) CountUserSubmissionsInWindow(ctx context.Context, userID int, windowStart time.Time) (int, error) {
+// CountUserSubmissionsInWindow counts submissions by a user in a time window.
+// Contest and practice submissions are counted separately: with contestID
+// set only submissions to that contest count, with nil only practice ones.
+func (s *Store) CountUserSubmissionsInWindow(ctx context.Context, userID int, contestID *int, windowStart time.Time) (int, error) {
 	var count int
 	err := s.db.QueryRowContext(ctx, `
-		SELECT COUNT(*) FROM "Submission" WHERE "userId" = $1 AND "createdAt" >= $2
-	`, userID, windowStart).Scan(&count)
+		SELECT COUNT(*) FROM "Submission"
+		WHERE "userId" = $1 AND "createdAt" >= $2 AND "contestId" IS NOT DISTINCT FROM $3
+	`, userID, windowStart, contestID).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
 	return count, nil
 }
+
+// OldestUserSubmissionInWindow returns the time of the user's earliest
+// submission at or after windowStart, or ErrNotFound if there is none.
+// contestID selects the bucket as in CountUserSubmissionsInWindow.
+func (s *Store) OldestUserSubmissionInWindow(ctx context.Context, userID int, contestID *int, windowStart time.Time) (time.Time, error) {
+	var oldest sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT MIN("createdAt") FROM "Submission"
+		WHERE "userId" = $1 AND "createdAt" >= $2 AND "contestId" IS NOT DISTINCT FROM $3
+	`, userID, windowStart, contestID).Scan(&oldest)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !oldest.Valid {
+		return time.Time{}, ErrNotFound
+	}
+	return oldest.Time, nil
+}
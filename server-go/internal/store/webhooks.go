@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Webhook is an outbound endpoint notified when a submission finishes judging.
+// The secret is only used for signing and never serialized.
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+const webhookColumns = `"id","url","secret","enabled","createdAt","updatedAt"`
+
+func scanWebhook(row interface{ Scan(...any) error }) (Webhook, error) {
+	var h Webhook
+	err := row.Scan(&h.ID, &h.URL, &h.Secret, &h.Enabled, &h.CreatedAt, &h.UpdatedAt)
+	return h, err
+}
+
+func (s *Store) listWebhooks(ctx context.Context, onlyEnabled bool) ([]Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM "Webhook"`
+	if onlyEnabled {
+		query += ` WHERE "enabled"=true`
+	}
+	query += ` ORDER BY "id" ASC`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Webhook{}
+	for rows.Next() {
+		h, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, h)
+	}
+	return items, rows.Err()
+}
+
+func (s *Store) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	return s.listWebhooks(ctx, false)
+}
+
+func (s *Store) ListEnabledWebhooks(ctx context.Context) ([]Webhook, error) {
+	return s.listWebhooks(ctx, true)
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, url, secret string, enabled bool) (Webhook, error) {
+	return scanWebhook(s.db.QueryRowContext(ctx, `
+		INSERT INTO "Webhook" ("url","secret","enabled","updatedAt")
+		VALUES ($1,$2,$3,NOW())
+		RETURNING `+webhookColumns, url, secret, enabled))
+}
+
+// UpdateWebhook changes the given fields; nil leaves a field unchanged.
+func (s *Store) UpdateWebhook(ctx context.Context, id int, url, secret *string, enabled *bool) (Webhook, error) {
+	h, err := scanWebhook(s.db.QueryRowContext(ctx, `
+		UPDATE "Webhook"
+		SET "url"=COALESCE($2,"url"),"secret"=COALESCE($3,"secret"),"enabled"=COALESCE($4,"enabled"),"updatedAt"=NOW()
+		WHERE "id"=$1
+		RETURNING `+webhookColumns, id, url, secret, enabled))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Webhook{}, ErrNotFound
+		}
+		return Webhook{}, err
+	}
+	return h, nil
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM "Webhook" WHERE "id"=$1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SubmissionOwner identifies who made a submission, for outbound notifications.
+type SubmissionOwner struct {
+	UserID    *int
+	Username  string
+	ContestID *int
+}
+
+func (s *Store) GetSubmissionOwner(ctx context.Context, submissionID int) (SubmissionOwner, error) {
+	var o SubmissionOwner
+	var userID, contestID sql.NullInt64
+	var username sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT s."userId", u."username", s."contestId"
+		FROM "Submission" s
+		LEFT JOIN "User" u ON u."id"=s."userId"
+		WHERE s."id"=$1
+	`, submissionID).Scan(&userID, &username, &contestID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SubmissionOwner{}, ErrNotFound
+		}
+		return SubmissionOwner{}, err
+	}
+	if userID.Valid {
+		v := int(userID.Int64)
+		o.UserID = &v
+	}
+	o.Username = username.String
+	if contestID.Valid {
+		v := int(contestID.Int64)
+		o.ContestID = &v
+	}
+	return o, nil
+}
@@ -0,0 +1,60 @@
+// Package testdb gives tests a freshly migrated PostgreSQL schema of their
+// own. Tests using it are skipped unless TEST_DATABASE_URL points at a
+// database in which they may create and drop schemas.
+package testdb
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"onlinejudge-server-go/internal/migrate"
+	"onlinejudge-server-go/prisma"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Open creates a schema named after a random suffix, applies every migration
+// to it and returns a handle whose connections use it. The schema is dropped
+// when the test ends.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := strings.TrimSpace(os.Getenv("TEST_DATABASE_URL"))
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("parse TEST_DATABASE_URL: %v", err)
+	}
+
+	suffix := make([]byte, 6)
+	_, _ = rand.Read(suffix)
+	schema := "test_" + hex.EncodeToString(suffix)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	admin := stdlib.OpenDB(*cfg.Copy())
+	if _, err := admin.ExecContext(ctx, `CREATE SCHEMA "`+schema+`"`); err != nil {
+		admin.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = admin.ExecContext(context.Background(), `DROP SCHEMA "`+schema+`" CASCADE`)
+		admin.Close()
+	})
+
+	cfg.RuntimeParams["search_path"] = schema
+	db := stdlib.OpenDB(*cfg)
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrate.Up(ctx, db, prisma.Migrations); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
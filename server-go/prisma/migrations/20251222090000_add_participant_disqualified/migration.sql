@@ -0,0 +1 @@
+ALTER TABLE "ContestParticipant" ADD COLUMN IF NOT EXISTS "disqualified" BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,17 @@
+ALTER TABLE "Problem" ADD COLUMN IF NOT EXISTS "createdBy" INTEGER;
+ALTER TABLE "Problem" ADD COLUMN IF NOT EXISTS "updatedBy" INTEGER;
+ALTER TABLE "Contest" ADD COLUMN IF NOT EXISTS "createdBy" INTEGER;
+ALTER TABLE "Contest" ADD COLUMN IF NOT EXISTS "updatedBy" INTEGER;
+
+DO $$ BEGIN
+    ALTER TABLE "Problem" ADD CONSTRAINT "Problem_createdBy_fkey" FOREIGN KEY ("createdBy") REFERENCES "User"("id") ON DELETE SET NULL ON UPDATE CASCADE;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+DO $$ BEGIN
+    ALTER TABLE "Problem" ADD CONSTRAINT "Problem_updatedBy_fkey" FOREIGN KEY ("updatedBy") REFERENCES "User"("id") ON DELETE SET NULL ON UPDATE CASCADE;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+DO $$ BEGIN
+    ALTER TABLE "Contest" ADD CONSTRAINT "Contest_createdBy_fkey" FOREIGN KEY ("createdBy") REFERENCES "User"("id") ON DELETE SET NULL ON UPDATE CASCADE;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+DO $$ BEGIN
+    ALTER TABLE "Contest" ADD CONSTRAINT "Contest_updatedBy_fkey" FOREIGN KEY ("updatedBy") REFERENCES "User"("id") ON DELETE SET NULL ON UPDATE CASCADE;
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS "Submission_userId_status_problemId_createdAt_idx" ON "Submission"("userId", "status", "problemId", "createdAt");
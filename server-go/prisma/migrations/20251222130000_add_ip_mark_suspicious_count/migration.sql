@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "IPMark" ADD COLUMN "suspiciousCount" INTEGER NOT NULL DEFAULT 0;
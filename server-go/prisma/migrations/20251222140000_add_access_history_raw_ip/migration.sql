@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "AccessHistory" ADD COLUMN "rawIp" TEXT;
+
+-- AlterTable
+ALTER TABLE "AccessHistoryArchive" ADD COLUMN "rawIp" TEXT;
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "Contest" ADD COLUMN "scoringMode" TEXT NOT NULL DEFAULT 'DEFAULT';
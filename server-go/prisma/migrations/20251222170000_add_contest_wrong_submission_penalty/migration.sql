@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "Contest" ADD COLUMN "wrongSubmissionPenalty" INTEGER NOT NULL DEFAULT 0;
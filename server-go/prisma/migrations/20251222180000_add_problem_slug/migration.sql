@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "Problem" ADD COLUMN "slug" TEXT;
+
+-- CreateIndex
+CREATE UNIQUE INDEX "Problem_slug_key" ON "Problem"("slug");
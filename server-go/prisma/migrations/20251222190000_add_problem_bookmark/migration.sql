@@ -0,0 +1,17 @@
+-- CreateTable
+CREATE TABLE "ProblemBookmark" (
+    "userId" INTEGER NOT NULL,
+    "problemId" INTEGER NOT NULL,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "ProblemBookmark_pkey" PRIMARY KEY ("userId","problemId")
+);
+
+-- CreateIndex
+CREATE INDEX "ProblemBookmark_problemId_idx" ON "ProblemBookmark"("problemId");
+
+-- AddForeignKey
+ALTER TABLE "ProblemBookmark" ADD CONSTRAINT "ProblemBookmark_userId_fkey" FOREIGN KEY ("userId") REFERENCES "User"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "ProblemBookmark" ADD CONSTRAINT "ProblemBookmark_problemId_fkey" FOREIGN KEY ("problemId") REFERENCES "Problem"("id") ON DELETE CASCADE ON UPDATE CASCADE;
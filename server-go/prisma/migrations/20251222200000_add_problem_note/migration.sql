@@ -0,0 +1,19 @@
+-- CreateTable
+CREATE TABLE "ProblemNote" (
+    "userId" INTEGER NOT NULL,
+    "problemId" INTEGER NOT NULL,
+    "content" TEXT NOT NULL,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updatedAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "ProblemNote_pkey" PRIMARY KEY ("userId","problemId")
+);
+
+-- CreateIndex
+CREATE INDEX "ProblemNote_problemId_idx" ON "ProblemNote"("problemId");
+
+-- AddForeignKey
+ALTER TABLE "ProblemNote" ADD CONSTRAINT "ProblemNote_userId_fkey" FOREIGN KEY ("userId") REFERENCES "User"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "ProblemNote" ADD CONSTRAINT "ProblemNote_problemId_fkey" FOREIGN KEY ("problemId") REFERENCES "Problem"("id") ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "Submission" ADD COLUMN "label" TEXT;
@@ -0,0 +1,21 @@
+-- CreateTable
+CREATE TABLE "FeaturedSolution" (
+    "userId" INTEGER NOT NULL,
+    "problemId" INTEGER NOT NULL,
+    "submissionId" INTEGER NOT NULL,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "FeaturedSolution_pkey" PRIMARY KEY ("userId","problemId")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "FeaturedSolution_submissionId_key" ON "FeaturedSolution"("submissionId");
+
+-- AddForeignKey
+ALTER TABLE "FeaturedSolution" ADD CONSTRAINT "FeaturedSolution_userId_fkey" FOREIGN KEY ("userId") REFERENCES "User"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "FeaturedSolution" ADD CONSTRAINT "FeaturedSolution_problemId_fkey" FOREIGN KEY ("problemId") REFERENCES "Problem"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "FeaturedSolution" ADD CONSTRAINT "FeaturedSolution_submissionId_fkey" FOREIGN KEY ("submissionId") REFERENCES "Submission"("id") ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "Problem" ADD COLUMN     "visibleFrom" TIMESTAMP(3);
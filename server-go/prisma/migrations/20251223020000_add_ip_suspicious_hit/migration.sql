@@ -0,0 +1,18 @@
+-- CreateTable
+CREATE TABLE "IPSuspiciousHit" (
+    "id" SERIAL NOT NULL,
+    "ipAddress" TEXT NOT NULL,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "IPSuspiciousHit_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "IPSuspiciousHit_ipAddress_createdAt_idx" ON "IPSuspiciousHit"("ipAddress", "createdAt");
+
+-- Carry existing counts over as hits recorded now
+INSERT INTO "IPSuspiciousHit" ("ipAddress")
+SELECT m."ipAddress" FROM "IPMark" m, generate_series(1, m."suspiciousCount");
+
+-- AlterTable
+ALTER TABLE "IPMark" DROP COLUMN "suspiciousCount";
@@ -0,0 +1,24 @@
+// Package prisma embeds the Prisma migrations so the server can apply them
+// at startup. schema.prisma stays the source of truth for the schema.
+package prisma
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*/migration.sql
+var embedded embed.FS
+
+// Migrations holds one directory per migration, each with a migration.sql.
+var Migrations = mustSub(embedded, "migrations")
+
+// mustSub panics if dir is not a valid path, which can only happen if the
+// embed pattern above and dir disagree.
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,33 @@
+package prisma
+
+import (
+	"io/fs"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var migrationName = regexp.MustCompile(`^([0-9]{14})_[a-z0-9_]+$`)
+
+func TestMigrationNames(t *testing.T) {
+	entries, err := fs.ReadDir(Migrations, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for _, e := range entries {
+		m := migrationName.FindStringSubmatch(e.Name())
+		if !e.IsDir() || m == nil {
+			t.Errorf("%s: want a directory named <YYYYMMDDHHMMSS>_<name>", e.Name())
+			continue
+		}
+		if _, err := time.Parse("20060102150405", m[1]); err != nil {
+			t.Errorf("%s: invalid timestamp: %v", e.Name(), err)
+		}
+		if _, err := fs.Stat(Migrations, e.Name()+"/migration.sql"); err != nil {
+			t.Errorf("%s: %v", e.Name(), err)
+		}
+	}
+}